@@ -0,0 +1,191 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package api
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	cons "github.com/moemoe89/go-graphql-gendhis/constant"
+
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moemoe89/go-localization"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// BatchMaxOperations represent the maximum number of operations in one batch
+	BatchMaxOperations = 50
+
+	// batchPathPrefix represent the prefix of path allowed inside batch
+	batchPathPrefix = "/api/v1/"
+)
+
+// batchStreamingPaths represent the streaming routes not allowed inside batch, the events never finish before
+// the batch and the export would be buffered whole in memory
+var batchStreamingPaths = map[string]bool{
+	batchPathPrefix + "user/events": true,
+	batchPathPrefix + "user/export": true,
+}
+
+var (
+	errBatchRollback = errors.New("Batch rolled back")
+	errInvalidBatch  = errors.New("Invalid batch request")
+)
+
+// AtomicFunc represent the runner of atomic batch, fn gets the handler whose writes are part of one
+// transaction bound to ctx that is rolled back when fn returns error, which is returned as is
+type AtomicFunc func(ctx context.Context, fn func(http.Handler) error) error
+
+type batchCtrl struct {
+	lang    *language.Config
+	log     *logrus.Entry
+	handler http.Handler
+	atomic  AtomicFunc
+}
+
+// NewBatchCtrl will create an object that represent the batchCtrl struct
+func NewBatchCtrl(lang *language.Config, log *logrus.Entry, handler http.Handler, atomic AtomicFunc) *batchCtrl {
+	return &batchCtrl{lang, log, handler, atomic}
+}
+
+//
+// @Summary Batch
+// @Description execute multiple operations in one request, optionally atomically within one transaction
+// @Accept  json
+// @Produce  json
+// @Param Accept-Language header string false "language message response"
+// @Param body body form.BatchRequest true "Request Payload"
+// @Success 200 {object} model.BatchResponse
+// @Failure 400 {object} model.GenericResponse
+// @Failure 500 {object} model.GenericResponse
+// @Router /batch [post]
+func (b *batchCtrl) Batch(c *gin.Context) {
+	l := c.Request.Header.Get("Accept-Language")
+	resp := &model.BatchResponse{}
+
+	req := &form.BatchRequest{}
+	if err := c.ShouldBindJSON(req); err != nil {
+		b.log.Errorf("can't get json body: %s", err.Error())
+		b.errorResponse(c, http.StatusBadRequest, "Oops! Something went wrong with your request")
+		return
+	}
+
+	if len(req.Operations) == 0 || len(req.Operations) > BatchMaxOperations {
+		b.errorResponse(c, http.StatusBadRequest, errInvalidBatch.Error())
+		return
+	}
+
+	for _, op := range req.Operations {
+		op.Method = strings.ToUpper(op.Method)
+		if len(op.Method) == 0 || !validBatchPath(op.Path) {
+			b.errorResponse(c, http.StatusBadRequest, errInvalidBatch.Error())
+			return
+		}
+	}
+
+	report := &model.BatchReport{Atomic: req.Atomic, Results: []*model.BatchResult{}}
+	message := "OK"
+
+	if !req.Atomic {
+		for _, op := range req.Operations {
+			report.Results = append(report.Results, b.dispatch(c, b.handler, op))
+		}
+	} else {
+		err := b.atomic(c.Request.Context(), func(handler http.Handler) error {
+			for _, op := range req.Operations {
+				result := b.dispatch(c, handler, op)
+				report.Results = append(report.Results, result)
+				if result.Status >= http.StatusBadRequest {
+					return errBatchRollback
+				}
+			}
+			return nil
+		})
+		if err == errBatchRollback {
+			report.RolledBack = true
+			message = errBatchRollback.Error()
+		} else if err != nil {
+			b.log.Errorf("can't run atomic batch: %s", err.Error())
+			b.errorResponse(c, http.StatusInternalServerError, "Oops! Something went wrong. Please try again later")
+			return
+		}
+	}
+
+	resp.GenericResponse = model.NewGenericResponse(http.StatusOK, cons.OK, []string{b.lang.Lookup(l, message)})
+	resp.Data = report
+	c.JSON(http.StatusOK, resp)
+}
+
+// validBatchPath will check the path of operation is an API route allowed inside batch, which isn't the batch
+// itself or a streaming route
+func validBatchPath(p string) bool {
+	u, err := url.Parse(p)
+	if err != nil {
+		return false
+	}
+
+	clean := path.Clean(u.Path)
+	if !strings.HasPrefix(clean+"/", batchPathPrefix) || clean+"/" == batchPathPrefix {
+		return false
+	}
+
+	return clean != batchPathPrefix+"batch" && !batchStreamingPaths[clean]
+}
+
+// dispatch will serve the operation in-process with the context of batch, the Accept and Accept-Language
+// headers of batch are inherited unless the operation overrides them
+func (b *batchCtrl) dispatch(c *gin.Context, handler http.Handler, op *form.BatchOperation) *model.BatchResult {
+	req, err := http.NewRequestWithContext(c.Request.Context(), op.Method, op.Path, bytes.NewReader(op.Body))
+	if err != nil {
+		return &model.BatchResult{Status: http.StatusBadRequest}
+	}
+
+	for _, h := range []string{"Accept", "Accept-Language"} {
+		if v := c.Request.Header.Get(h); len(v) > 0 {
+			req.Header.Set(h, v)
+		}
+	}
+	if len(op.Body) > 0 {
+		req.Header.Set("Content-Type", gin.MIMEJSON)
+	}
+	for k, v := range op.Headers {
+		req.Header.Set(k, v)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	result := &model.BatchResult{Status: w.Code, Headers: map[string]string{}}
+	for k := range w.Header() {
+		result.Headers[k] = w.Header().Get(k)
+	}
+
+	body := w.Body.Bytes()
+	if json.Valid(body) {
+		result.Body = json.RawMessage(body)
+	} else if len(body) > 0 {
+		result.Body = string(body)
+	}
+
+	return result
+}
+
+// errorResponse will write the localized error response of batch
+func (b *batchCtrl) errorResponse(c *gin.Context, status int, message string) {
+	l := c.Request.Header.Get("Accept-Language")
+	c.JSON(status, model.NewGenericResponse(status, cons.ERR, []string{b.lang.Lookup(l, message)}))
+}
@@ -0,0 +1,183 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package api_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user/mocks"
+	"github.com/moemoe89/go-graphql-gendhis/config"
+	"github.com/moemoe89/go-graphql-gendhis/routers"
+
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func batchResponse(t *testing.T, w *httptest.ResponseRecorder) *model.BatchResponse {
+	resp := &model.BatchResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), resp)
+	assert.NoError(t, err)
+
+	return resp
+}
+
+func TestBatch(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	id := xid.New().String()
+	mockUser := &model.UserModel{ID: id, Name: "Momo", Email: "momo@mail.com"}
+
+	mockService := new(mocks.Service)
+	mockService.On("Create", mock.Anything, mock.AnythingOfType("*form.UserForm")).Return(mockUser, nil)
+	mockService.On("Detail", mock.Anything, id, model.UserSelectField).Return(nil, user.ErrNotFound)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	body := `{"operations":[
+		{"method":"post","path":"/api/v1/user","body":{"name":"Momo","email":"momo@mail.com"}},
+		{"method":"GET","path":"/api/v1/user/` + id + `","headers":{"Accept":"application/problem+json"}}
+	]}`
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "id")
+	router.ServeHTTP(w, req)
+
+	resp := batchResponse(t, w)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, resp.Data.RolledBack)
+	assert.Len(t, resp.Data.Results, 2)
+	assert.Equal(t, http.StatusCreated, resp.Data.Results[0].Status)
+	assert.Equal(t, http.StatusNotFound, resp.Data.Results[1].Status)
+	assert.Equal(t, model.ProblemJSONType, resp.Data.Results[1].Headers["Content-Type"])
+
+	problem := resp.Data.Results[1].Body.(map[string]interface{})
+	assert.Equal(t, "Pengguna tidak ditemukan", problem["detail"])
+	mockService.AssertExpectations(t)
+}
+
+// storedRow will return the row of user returned by the insert
+func storedRow(id, name, email string) *sqlmock.Rows {
+	now := time.Now().UTC()
+	return sqlmock.NewRows(strings.Split(model.UserSelectField, ",")).
+		AddRow(id, name, email, "", "", "", now, now)
+}
+
+func TestBatchAtomic(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	db, sqlMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	userSvc := user.NewService(log, user.NewPostgresRepository(sqlxDB, sqlxDB, nil))
+	router := routers.GetRouter(lang, log, userSvc, nil)
+
+	t.Run("commit", func(t *testing.T) {
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery("INSERT INTO users").WillReturnRows(storedRow(xid.New().String(), "Momo", "momo@mail.com"))
+		sqlMock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectQuery("INSERT INTO users").WillReturnRows(storedRow(xid.New().String(), "Gendhis", "gendhis@mail.com"))
+		sqlMock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectCommit()
+
+		body := `{"atomic":true,"operations":[
+			{"method":"POST","path":"/api/v1/user","body":{"name":"Momo","email":"momo@mail.com"}},
+			{"method":"POST","path":"/api/v1/user","body":{"name":"Gendhis","email":"gendhis@mail.com"}}
+		]}`
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		resp := batchResponse(t, w)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, resp.Data.Atomic)
+		assert.False(t, resp.Data.RolledBack)
+		assert.Len(t, resp.Data.Results, 2)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		sqlMock.ExpectBegin()
+		sqlMock.ExpectQuery("INSERT INTO users").WillReturnRows(storedRow(xid.New().String(), "Momo", "momo@mail.com"))
+		sqlMock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+		sqlMock.ExpectRollback()
+
+		body := `{"atomic":true,"operations":[
+			{"method":"POST","path":"/api/v1/user","body":{"name":"Momo","email":"momo@mail.com"}},
+			{"method":"POST","path":"/api/v1/user","body":{"name":"","email":"gendhis@mail.com"}},
+			{"method":"POST","path":"/api/v1/user","body":{"name":"Gendhis","email":"gendhis@mail.com"}}
+		]}`
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		resp := batchResponse(t, w)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, resp.Data.RolledBack)
+		assert.Equal(t, []string{"Batch rolled back"}, resp.Messages)
+		assert.Len(t, resp.Data.Results, 2)
+		assert.Equal(t, http.StatusBadRequest, resp.Data.Results[1].Status)
+		assert.NoError(t, sqlMock.ExpectationsWereMet())
+	})
+}
+
+func TestBatchFailInvalid(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	mockService := new(mocks.Service)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	for _, ops := range [][]*form.BatchOperation{
+		{},
+		{{Method: "GET", Path: "/ping"}},
+		{{Method: "POST", Path: "/api/v1/batch"}},
+		{{Path: "/api/v1/user"}},
+		{{Method: "GET", Path: "/api/v1/../ping"}},
+		{{Method: "GET", Path: "/api/v1/user/events"}},
+		{{Method: "GET", Path: "/api/v1/user/export?format=ndjson"}},
+		{{Method: "GET", Path: "/api/v1/user/./export/"}},
+		{{Method: "GET", Path: "/api/v1/user"}, {Method: "GET", Path: "/api/v1/user/events?types=user.created"}},
+	} {
+		j, err := json.Marshal(&form.BatchRequest{Operations: ops})
+		assert.NoError(t, err)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/api/v1/batch", strings.NewReader(string(j)))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+	mockService.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+	mockService.AssertNotCalled(t, "Subscribe", mock.Anything)
+}
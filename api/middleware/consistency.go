@@ -0,0 +1,120 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package middleware
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/consistency"
+
+	"crypto/rand"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// LSNHeader represent the header carrying the signed write position, sent back after the request writing
+	// and read from the client which keeps it by itself
+	LSNHeader = "X-Write-LSN"
+	// LSNCookie represent the cookie carrying the signed write position for ConsistencyWindow
+	LSNCookie = "write_lsn"
+)
+
+var (
+	// ConsistencyWindow represent how long the reads of client must see its last write, it should outlast the
+	// replica lag since the reads go to replica afterwards
+	ConsistencyWindow = 5 * time.Second
+
+	// ConsistencyKey represent the key signing the write positions sent to client, so the client can't pin its
+	// reads to primary with a made up position. It must be shared by the instances serving the same clients,
+	// the random key generated at start only accepts the positions issued by this instance
+	ConsistencyKey = newConsistencyKey()
+)
+
+// ReadYourWrites will bind the read-your-writes session to the request, the reads must see the write position
+// of LSNHeader or LSNCookie and the position written by request is sent back in both, signed with ConsistencyKey
+// and valid for ConsistencyWindow. The position not signed or expired is ignored. The sub-request of batch joins
+// the session of its parent
+func ReadYourWrites(c *gin.Context) {
+	if consistency.FromContext(c.Request.Context()) != nil {
+		c.Next()
+		return
+	}
+
+	var minRead consistency.LSN
+	cookie, _ := c.Cookie(LSNCookie)
+	now := time.Now()
+	for _, value := range []string{c.GetHeader(LSNHeader), cookie} {
+		if lsn, err := consistency.VerifyLSN(ConsistencyKey, value, now); err == nil && lsn > minRead {
+			minRead = lsn
+		}
+	}
+
+	session := consistency.NewSession(minRead)
+	c.Request = c.Request.WithContext(consistency.NewContext(c.Request.Context(), session))
+	c.Writer = &lsnWriter{ResponseWriter: c.Writer, session: session}
+	c.Next()
+}
+
+// lsnWriter represent the response writer adding the write position of session before the headers are sent
+type lsnWriter struct {
+	gin.ResponseWriter
+	session *consistency.Session
+	done    bool
+}
+
+func (w *lsnWriter) WriteHeaderNow() {
+	w.writeLSN()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *lsnWriter) Write(data []byte) (int, error) {
+	w.writeLSN()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *lsnWriter) WriteString(s string) (int, error) {
+	w.writeLSN()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *lsnWriter) Flush() {
+	w.writeLSN()
+	w.ResponseWriter.Flush()
+}
+
+// writeLSN will set the write position once, nothing is set when the request didn't write
+func (w *lsnWriter) writeLSN() {
+	if w.done || w.Written() {
+		return
+	}
+	w.done = true
+
+	lsn := w.session.Written()
+	if lsn == 0 {
+		return
+	}
+
+	maxAge := int(ConsistencyWindow / time.Second)
+	if maxAge < 1 {
+		maxAge = 1
+	}
+
+	token := consistency.SignLSN(ConsistencyKey, lsn, time.Now().Add(ConsistencyWindow))
+	w.Header().Set(LSNHeader, token)
+	http.SetCookie(w, &http.Cookie{Name: LSNCookie, Value: token, Path: "/", MaxAge: maxAge, HttpOnly: true})
+}
+
+// newConsistencyKey will generate the random key of ConsistencyKey
+func newConsistencyKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic("can't generate the consistency key: " + err.Error())
+	}
+
+	return key
+}
@@ -0,0 +1,78 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package middleware_test
+
+import (
+	mw "github.com/moemoe89/go-graphql-gendhis/api/middleware"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/consistency"
+
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	r := gin.New()
+	r.Use(mw.ReadYourWrites)
+	r.POST("/write", func(c *gin.Context) {
+		consistency.FromContext(c.Request.Context()).Wrote(0x16B374D848)
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+	r.GET("/read", func(c *gin.Context) {
+		c.String(http.StatusOK, consistency.FromContext(c.Request.Context()).MinRead().String())
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/write", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	token := w.Header().Get(mw.LSNHeader)
+	lsn, err := consistency.VerifyLSN(mw.ConsistencyKey, token, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, consistency.LSN(0x16B374D848), lsn)
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, mw.LSNCookie, cookies[0].Name)
+	assert.Equal(t, token, cookies[0].Value)
+	assert.Equal(t, int(mw.ConsistencyWindow.Seconds()), cookies[0].MaxAge)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/read", nil)
+	req.AddCookie(cookies[0])
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "16/B374D848", w.Body.String())
+	assert.Empty(t, w.Header().Get(mw.LSNHeader))
+	assert.Empty(t, w.Result().Cookies())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/read", nil)
+	req.Header.Set(mw.LSNHeader, token)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "16/B374D848", w.Body.String())
+
+	// the position made up by client, signed by other key or expired doesn't pin the reads to primary
+	for _, value := range []string{
+		"invalid",
+		"FFFFFFFF/FFFFFFFF",
+		consistency.SignLSN([]byte("other key"), consistency.MaxLSN, time.Now().Add(time.Minute)),
+		consistency.SignLSN(mw.ConsistencyKey, consistency.MaxLSN, time.Now().Add(-time.Second)),
+	} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/read", nil)
+		req.Header.Set(mw.LSNHeader, value)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, "0/0", w.Body.String(), value)
+	}
+}
@@ -15,7 +15,7 @@ import (
 // CORS will handle the CORS middleware
 func CORS(c *gin.Context) {
 	c.Header("Access-Control-Allow-Origin", "*")
-	c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+	c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE")
 	if c.Request.Method == "OPTIONS" {
 		if len(c.Request.Header["Access-Control-Request-Headers"]) > 0 {
 			c.Header("Access-Control-Allow-Headers", c.Request.Header["Access-Control-Request-Headers"][0])
@@ -0,0 +1,25 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package form
+
+import (
+	"encoding/json"
+)
+
+// BatchOperation represent the sub-request of batch
+type BatchOperation struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchRequest represent the batch request payload
+type BatchRequest struct {
+	Atomic     bool              `json:"atomic"`
+	Operations []*BatchOperation `json:"operations"`
+}
@@ -0,0 +1,118 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package form
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+var (
+	// DefaultRegion represent the region of national phone number when the request doesn't give one
+	DefaultRegion = "ID"
+
+	// ErrInvalidPhone represent the error of phone number which isn't valid number by the libphonenumber metadata
+	ErrInvalidPhone = errors.New("Invalid phone number format")
+	// ErrUnknownRegion represent the error of region without known calling code
+	ErrUnknownRegion = errors.New("Unknown phone region")
+)
+
+// IsRegion will check whether the region has known calling code, empty region is the DefaultRegion
+func IsRegion(region string) bool {
+	return phonenumbers.GetCountryCodeForRegion(phoneRegion(region)) != 0
+}
+
+// NormalizePhone will normalize the phone number into E.164, the number starting with `+` or `00` is
+// international and any other is national number of region (DefaultRegion when it's empty).
+// The spaces, dots, dashes and parentheses are ignored, and the number must be valid for its
+// region by the libphonenumber metadata (length and number ranges)
+func NormalizePhone(raw, region string) (string, error) {
+	digits, international, ok := phoneDigits(raw)
+	if !ok || len(digits) == 0 {
+		return "", ErrInvalidPhone
+	}
+
+	if international {
+		digits = "+" + digits
+	} else if !IsRegion(region) {
+		return "", ErrUnknownRegion
+	}
+
+	num, err := phonenumbers.Parse(digits, phoneRegion(region))
+	if err != nil || !phonenumbers.IsValidNumber(num) {
+		return "", ErrInvalidPhone
+	}
+
+	return phonenumbers.Format(num, phonenumbers.E164), nil
+}
+
+// PhoneFilter will turn the partial phone number into the substring of E.164 number, so it matches the stored
+// number regardless of formatting. The international or national number (with trunk prefix) is anchored to
+// its calling code and any other is matched as plain digits
+func PhoneFilter(value, region string) string {
+	digits, international, ok := phoneDigits(value)
+	if !ok || len(digits) == 0 {
+		return value
+	}
+
+	if international {
+		return "+" + digits
+	}
+
+	if code, trunk := phoneCallingCode(region); code != 0 && len(trunk) > 0 && strings.HasPrefix(digits, trunk) {
+		return "+" + strconv.Itoa(code) + strings.TrimPrefix(digits, trunk)
+	}
+
+	return digits
+}
+
+// phoneDigits will strip the formatting of phone number and the international prefix, ok is false
+// when there is any other character than the digits and formatting
+func phoneDigits(raw string) (digits string, international, ok bool) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "+") {
+		raw = raw[1:]
+		international = true
+	}
+
+	b := strings.Builder{}
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+		default:
+			return "", false, false
+		}
+	}
+
+	digits = b.String()
+	if !international && strings.HasPrefix(digits, "00") {
+		digits = digits[2:]
+		international = true
+	}
+
+	return digits, international, true
+}
+
+// phoneCallingCode will return the country calling code and the national trunk prefix of region,
+// the code is zero when the region is unknown
+func phoneCallingCode(region string) (code int, trunk string) {
+	region = phoneRegion(region)
+	return phonenumbers.GetCountryCodeForRegion(region), phonenumbers.GetNddPrefixForRegion(region, true)
+}
+
+func phoneRegion(region string) string {
+	if len(region) == 0 {
+		return strings.ToUpper(DefaultRegion)
+	}
+
+	return strings.ToUpper(region)
+}
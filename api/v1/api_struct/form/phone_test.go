@@ -0,0 +1,53 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package form_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	for _, c := range []struct {
+		raw      string
+		region   string
+		expected string
+	}{
+		{"0856-4012-3456", "", "+6285640123456"},
+		{"(0856) 4012 3456", "id", "+6285640123456"},
+		{"+62 856 4012 3456", "US", "+6285640123456"},
+		{"0062 856 4012 3456", "", "+6285640123456"},
+		{"1 (415) 555-2671", "US", "+14155552671"},
+		{"415.555.2671", "US", "+14155552671"},
+		{"090-1234-5678", "JP", "+819012345678"},
+		{"(11) 98765-4321", "br", "+5511987654321"},
+	} {
+		phone, err := form.NormalizePhone(c.raw, c.region)
+		assert.NoError(t, err, c.raw)
+		assert.Equal(t, c.expected, phone, c.raw)
+	}
+
+	for _, raw := range []string{"", "085640", "+0856401234", "0856-4012-3456 ext 1", "+1234567890123456", "+1 (055) 555-2671", "+62 12345 678"} {
+		_, err := form.NormalizePhone(raw, "")
+		assert.Equal(t, form.ErrInvalidPhone, err, raw)
+	}
+
+	_, err := form.NormalizePhone("0856-4012-3456", "XX")
+	assert.Equal(t, form.ErrUnknownRegion, err)
+}
+
+func TestPhoneFilter(t *testing.T) {
+	assert.Equal(t, "+6285640", form.PhoneFilter("0856-40", ""))
+	assert.Equal(t, "+6285640", form.PhoneFilter("+62 856 40", ""))
+	assert.Equal(t, "40123456", form.PhoneFilter("4012 3456", ""))
+	assert.Equal(t, "+14155", form.PhoneFilter("1-415-5", "US"))
+	assert.Equal(t, "", form.PhoneFilter("", ""))
+	assert.Equal(t, "abc", form.PhoneFilter("abc", ""))
+}
@@ -7,6 +7,8 @@
 package form
 
 import (
+	"unicode/utf8"
+
 	"github.com/asaskevich/govalidator"
 )
 
@@ -17,32 +19,62 @@ type UserForm struct {
 	Phone   string `json:"phone"`
 	Email   string `json:"email"`
 	Address string `json:"address"`
+	// Region represent the region of national phone number, DefaultRegion when it's empty
+	Region string `json:"region,omitempty"`
 }
 
+const (
+	// NameMaxLength represent the maximum length of name column
+	NameMaxLength = 50
+	// EmailMaxLength represent the maximum length of email column
+	EmailMaxLength = 50
+	// PhoneMaxLength represent the maximum length of phone column
+	PhoneMaxLength = 20
+)
+
 // Validate represent the validation method from UserForm
-func (v *UserForm) Validate() []string {
-	errs := []string{}
+func (v *UserForm) Validate() ValidationErrors {
+	errs := ValidationErrors{}
 	if len(v.Name) < 1 {
-		errs = append(errs, "Name can't be empty")
+		errs = append(errs, &FieldError{Field: "name", Rule: RuleRequired, Message: "Name can't be empty"})
 	}
+	errs = maxLength(errs, "name", v.Name, NameMaxLength, "Name can't be longer than {max} characters")
 
 	if !govalidator.IsEmail(v.Email) {
-		errs = append(errs, "Invalid email address")
+		errs = append(errs, &FieldError{Field: "email", Rule: RuleEmail, Message: "Invalid email address format"})
+	}
+	errs = maxLength(errs, "email", v.Email, EmailMaxLength, "Email can't be longer than {max} characters")
+
+	switch {
+	case utf8.RuneCountInString(v.Phone) > PhoneMaxLength:
+		errs = maxLength(errs, "phone", v.Phone, PhoneMaxLength, "Phone can't be longer than {max} characters")
+	case len(v.Region) > 0 && !IsRegion(v.Region):
+		errs = append(errs, &FieldError{Field: "region", Rule: RulePhone, Message: "Unknown phone region"})
+	case len(v.Phone) > 0:
+		if _, err := NormalizePhone(v.Phone, v.Region); err != nil {
+			errs = append(errs, &FieldError{Field: "phone", Rule: RulePhone, Message: "Invalid phone number format"})
+		}
 	}
 
 	return errs
 }
 
+// NormalizedPhone will return the phone in E.164, empty when it's empty or invalid
+func (v *UserForm) NormalizedPhone() string {
+	phone, _ := NormalizePhone(v.Phone, v.Region)
+	return phone
+}
+
 // UserQueryForm represent the user request model
 type UserQueryForm struct {
 	Query string `json:"query"`
 }
 
 // Validate represent the validation method from UserQueryForm
-func (v *UserQueryForm) Validate() []string {
-	errs := []string{}
+func (v *UserQueryForm) Validate() ValidationErrors {
+	errs := ValidationErrors{}
 	if len(v.Query) < 1 {
-		errs = append(errs, "Query can't be empty")
+		errs = append(errs, &FieldError{Field: "query", Rule: RuleRequired, Message: "Query can't be empty"})
 	}
 
 	return errs
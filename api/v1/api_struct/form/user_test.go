@@ -8,7 +8,9 @@ package form_test
 
 import (
 	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+	"github.com/moemoe89/go-graphql-gendhis/config"
 
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -20,7 +22,9 @@ func TestUserNameEmpty(t *testing.T) {
 	expected := "Name can't be empty"
 	errs := user.Validate()
 
-	assert.Equal(t, expected, errs[0])
+	assert.Equal(t, expected, errs[0].Message)
+	assert.Equal(t, "name", errs[0].Field)
+	assert.Equal(t, form.RuleRequired, errs[0].Rule)
 }
 
 func TestUserInvalidEmail(t *testing.T) {
@@ -29,8 +33,76 @@ func TestUserInvalidEmail(t *testing.T) {
 		Email: "Baruno",
 	}
 
-	expected := "Invalid email address"
+	expected := "Invalid email address format"
 	errs := user.Validate()
 
-	assert.Equal(t, expected, errs[0])
+	assert.Equal(t, expected, errs[0].Message)
+	assert.Equal(t, "email", errs[0].Field)
+	assert.Equal(t, form.RuleEmail, errs[0].Rule)
+}
+
+func TestUserMaxLength(t *testing.T) {
+	user := &form.UserForm{
+		Name:  strings.Repeat("a", form.NameMaxLength+1),
+		Email: strings.Repeat("a", form.EmailMaxLength) + "@mail.com",
+		Phone: strings.Repeat("0", form.PhoneMaxLength+1),
+	}
+
+	errs := user.Validate()
+
+	assert.Len(t, errs, 3)
+	for i, field := range []string{"name", "email", "phone"} {
+		assert.Equal(t, field, errs[i].Field)
+		assert.Equal(t, form.RuleMax, errs[i].Rule)
+	}
+	assert.Equal(t, form.PhoneMaxLength, errs[2].Params["max"])
+}
+
+func TestUserInvalidPhone(t *testing.T) {
+	user := &form.UserForm{
+		Name:  "Momo",
+		Email: "momo@mail.com",
+		Phone: "085640",
+	}
+
+	errs := user.Validate()
+
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "phone", errs[0].Field)
+	assert.Equal(t, form.RulePhone, errs[0].Rule)
+
+	user.Phone = "0856-4012-3456"
+	assert.Empty(t, user.Validate())
+	assert.Equal(t, "+6285640123456", user.NormalizedPhone())
+
+	user.Region = "XX"
+	errs = user.Validate()
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "region", errs[0].Field)
+}
+
+func TestUserMaxLengthMultiByte(t *testing.T) {
+	user := &form.UserForm{
+		Name:  strings.Repeat("あ", form.NameMaxLength),
+		Email: "momo@mail.com",
+	}
+
+	errs := user.Validate()
+
+	assert.Empty(t, errs)
+}
+
+func TestFieldErrorLocalize(t *testing.T) {
+	lang, _ := config.InitLang()
+
+	user := &form.UserForm{
+		Name:  strings.Repeat("a", form.NameMaxLength+1),
+		Email: "momo@mail.com",
+	}
+
+	errs := user.Validate()
+
+	assert.Equal(t, "Name can't be longer than 50 characters", errs[0].Localize(lang, "en"))
+	assert.Equal(t, "Nama tidak boleh lebih dari 50 karakter", errs[0].Localize(lang, "id"))
+	assert.Equal(t, errs[0].Message, errs.Error())
 }
@@ -0,0 +1,71 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package form
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/moemoe89/go-localization"
+)
+
+const (
+	// RuleRequired represent the validation rule of mandatory field
+	RuleRequired = "required"
+	// RuleEmail represent the validation rule of email address field
+	RuleEmail = "email"
+	// RulePhone represent the validation rule of phone number field, normalized to E.164
+	RulePhone = "phone"
+	// RuleMax represent the validation rule of maximum characters length
+	RuleMax = "max"
+)
+
+// FieldError represent the validation problem of a single field
+type FieldError struct {
+	Field   string                 `json:"field"`
+	Rule    string                 `json:"rule"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+	Message string                 `json:"message"`
+}
+
+// Localize will translate the message key and fill the rule parameters into it
+func (e *FieldError) Localize(lang *language.Config, locale string) string {
+	msg := lang.Lookup(locale, e.Message)
+	for k, v := range e.Params {
+		msg = strings.Replace(msg, "{"+k+"}", fmt.Sprint(v), -1)
+	}
+
+	return msg
+}
+
+// ValidationErrors represent the list of validation problem from a form
+type ValidationErrors []*FieldError
+
+// Error will join the message keys of validation problem
+func (v ValidationErrors) Error() string {
+	msgs := []string{}
+	for _, e := range v {
+		msgs = append(msgs, e.Message)
+	}
+
+	return strings.Join(msgs, ", ")
+}
+
+// maxLength will check the characters length of value, the same way VARCHAR counts it
+func maxLength(errs ValidationErrors, field, value string, max int, message string) ValidationErrors {
+	if utf8.RuneCountInString(value) > max {
+		errs = append(errs, &FieldError{
+			Field:   field,
+			Rule:    RuleMax,
+			Params:  map[string]interface{}{"max": max},
+			Message: message,
+		})
+	}
+
+	return errs
+}
@@ -0,0 +1,78 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package form
+
+import (
+	"net/url"
+	"unicode/utf8"
+)
+
+// WebhookForm represent the webhook request model, empty events subscribe to every event and empty
+// secret is generated on create or kept on update
+type WebhookForm struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+const (
+	// URLMaxLength represent the maximum length of webhook url column
+	URLMaxLength = 2048
+	// SecretMinLength represent the minimum length of webhook secret
+	SecretMinLength = 16
+	// SecretMaxLength represent the maximum length of webhook secret column
+	SecretMaxLength = 64
+
+	// RuleURL represent the validation rule of absolute http or https URL field
+	RuleURL = "url"
+	// RuleOneOf represent the validation rule of value from the allowed list
+	RuleOneOf = "oneof"
+	// RuleBetween represent the validation rule of minimum and maximum characters length
+	RuleBetween = "between"
+)
+
+// webhookEvents holds the event types webhook can subscribe to, the same as user event types
+var webhookEvents = map[string]bool{
+	"user.created": true,
+	"user.updated": true,
+	"user.deleted": true,
+}
+
+// Validate represent the validation method from WebhookForm
+func (v *WebhookForm) Validate() ValidationErrors {
+	errs := ValidationErrors{}
+	if len(v.URL) < 1 {
+		errs = append(errs, &FieldError{Field: "url", Rule: RuleRequired, Message: "URL can't be empty"})
+	} else if u, err := url.Parse(v.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) < 1 {
+		errs = append(errs, &FieldError{Field: "url", Rule: RuleURL, Message: "Invalid URL format: must be absolute http or https URL"})
+	}
+	errs = maxLength(errs, "url", v.URL, URLMaxLength, "URL can't be longer than {max} characters")
+
+	for _, event := range v.Events {
+		if !webhookEvents[event] {
+			errs = append(errs, &FieldError{
+				Field:   "events",
+				Rule:    RuleOneOf,
+				Params:  map[string]interface{}{"event": event},
+				Message: "Unknown event {event}: must be user.created, user.updated or user.deleted",
+			})
+		}
+	}
+
+	n := utf8.RuneCountInString(v.Secret)
+	if len(v.Secret) > 0 && (n < SecretMinLength || n > SecretMaxLength) {
+		errs = append(errs, &FieldError{
+			Field:   "secret",
+			Rule:    RuleBetween,
+			Params:  map[string]interface{}{"min": SecretMinLength, "max": SecretMaxLength},
+			Message: "Secret must be between {min} and {max} characters",
+		})
+	}
+
+	return errs
+}
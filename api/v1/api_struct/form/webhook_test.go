@@ -0,0 +1,73 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package form_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookValid(t *testing.T) {
+	webhook := &form.WebhookForm{
+		URL:    "https://crm.example.com/hooks/users",
+		Events: []string{"user.created", "user.deleted"},
+		Secret: "0123456789abcdef",
+	}
+
+	assert.Empty(t, webhook.Validate())
+}
+
+func TestWebhookURLEmpty(t *testing.T) {
+	webhook := &form.WebhookForm{}
+
+	errs := webhook.Validate()
+
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "url", errs[0].Field)
+	assert.Equal(t, form.RuleRequired, errs[0].Rule)
+}
+
+func TestWebhookInvalidURL(t *testing.T) {
+	for _, url := range []string{"crm.example.com/hooks", "ftp://crm.example.com", "https://"} {
+		webhook := &form.WebhookForm{URL: url}
+
+		errs := webhook.Validate()
+
+		assert.Len(t, errs, 1)
+		assert.Equal(t, form.RuleURL, errs[0].Rule)
+	}
+}
+
+func TestWebhookUnknownEvent(t *testing.T) {
+	webhook := &form.WebhookForm{
+		URL:    "https://crm.example.com/hooks/users",
+		Events: []string{"user.created", "user.merged"},
+	}
+
+	errs := webhook.Validate()
+
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "events", errs[0].Field)
+	assert.Equal(t, form.RuleOneOf, errs[0].Rule)
+	assert.Equal(t, "user.merged", errs[0].Params["event"])
+}
+
+func TestWebhookSecretLength(t *testing.T) {
+	webhook := &form.WebhookForm{
+		URL:    "https://crm.example.com/hooks/users",
+		Secret: "short",
+	}
+
+	errs := webhook.Validate()
+
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "secret", errs[0].Field)
+	assert.Equal(t, form.RuleBetween, errs[0].Rule)
+}
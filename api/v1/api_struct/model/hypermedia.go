@@ -0,0 +1,37 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package model
+
+const (
+	// JSONAPIType represent the JSON:API media type
+	JSONAPIType = "application/vnd.api+json"
+	// HALType represent the HAL media type
+	HALType = "application/hal+json"
+
+	// UserResourceType represent the JSON:API resource type of user
+	UserResourceType = "users"
+)
+
+// JSONAPIDocument represent the top level JSON:API document
+type JSONAPIDocument struct {
+	Data  interface{}            `json:"data"`
+	Links map[string]string      `json:"links,omitempty"`
+	Meta  map[string]interface{} `json:"meta,omitempty"`
+}
+
+// JSONAPIResource represent the JSON:API resource object
+type JSONAPIResource struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id"`
+	Attributes map[string]interface{} `json:"attributes"`
+	Links      map[string]string      `json:"links,omitempty"`
+}
+
+// HALLink represent the HAL link object
+type HALLink struct {
+	Href string `json:"href"`
+}
@@ -0,0 +1,26 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxSelectField represent the selected column for outbox model
+const OutboxSelectField = "id,aggregate_type,aggregate_id,event_type,payload,attempts,created_at"
+
+// OutboxModel represent the domain event written in the same transaction as the change it describes
+type OutboxModel struct {
+	ID            int64           `json:"id" db:"id"`
+	AggregateType string          `json:"aggregate_type" db:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id" db:"aggregate_id"`
+	EventType     string          `json:"type" db:"event_type"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	Attempts      int             `json:"-" db:"attempts"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+}
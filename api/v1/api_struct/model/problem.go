@@ -0,0 +1,67 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package model
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	// ProblemJSONType represent the RFC 7807 problem details media type
+	ProblemJSONType = "application/problem+json"
+
+	// CodeBadRequest represent the error code of malformed request
+	CodeBadRequest = "BAD_REQUEST"
+	// CodeValidationFailed represent the error code of invalid request payload
+	CodeValidationFailed = "VALIDATION_FAILED"
+	// CodeUserNotFound represent the error code of missing user
+	CodeUserNotFound = "USER_NOT_FOUND"
+	// CodeWebhookNotFound represent the error code of missing webhook
+	CodeWebhookNotFound = "WEBHOOK_NOT_FOUND"
+	// CodeConflict represent the error code of resource clashing with the stored one
+	CodeConflict = "CONFLICT"
+	// CodeUnsupportedMediaType represent the error code of unsupported request media type
+	CodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	// CodeInternalError represent the error code of unexpected server failure
+	CodeInternalError = "INTERNAL_ERROR"
+	// CodeServiceUnavailable represent the error code of temporarily unavailable service, the request can be retried
+	CodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+)
+
+// NewProblemResponse will create an object that represent the ProblemResponse struct
+func NewProblemResponse(stsCd int, code, detail, instance string, errs []*ErrorDetail) *ProblemResponse {
+
+	return &ProblemResponse{
+		Type:     "/problems/" + strings.ToLower(strings.Replace(code, "_", "-", -1)),
+		Title:    http.StatusText(stsCd),
+		Status:   stsCd,
+		Detail:   detail,
+		Instance: instance,
+		Code:     code,
+		Errors:   errs,
+	}
+}
+
+// ProblemResponse represent the RFC 7807 problem details response API
+type ProblemResponse struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Code     string         `json:"code"`
+	Errors   []*ErrorDetail `json:"errors,omitempty"`
+}
+
+// ErrorDetail represent the single field-level problem inside error response
+type ErrorDetail struct {
+	Field  string                 `json:"field,omitempty"`
+	Rule   string                 `json:"rule,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty"`
+	Detail string                 `json:"detail"`
+}
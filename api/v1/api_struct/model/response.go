@@ -27,11 +27,22 @@ func NewPaginationResponse(page, perPage, totalPage, totalData int) *PaginationR
 	}
 }
 
+// NewCursorPaginationResponse will create an object that represent the CursorPaginationResponse struct
+func NewCursorPaginationResponse(limit int, nextCursor, prevCursor string) *CursorPaginationResponse {
+
+	return &CursorPaginationResponse{
+		Limit:      limit,
+		NextCursor: nextCursor,
+		PrevCursor: prevCursor,
+	}
+}
+
 // GenericResponse represent the generic response API
 type GenericResponse struct {
-	Status   int      `json:"status"`
-	Success  bool     `json:"success"`
-	Messages []string `json:"messages"`
+	Status   int            `json:"status"`
+	Success  bool           `json:"success"`
+	Messages []string       `json:"messages"`
+	Errors   []*ErrorDetail `json:"errors,omitempty"`
 }
 
 // PaginationResponse represent the response API with pagination
@@ -42,6 +53,14 @@ type PaginationResponse struct {
 	TotalData int `json:"total_data"`
 }
 
+// CursorPaginationResponse represent the response API with keyset pagination
+type CursorPaginationResponse struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+	TotalData  *int   `json:"total_data,omitempty"`
+}
+
 // UserResponse represent the generic user response API
 type UserResponse struct {
 	*GenericResponse
@@ -54,8 +73,104 @@ type UserPaginationResponse struct {
 	List []*UserModel `json:"list"`
 }
 
+// UserCursorPaginationResponse represent the user response API with keyset pagination
+type UserCursorPaginationResponse struct {
+	*CursorPaginationResponse
+	List []*UserModel `json:"list"`
+}
+
+// UsersCursorResponse represent the generic user response API with keyset pagination
+type UsersCursorResponse struct {
+	*GenericResponse
+	Data *UserCursorPaginationResponse `json:"data"`
+}
+
 // UsersResponse represent the generic user response API with pagination
 type UsersResponse struct {
 	*GenericResponse
 	Data *UserPaginationResponse `json:"data"`
 }
+
+// UserSearchPaginationResponse represent the user search response API with pagination
+type UserSearchPaginationResponse struct {
+	*PaginationResponse
+	List []*UserSearchModel `json:"list"`
+}
+
+// UserSearchResponse represent the generic user search response API with pagination
+type UserSearchResponse struct {
+	*GenericResponse
+	Data *UserSearchPaginationResponse `json:"data"`
+}
+
+// ImportRejection represent the rejected row of user import with its reasons
+type ImportRejection struct {
+	Row    int            `json:"row"`
+	Errors []*ErrorDetail `json:"errors"`
+}
+
+// ImportReport represent the report of user import
+type ImportReport struct {
+	DryRun   bool               `json:"dry_run"`
+	Total    int                `json:"total"`
+	Created  int                `json:"created"`
+	Updated  int                `json:"updated"`
+	Accepted []int              `json:"accepted"`
+	Rejected []*ImportRejection `json:"rejected"`
+}
+
+// ImportResponse represent the generic user import response API
+type ImportResponse struct {
+	*GenericResponse
+	Data *ImportReport `json:"data"`
+}
+
+// BatchResult represent the response of sub-request
+type BatchResult struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    interface{}       `json:"body,omitempty"`
+}
+
+// BatchReport represent the result of batch, the operations after the failed one of atomic batch are skipped
+type BatchReport struct {
+	Atomic     bool           `json:"atomic"`
+	RolledBack bool           `json:"rolled_back"`
+	Results    []*BatchResult `json:"results"`
+}
+
+// BatchResponse represent the generic batch response API
+type BatchResponse struct {
+	*GenericResponse
+	Data *BatchReport `json:"data"`
+}
+
+// WebhookResponse represent the generic webhook response API
+type WebhookResponse struct {
+	*GenericResponse
+	Data *WebhookModel `json:"data"`
+}
+
+// WebhookPaginationResponse represent the webhook response API with pagination
+type WebhookPaginationResponse struct {
+	*PaginationResponse
+	List []*WebhookModel `json:"list"`
+}
+
+// WebhooksResponse represent the generic webhook response API with pagination
+type WebhooksResponse struct {
+	*GenericResponse
+	Data *WebhookPaginationResponse `json:"data"`
+}
+
+// WebhookDeliveryPaginationResponse represent the webhook delivery response API with pagination
+type WebhookDeliveryPaginationResponse struct {
+	*PaginationResponse
+	List []*WebhookDeliveryModel `json:"list"`
+}
+
+// WebhookDeliveriesResponse represent the generic webhook delivery response API with pagination
+type WebhookDeliveriesResponse struct {
+	*GenericResponse
+	Data *WebhookDeliveryPaginationResponse `json:"data"`
+}
@@ -56,3 +56,20 @@ func TestNewPaginationResponse(t *testing.T) {
 	assert.Equal(t, totalPage, resp.TotalPage)
 	assert.Equal(t, totalData, resp.TotalData)
 }
+
+func TestNewProblemResponse(t *testing.T) {
+	status := http.StatusNotFound
+	code := model.CodeUserNotFound
+	detail := "User not found"
+	instance := "/api/v1/user/1"
+
+	resp := model.NewProblemResponse(status, code, detail, instance, nil)
+
+	assert.Equal(t, "/problems/user-not-found", resp.Type)
+	assert.Equal(t, "Not Found", resp.Title)
+	assert.Equal(t, status, resp.Status)
+	assert.Equal(t, detail, resp.Detail)
+	assert.Equal(t, instance, resp.Instance)
+	assert.Equal(t, code, resp.Code)
+	assert.Nil(t, resp.Errors)
+}
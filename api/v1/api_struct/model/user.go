@@ -11,7 +11,7 @@ import (
 )
 
 // UserSelectField represent the default selected column for user model
-const UserSelectField = "id,name,email,phone,address,created_at,updated_at"
+const UserSelectField = "id,name,email,phone,phone_raw,address,created_at,updated_at"
 
 // UserModel represent the user model
 type UserModel struct {
@@ -19,8 +19,23 @@ type UserModel struct {
 	Name      string     `json:"name" db:"name"`
 	Email     string     `json:"email" db:"email"`
 	Phone     string     `json:"phone" db:"phone"`
+	PhoneRaw  string     `json:"phone_raw" db:"phone_raw"`
 	Address   string     `json:"address" db:"address"`
 	CreatedAt time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
 	DeletedAt *time.Time `json:"-" db:"deleted_at"`
 }
+
+// UserSearchModel represent the user model of full-text search result
+type UserSearchModel struct {
+	UserModel
+	Rank      float64 `json:"rank" db:"rank"`
+	Highlight string  `json:"highlight,omitempty" db:"highlight"`
+}
+
+// UserEvent represent the change event of user, the id is increasing monotonically
+type UserEvent struct {
+	ID   uint64     `json:"id"`
+	Type string     `json:"type"`
+	User *UserModel `json:"user"`
+}
@@ -0,0 +1,66 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// WebhookSelectField represent the default selected column for webhook model
+	WebhookSelectField = "id,url,secret,events,active,created_at,updated_at"
+	// WebhookDeliverySelectField represent the default selected column for webhook delivery model
+	WebhookDeliverySelectField = "id,webhook_id,event_id,event_type,payload,status,attempts,response_status,last_error,next_attempt_at,created_at,updated_at"
+
+	// DeliveryPending represent the status of delivery waiting for the first or next attempt
+	DeliveryPending = "pending"
+	// DeliverySucceeded represent the status of delivery acknowledged with 2xx by receiver
+	DeliverySucceeded = "succeeded"
+	// DeliveryDead represent the status of delivery which failed every attempt, the dead letter
+	DeliveryDead = "dead"
+)
+
+// WebhookModel represent the webhook subscription model, empty events subscribe to every event
+type WebhookModel struct {
+	ID        string         `json:"id" db:"id"`
+	URL       string         `json:"url" db:"url"`
+	Secret    string         `json:"secret,omitempty" db:"secret"`
+	Events    pq.StringArray `json:"events" db:"events"`
+	Active    bool           `json:"active" db:"active"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at" db:"updated_at"`
+	DeletedAt *time.Time     `json:"-" db:"deleted_at"`
+}
+
+// WebhookDeliveryModel represent the delivery log model of one event to one webhook, the pending delivery
+// is attempted at next_attempt_at
+type WebhookDeliveryModel struct {
+	ID             string          `json:"id" db:"id"`
+	WebhookID      string          `json:"webhook_id" db:"webhook_id"`
+	EventID        uint64          `json:"event_id" db:"event_id"`
+	EventType      string          `json:"event_type" db:"event_type"`
+	Payload        json.RawMessage `json:"payload" db:"payload"`
+	Status         string          `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	ResponseStatus int             `json:"response_status" db:"response_status"`
+	LastError      string          `json:"last_error" db:"last_error"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookPayload represent the JSON body sent to webhook receiver
+type WebhookPayload struct {
+	ID        string     `json:"id"`
+	Type      string     `json:"type"`
+	EventID   uint64     `json:"event_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Data      *UserModel `json:"data"`
+}
@@ -0,0 +1,191 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package consistency
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxLSN represent the write position never replayed by replica, so the reads go to primary
+const MaxLSN = LSN(1<<64 - 1)
+
+var (
+	// ErrInvalidLSN represent the error of malformed write-ahead log position
+	ErrInvalidLSN = errors.New("Invalid LSN")
+	// ErrInvalidToken represent the error of write position token which isn't signed by the key or has expired
+	ErrInvalidToken = errors.New("Invalid LSN token")
+)
+
+// LSN represent the position in the write-ahead log of postgres, written like 16/B374D848
+type LSN uint64
+
+// ParseLSN will parse the position written like pg_lsn
+func ParseLSN(s string) (LSN, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return 0, ErrInvalidLSN
+	}
+
+	hi, err := strconv.ParseUint(parts[0], 16, 32)
+	if err != nil {
+		return 0, ErrInvalidLSN
+	}
+	lo, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil {
+		return 0, ErrInvalidLSN
+	}
+
+	return LSN(hi<<32 | lo), nil
+}
+
+func (l LSN) String() string {
+	return fmt.Sprintf("%X/%X", uint64(l)>>32, uint64(l)&(1<<32-1))
+}
+
+// SignLSN will create the token of write position handed to client, written like 16/B374D848.<expiry>.<signature>
+// where expiry is unix milliseconds and signature is hex HMAC-SHA256 of the rest with key. So the client can
+// only send back the position issued by server and only until it expires
+func SignLSN(key []byte, lsn LSN, expires time.Time) string {
+	payload := lsn.String() + "." + strconv.FormatInt(expires.UnixNano()/int64(time.Millisecond), 10)
+	return payload + "." + tokenSignature(key, payload)
+}
+
+// VerifyLSN will return the write position of token signed by key with SignLSN, which hasn't expired at now
+func VerifyLSN(key []byte, token string, now time.Time) (LSN, error) {
+	i := strings.LastIndex(token, ".")
+	if i < 0 || !hmac.Equal([]byte(token[i+1:]), []byte(tokenSignature(key, token[:i]))) {
+		return 0, ErrInvalidToken
+	}
+
+	payload := token[:i]
+	j := strings.LastIndex(payload, ".")
+	if j < 0 {
+		return 0, ErrInvalidToken
+	}
+	expires, err := strconv.ParseInt(payload[j+1:], 10, 64)
+	if err != nil || now.UnixNano()/int64(time.Millisecond) > expires {
+		return 0, ErrInvalidToken
+	}
+
+	return ParseLSN(payload[:j])
+}
+
+func tokenSignature(key []byte, payload string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ReplayCache represent the positions replayed by replicas, kept for TTL so the reads of session don't ask the
+// replica for its position every time. The replayed position only advances, so the cached one which has reached
+// the position needed is used regardless of its age
+type ReplayCache struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	positions map[interface{}]replayed
+}
+
+// replayed represent the position replayed by replica and when it's read
+type replayed struct {
+	lsn LSN
+	at  time.Time
+}
+
+// NewReplayCache will create an object that represent the ReplayCache struct
+func NewReplayCache(ttl time.Duration) *ReplayCache {
+	return &ReplayCache{ttl: ttl, positions: map[interface{}]replayed{}}
+}
+
+// Replayed will check whether the replica has replayed lsn, read is called for the replayed position of replica
+// when the cached one is behind lsn and older than TTL
+func (c *ReplayCache) Replayed(replica interface{}, lsn LSN, read func() (LSN, error)) (bool, error) {
+	c.mu.Lock()
+	cached, ok := c.positions[replica]
+	c.mu.Unlock()
+
+	if ok && (cached.lsn >= lsn || time.Since(cached.at) < c.ttl) {
+		return cached.lsn >= lsn, nil
+	}
+
+	position, err := read()
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	if current, ok := c.positions[replica]; !ok || position >= current.lsn {
+		c.positions[replica] = replayed{lsn: position, at: time.Now()}
+	}
+	c.mu.Unlock()
+
+	return position >= lsn, nil
+}
+
+// Session represent the read-your-writes state of one request, the reads must see MinRead and the writes
+// of request advance Written
+type Session struct {
+	mu      sync.Mutex
+	minRead LSN
+	written LSN
+}
+
+// NewSession will create an object that represent the Session struct, minRead is the last write position
+// known by client
+func NewSession(minRead LSN) *Session {
+	return &Session{minRead: minRead}
+}
+
+// MinRead will return the position the replica must have replayed to serve the reads of session
+func (s *Session) MinRead() LSN {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.minRead
+}
+
+// Written will return the last write position of session, zero when nothing is written
+func (s *Session) Written() LSN {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.written
+}
+
+// Wrote will record the write position, the later reads of session must see it too
+func (s *Session) Wrote(lsn LSN) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lsn > s.written {
+		s.written = lsn
+	}
+	if lsn > s.minRead {
+		s.minRead = lsn
+	}
+}
+
+type sessionKey struct{}
+
+// NewContext will return the context carrying the session
+func NewContext(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// FromContext will return the session of context, nil when there is none
+func FromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
@@ -0,0 +1,115 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package consistency_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/consistency"
+
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLSN(t *testing.T) {
+	lsn, err := consistency.ParseLSN("16/B374D848")
+	assert.NoError(t, err)
+	assert.Equal(t, consistency.LSN(0x16B374D848), lsn)
+	assert.Equal(t, "16/B374D848", lsn.String())
+	assert.Equal(t, "FFFFFFFF/FFFFFFFF", consistency.MaxLSN.String())
+
+	for _, s := range []string{"", "16", "16/", "G/0", "1/2/3", "100000000/0"} {
+		_, err := consistency.ParseLSN(s)
+		assert.Equal(t, consistency.ErrInvalidLSN, err, s)
+	}
+}
+
+func TestSignLSN(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	now := time.Now()
+
+	token := consistency.SignLSN(key, 0x16B374D848, now.Add(time.Second))
+	assert.True(t, strings.HasPrefix(token, "16/B374D848."))
+
+	lsn, err := consistency.VerifyLSN(key, token, now)
+	assert.NoError(t, err)
+	assert.Equal(t, consistency.LSN(0x16B374D848), lsn)
+
+	for _, c := range []struct {
+		key   []byte
+		token string
+		now   time.Time
+	}{
+		{key, "16/B374D848", now},
+		{key, "", now},
+		{key, strings.Replace(token, "16/B374D848", "FFFFFFFF/FFFFFFFF", 1), now},
+		{[]byte("fedcba9876543210"), token, now},
+		{key, token, now.Add(2 * time.Second)},
+	} {
+		_, err := consistency.VerifyLSN(c.key, c.token, c.now)
+		assert.Equal(t, consistency.ErrInvalidToken, err, c.token)
+	}
+}
+
+func TestReplayCache(t *testing.T) {
+	cache := consistency.NewReplayCache(time.Hour)
+	reads := 0
+	position := consistency.LSN(0x100)
+	read := func() (consistency.LSN, error) {
+		reads++
+		return position, nil
+	}
+
+	replayed, err := cache.Replayed("replica", 0x200, read)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Equal(t, 1, reads)
+
+	// the position behind is used until it's older than TTL
+	position = 0x300
+	replayed, _ = cache.Replayed("replica", 0x200, read)
+	assert.False(t, replayed)
+	assert.Equal(t, 1, reads)
+
+	// the position reached is used regardless of its age
+	replayed, _ = cache.Replayed("replica", 0x100, read)
+	assert.True(t, replayed)
+	assert.Equal(t, 1, reads)
+
+	cache = consistency.NewReplayCache(0)
+	replayed, _ = cache.Replayed("replica", 0x200, read)
+	assert.True(t, replayed)
+	replayed, _ = cache.Replayed("replica", 0x200, read)
+	assert.True(t, replayed)
+	assert.Equal(t, 2, reads)
+
+	_, err = cache.Replayed("other", 0x200, func() (consistency.LSN, error) {
+		return 0, errors.New("connection refused")
+	})
+	assert.Error(t, err)
+}
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, consistency.FromContext(ctx))
+
+	session := consistency.NewSession(0x200)
+	ctx = consistency.NewContext(ctx, session)
+	assert.Equal(t, session, consistency.FromContext(ctx))
+
+	session.Wrote(0x100)
+	assert.Equal(t, consistency.LSN(0x100), session.Written())
+	assert.Equal(t, consistency.LSN(0x200), session.MinRead())
+
+	session.Wrote(0x300)
+	session.Wrote(0x250)
+	assert.Equal(t, consistency.LSN(0x300), session.Written())
+	assert.Equal(t, consistency.LSN(0x300), session.MinRead())
+}
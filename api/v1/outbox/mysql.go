@@ -0,0 +1,19 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package outbox
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// NewMySQLRepository will create an object that represent the Repository interface, skipping the locked
+// events needs MySQL 8.0
+func NewMySQLRepository(DB *sqlx.DB) Repository {
+	return &sqlRepository{DB: DB, selectField: model.OutboxSelectField, lock: lockSkipLocked, timestamp: utcTimestamp}
+}
@@ -0,0 +1,159 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package outbox
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// PublisherLog represent the publisher writing events into log
+	PublisherLog = "log"
+	// PublisherMemory represent the publisher keeping events in memory
+	PublisherMemory = "memory"
+	// PublisherHTTP represent the publisher posting events to HTTP endpoint
+	PublisherHTTP = "http"
+
+	// HeaderEvent represent the header of event type posted by HTTP publisher
+	HeaderEvent = "X-Outbox-Event"
+	// HeaderIdempotencyKey represent the header of event id posted by HTTP publisher, the same on every retry
+	HeaderIdempotencyKey = "Idempotency-Key"
+)
+
+// Publisher represent the destination of outbox events, the event is published at least once
+type Publisher interface {
+	Publish(msg *model.OutboxModel) error
+}
+
+// NewPublisher will create the publisher by name, url is the endpoint of HTTP publisher
+func NewPublisher(name string, log *logrus.Entry, client *http.Client, url string) (Publisher, error) {
+	switch name {
+	case "", PublisherLog:
+		return NewLogPublisher(log), nil
+	case PublisherMemory:
+		return NewMemoryPublisher(), nil
+	case PublisherHTTP:
+		if len(url) < 1 {
+			return nil, errors.New("url of http publisher can't be empty")
+		}
+		return NewHTTPPublisher(client, url), nil
+	}
+
+	return nil, fmt.Errorf("unknown outbox publisher %s", name)
+}
+
+type logPublisher struct {
+	log *logrus.Entry
+}
+
+// NewLogPublisher will create the publisher writing every event into log
+func NewLogPublisher(log *logrus.Entry) Publisher {
+	return &logPublisher{log}
+}
+
+func (l *logPublisher) Publish(msg *model.OutboxModel) error {
+	l.log.Infof("outbox event %d %s of %s %s: %s", msg.ID, msg.EventType, msg.AggregateType, msg.AggregateID, msg.Payload)
+	return nil
+}
+
+// MemoryPublisher represent the publisher keeping every event in memory
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []*model.OutboxModel
+}
+
+// NewMemoryPublisher will create an object that represent the MemoryPublisher struct
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{messages: []*model.OutboxModel{}}
+}
+
+// Publish will keep the event
+func (m *MemoryPublisher) Publish(msg *model.OutboxModel) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages will return the published events in order
+func (m *MemoryPublisher) Messages() []*model.OutboxModel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]*model.OutboxModel{}, m.messages...)
+}
+
+type httpPublisher struct {
+	client *http.Client
+	url    string
+}
+
+// NewHTTPPublisher will create the publisher posting every event as JSON to url, any non-2xx response fails it
+func NewHTTPPublisher(client *http.Client, url string) Publisher {
+	return &httpPublisher{client, url}
+}
+
+func (h *httpPublisher) Publish(msg *model.OutboxModel) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, msg.EventType)
+	req.Header.Set(HeaderIdempotencyKey, strconv.FormatInt(msg.ID, 10))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+type fanoutPublisher struct {
+	publishers []Publisher
+}
+
+// NewFanoutPublisher will create the publisher handing every event to publishers in order, the first failed one
+// fails it. The event is published again to every publisher, so they must tolerate the event published twice
+func NewFanoutPublisher(publishers ...Publisher) Publisher {
+	return &fanoutPublisher{publishers}
+}
+
+func (f *fanoutPublisher) Publish(msg *model.OutboxModel) error {
+	for _, p := range f.publishers {
+		if err := p.Publish(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
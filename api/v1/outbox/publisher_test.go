@@ -0,0 +1,93 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package outbox_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/outbox"
+	"github.com/moemoe89/go-graphql-gendhis/config"
+
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPPublisher(t *testing.T) {
+	var body []byte
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	p, err := outbox.NewPublisher(outbox.PublisherHTTP, config.InitLog(), srv.Client(), srv.URL)
+	assert.NoError(t, err)
+
+	msg := &model.OutboxModel{ID: 42, AggregateType: "user", AggregateID: "bpielbbipt341rif5i20", EventType: "user.updated", Payload: json.RawMessage(`{"name":"Momo"}`)}
+	err = p.Publish(msg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "42", header.Get(outbox.HeaderIdempotencyKey))
+	assert.Equal(t, "user.updated", header.Get(outbox.HeaderEvent))
+	assert.JSONEq(t, `{"id":42,"aggregate_type":"user","aggregate_id":"bpielbbipt341rif5i20","type":"user.updated","payload":{"name":"Momo"},"created_at":"0001-01-01T00:00:00Z"}`, string(body))
+}
+
+func TestHTTPPublisherFailStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	p := outbox.NewHTTPPublisher(srv.Client(), srv.URL)
+	err := p.Publish(&model.OutboxModel{ID: 1, Payload: json.RawMessage(`{}`)})
+
+	assert.Error(t, err)
+}
+
+func TestNewPublisher(t *testing.T) {
+	log := config.InitLog()
+
+	p, err := outbox.NewPublisher("", log, http.DefaultClient, "")
+	assert.NoError(t, err)
+	assert.NoError(t, p.Publish(&model.OutboxModel{ID: 1, Payload: json.RawMessage(`{}`)}))
+
+	p, err = outbox.NewPublisher(outbox.PublisherMemory, log, http.DefaultClient, "")
+	assert.NoError(t, err)
+	assert.IsType(t, &outbox.MemoryPublisher{}, p)
+
+	_, err = outbox.NewPublisher(outbox.PublisherHTTP, log, http.DefaultClient, "")
+	assert.Error(t, err)
+
+	_, err = outbox.NewPublisher("kafka", log, http.DefaultClient, "")
+	assert.Error(t, err)
+}
+
+func TestFanoutPublisher(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	first, last := outbox.NewMemoryPublisher(), outbox.NewMemoryPublisher()
+	msg := &model.OutboxModel{ID: 1, Payload: json.RawMessage(`{}`)}
+
+	assert.NoError(t, outbox.NewFanoutPublisher(first, last).Publish(msg))
+	assert.Len(t, first.Messages(), 1)
+	assert.Len(t, last.Messages(), 1)
+
+	// the publishers after the failed one don't get the event
+	err := outbox.NewFanoutPublisher(first, outbox.NewHTTPPublisher(srv.Client(), srv.URL), last).Publish(msg)
+	assert.Error(t, err)
+	assert.Len(t, first.Messages(), 2)
+	assert.Len(t, last.Messages(), 1)
+}
@@ -0,0 +1,145 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package outbox
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultBatchSize represent the number of events relayed in one transaction
+	DefaultBatchSize = 100
+	// DefaultInterval represent the wait before polling again once the outbox is drained or failed
+	DefaultInterval = time.Second
+	// DefaultMaxAttempts represent the attempts of event before it's dead, so one poison event doesn't block
+	// the outbox
+	DefaultMaxAttempts = 10
+	// DefaultLease represent how long the claimed events are kept from other relay, it must outlast the
+	// publish of a batch
+	DefaultLease = time.Minute
+)
+
+// Relay represent the worker publishing the outbox events, several relays can run side by side since
+// the claimed events are skipped
+type Relay struct {
+	log         *logrus.Entry
+	repository  Repository
+	publisher   Publisher
+	batchSize   int
+	maxAttempts int
+	interval    time.Duration
+	lease       time.Duration
+}
+
+// NewRelay will create an object that represent the Relay struct, zero batchSize, maxAttempts and interval use
+// the defaults
+func NewRelay(log *logrus.Entry, r Repository, p Publisher, batchSize, maxAttempts int, interval time.Duration) *Relay {
+	if batchSize < 1 {
+		batchSize = DefaultBatchSize
+	}
+	if maxAttempts < 1 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Relay{log: log, repository: r, publisher: p, batchSize: batchSize, maxAttempts: maxAttempts, interval: interval, lease: DefaultLease}
+}
+
+// Run will relay the outbox until ctx is done, the next batch is read right away while the batches are full
+func (r *Relay) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := r.Relay()
+		if err != nil {
+			r.log.Errorf("can't relay outbox: %s", err.Error())
+		}
+
+		if err == nil && n == r.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+// Relay will publish one batch of claimed events in order. The first failed event is retried on the next poll
+// and the later events of batch are released, so they aren't published before it. The event failing
+// maxAttempts times is dead and skipped
+func (r *Relay) Relay() (int, error) {
+	now := time.Now().UTC()
+	messages, err := r.repository.Claim(now, now.Add(r.lease), r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := []int64{}
+	dead := []*model.OutboxModel{}
+	deadErrs := []error{}
+	var failed *model.OutboxModel
+	var publishErr error
+	for _, msg := range messages {
+		if err := r.publisher.Publish(msg); err != nil {
+			if msg.Attempts+1 < r.maxAttempts {
+				failed, publishErr = msg, err
+				break
+			}
+
+			dead, deadErrs = append(dead, msg), append(deadErrs, err)
+			continue
+		}
+		published = append(published, msg.ID)
+	}
+
+	// the published events are recorded first, so they aren't sent again when recording the dead ones fails
+	if err := r.repository.Published(published); err != nil {
+		return 0, err
+	}
+
+	var recordErr error
+	for i, msg := range dead {
+		r.log.Errorf("outbox event %d is dead after %d attempts: %s", msg.ID, msg.Attempts+1, deadErrs[i].Error())
+		if err := r.repository.Failed(msg.ID, deadErrs[i].Error(), true); err != nil && recordErr == nil {
+			recordErr = err
+		}
+	}
+
+	if failed == nil {
+		if recordErr != nil {
+			return len(published), recordErr
+		}
+		return len(messages), nil
+	}
+
+	if err := r.repository.Failed(failed.ID, publishErr.Error(), false); err != nil {
+		return len(published), err
+	}
+
+	released := []int64{}
+	for _, msg := range messages {
+		if msg.ID > failed.ID {
+			released = append(released, msg.ID)
+		}
+	}
+	if err := r.repository.Release(released); err != nil {
+		return len(published), err
+	}
+	if recordErr != nil {
+		return len(published), recordErr
+	}
+
+	return len(published), fmt.Errorf("can't publish outbox event: %s", publishErr.Error())
+}
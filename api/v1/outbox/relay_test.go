@@ -0,0 +1,153 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package outbox_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/outbox"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/outbox/mocks"
+	"github.com/moemoe89/go-graphql-gendhis/config"
+
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// failingPublisher represent the publisher failing the events of ids
+type failingPublisher struct {
+	*outbox.MemoryPublisher
+	ids map[int64]bool
+}
+
+func (p *failingPublisher) Publish(msg *model.OutboxModel) error {
+	if p.ids[msg.ID] {
+		return errors.New("broker down")
+	}
+	return p.MemoryPublisher.Publish(msg)
+}
+
+func claimed() []*model.OutboxModel {
+	return []*model.OutboxModel{
+		{ID: 1, EventType: "user.created"},
+		{ID: 2, EventType: "user.updated", Attempts: 2},
+		{ID: 3, EventType: "user.deleted"},
+	}
+}
+
+func TestRelayPublish(t *testing.T) {
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("Claim", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return(claimed(), nil)
+	mockRepo.On("Published", []int64{1, 2, 3}).Return(nil)
+
+	publisher := outbox.NewMemoryPublisher()
+	relay := outbox.NewRelay(config.InitLog(), mockRepo, publisher, 10, 3, time.Second)
+
+	n, err := relay.Relay()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Len(t, publisher.Messages(), 3)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRelayFailPublish(t *testing.T) {
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("Claim", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return(claimed(), nil)
+	mockRepo.On("Published", []int64{1}).Return(nil)
+	mockRepo.On("Failed", int64(2), "broker down", false).Return(nil)
+	// the later events are released so they're published after the failed one
+	mockRepo.On("Release", []int64{3}).Return(nil)
+
+	publisher := &failingPublisher{outbox.NewMemoryPublisher(), map[int64]bool{2: true}}
+	relay := outbox.NewRelay(config.InitLog(), mockRepo, publisher, 10, 5, time.Second)
+
+	n, err := relay.Relay()
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, publisher.Messages(), 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRelayDeadLetter(t *testing.T) {
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("Claim", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return(claimed(), nil)
+	// the poison event is dead on its last attempt and doesn't block the later events
+	mockRepo.On("Failed", int64(2), "broker down", true).Return(nil)
+	mockRepo.On("Published", []int64{1, 3}).Return(nil)
+
+	publisher := &failingPublisher{outbox.NewMemoryPublisher(), map[int64]bool{2: true}}
+	relay := outbox.NewRelay(config.InitLog(), mockRepo, publisher, 10, 3, time.Second)
+
+	n, err := relay.Relay()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Len(t, publisher.Messages(), 2)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRelayFailClaim(t *testing.T) {
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("Claim", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return(nil, errors.New("Unexpected database error"))
+
+	relay := outbox.NewRelay(config.InitLog(), mockRepo, outbox.NewMemoryPublisher(), 10, 3, time.Second)
+
+	n, err := relay.Relay()
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, n)
+	mockRepo.AssertNotCalled(t, "Published", mock.Anything)
+}
+
+func TestRelayRun(t *testing.T) {
+	mockRepo := new(mocks.Repository)
+	// the full batch is followed right away by the next one, the failed one waits for the interval
+	mockRepo.On("Claim", mock.Anything, mock.Anything, 2).Return([]*model.OutboxModel{{ID: 1}, {ID: 2}}, nil).Once()
+	mockRepo.On("Claim", mock.Anything, mock.Anything, 2).Return(nil, errors.New("Unexpected database error")).Once()
+	mockRepo.On("Claim", mock.Anything, mock.Anything, 2).Return([]*model.OutboxModel{}, nil)
+	mockRepo.On("Published", mock.Anything).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	relay := outbox.NewRelay(config.InitLog(), mockRepo, outbox.NewMemoryPublisher(), 2, 0, 10*time.Millisecond)
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("relay didn't stop")
+	}
+	assert.True(t, len(mockRepo.Calls) >= 3)
+}
+
+func TestRelayFailDeadLetter(t *testing.T) {
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("Claim", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return(claimed(), nil)
+	// the published events are recorded even when the dead one can't be
+	mockRepo.On("Published", []int64{1, 3}).Return(nil)
+	mockRepo.On("Failed", int64(2), "broker down", true).Return(errors.New("Unexpected database error"))
+
+	publisher := &failingPublisher{outbox.NewMemoryPublisher(), map[int64]bool{2: true}}
+	relay := outbox.NewRelay(config.InitLog(), mockRepo, publisher, 10, 3, time.Second)
+
+	n, err := relay.Relay()
+
+	assert.Error(t, err)
+	assert.Equal(t, 2, n)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,120 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package outbox
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// lockSkipLocked represent the row lock of claim, skipping the events locked by other relay
+const lockSkipLocked = " FOR UPDATE SKIP LOCKED"
+
+// Repository represent the repositories
+type Repository interface {
+	Claim(now, until time.Time, limit int) ([]*model.OutboxModel, error)
+	Published(ids []int64) error
+	Failed(id int64, lastError string, dead bool) error
+	Release(ids []int64) error
+}
+
+// sqlRepository represent the outbox on SQL database, the queries are written with ? and rebound to the
+// placeholders of the driver
+type sqlRepository struct {
+	DB          *sqlx.DB
+	selectField string
+	lock        string
+	timestamp   func(time.Time) interface{}
+}
+
+// NewPostgresRepository will create an object that represent the Repository interface
+func NewPostgresRepository(DB *sqlx.DB) Repository {
+	return &sqlRepository{DB: DB, selectField: model.OutboxSelectField, lock: lockSkipLocked, timestamp: utcTimestamp}
+}
+
+// utcTimestamp will return t in UTC as the argument of TIMESTAMP column
+func utcTimestamp(t time.Time) interface{} {
+	return t.UTC()
+}
+
+// Claim will lease up to limit unpublished events in order until until, skipping the dead ones and the ones
+// leased by other relay. The lease is committed before the events are published, so no lock is held across the
+// network and the events of relay which stops before recording them are claimed again once it expires
+func (p *sqlRepository) Claim(now, until time.Time, limit int) ([]*model.OutboxModel, error) {
+	tx, err := p.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	messages := []*model.OutboxModel{}
+	query := fmt.Sprintf("SELECT %s FROM outbox WHERE published_at IS NULL AND dead_at IS NULL AND (locked_until IS NULL OR locked_until <= ?) ORDER BY id LIMIT ?%s", p.selectField, p.lock)
+	if err := tx.Select(&messages, tx.Rebind(query), p.timestamp(now), limit); err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 {
+		return messages, nil
+	}
+
+	ids := make([]int64, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+	if err := p.exec(tx, `UPDATE outbox SET locked_until = ? WHERE id IN (?)`, p.timestamp(until), ids); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// Published will mark the events of ids published
+func (p *sqlRepository) Published(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return p.exec(p.DB, `UPDATE outbox SET published_at = CURRENT_TIMESTAMP, attempts = attempts + 1, last_error = '', locked_until = NULL WHERE id IN (?)`, ids)
+}
+
+// Failed will record the failed attempt of event id and release it, the dead event isn't claimed anymore
+func (p *sqlRepository) Failed(id int64, lastError string, dead bool) error {
+	query := `UPDATE outbox SET attempts = attempts + 1, last_error = ?, locked_until = NULL WHERE id = ?`
+	if dead {
+		query = `UPDATE outbox SET attempts = attempts + 1, last_error = ?, locked_until = NULL, dead_at = CURRENT_TIMESTAMP WHERE id = ?`
+	}
+
+	_, err := p.DB.Exec(p.DB.Rebind(query), lastError, id)
+	return err
+}
+
+// Release will release the lease of events of ids, so they're claimed again right away
+func (p *sqlRepository) Release(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return p.exec(p.DB, `UPDATE outbox SET locked_until = NULL WHERE id IN (?)`, ids)
+}
+
+// exec will expand the slice args of query and execute it on e
+func (p *sqlRepository) exec(e sqlx.Execer, query string, args ...interface{}) error {
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.Exec(p.DB.Rebind(query), args...)
+	return err
+}
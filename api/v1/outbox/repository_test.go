@@ -0,0 +1,180 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package outbox_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/outbox"
+	"github.com/moemoe89/go-graphql-gendhis/db/sqlite"
+
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	claimOutboxQuery   = "SELECT " + model.OutboxSelectField + " FROM outbox WHERE published_at IS NULL AND dead_at IS NULL AND (locked_until IS NULL OR locked_until <= $1) ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED"
+	leaseOutboxQuery   = "UPDATE outbox SET locked_until = $1 WHERE id IN ($2, $3)"
+	publishOutboxQuery = "UPDATE outbox SET published_at = CURRENT_TIMESTAMP, attempts = attempts + 1, last_error = '', locked_until = NULL WHERE id IN ($1, $2)"
+)
+
+func outboxRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "aggregate_type", "aggregate_id", "event_type", "payload", "attempts", "created_at"}).
+		AddRow(1, "user", xid.New().String(), "user.created", []byte(`{"name":"Momo"}`), 0, time.Now().UTC()).
+		AddRow(2, "user", xid.New().String(), "user.deleted", []byte(`{"name":"Gendhis"}`), 0, time.Now().UTC())
+}
+
+func TestClaim(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+
+	now := time.Now().UTC()
+	until := now.Add(time.Minute)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(claimOutboxQuery)).WithArgs(now, 10).WillReturnRows(outboxRows())
+	mock.ExpectExec(regexp.QuoteMeta(leaseOutboxQuery)).WithArgs(until, 1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	r := outbox.NewPostgresRepository(sqlxDB)
+
+	messages, err := r.Claim(now, until, 10)
+
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "user.created", messages[0].EventType)
+	assert.JSONEq(t, `{"name":"Gendhis"}`, string(messages[1].Payload))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimFail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+
+	now := time.Now().UTC()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(claimOutboxQuery)).WithArgs(now, 10).WillReturnError(errors.New("Unexpected database error"))
+	mock.ExpectRollback()
+	r := outbox.NewPostgresRepository(sqlxDB)
+
+	messages, err := r.Claim(now, now.Add(time.Minute), 10)
+
+	assert.Error(t, err)
+	assert.Nil(t, messages)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPublishedAndFailed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+
+	mock.ExpectExec(regexp.QuoteMeta(publishOutboxQuery)).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE outbox SET attempts = attempts + 1, last_error = $1, locked_until = NULL WHERE id = $2")).WithArgs("broker down", 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE outbox SET attempts = attempts + 1, last_error = $1, locked_until = NULL, dead_at = CURRENT_TIMESTAMP WHERE id = $2")).WithArgs("invalid payload", 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE outbox SET locked_until = NULL WHERE id IN ($1)")).WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 1))
+	r := outbox.NewPostgresRepository(sqlxDB)
+
+	assert.NoError(t, r.Published([]int64{1, 2}))
+	assert.NoError(t, r.Failed(3, "broker down", false))
+	assert.NoError(t, r.Failed(4, "invalid payload", true))
+	assert.NoError(t, r.Release([]int64{5}))
+	// nothing to update without ids
+	assert.NoError(t, r.Published(nil))
+	assert.NoError(t, r.Release(nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimMySQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "mysql")
+
+	now := time.Now().UTC()
+	until := now.Add(time.Minute)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT "+model.OutboxSelectField+" FROM outbox WHERE published_at IS NULL AND dead_at IS NULL AND (locked_until IS NULL OR locked_until <= ?) ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED")).WithArgs(now, 10).WillReturnRows(outboxRows())
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE outbox SET locked_until = ? WHERE id IN (?, ?)")).WithArgs(until, 1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	r := outbox.NewMySQLRepository(sqlxDB)
+
+	messages, err := r.Claim(now, until, 10)
+
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimSQLite(t *testing.T) {
+	db, err := sqlx.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening the sqlite database", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if err := sqlite.Migrate(db); err != nil {
+		t.Fatalf("an error '%s' was not expected when migrating the sqlite database", err)
+	}
+	// the columns added after the table was created are skipped once they exist
+	if err := sqlite.Migrate(db); err != nil {
+		t.Fatalf("an error '%s' was not expected when migrating the sqlite database again", err)
+	}
+	for _, name := range []string{"Momo", "Gendhis"} {
+		_, err = db.Exec(`INSERT INTO outbox (aggregate_type, aggregate_id, event_type, payload) VALUES ('user', ?, 'user.created', ?)`, xid.New().String(), `{"name":"`+name+`"}`)
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when inserting the outbox", err)
+		}
+	}
+	r := outbox.NewSQLiteRepository(db)
+
+	now := time.Now().UTC()
+	messages, err := r.Claim(now, now.Add(time.Minute), 10)
+
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.JSONEq(t, `{"name":"Momo"}`, string(messages[0].Payload))
+
+	// the leased events aren't claimed by other relay until the lease expires
+	messages, err = r.Claim(now, now.Add(time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 0)
+
+	messages, err = r.Claim(now.Add(2*time.Minute), now.Add(3*time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+
+	assert.NoError(t, r.Published([]int64{messages[0].ID}))
+	assert.NoError(t, r.Failed(messages[1].ID, "invalid payload", true))
+
+	// neither the published nor the dead event is claimed again
+	messages, err = r.Claim(now.Add(time.Hour), now.Add(time.Hour), 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 0)
+}
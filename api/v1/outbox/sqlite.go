@@ -0,0 +1,31 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package outbox
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// sqliteSelectField represent the selected column of outbox on SQLite, the payload is stored as TEXT and it's
+// read as BLOB so it scans into json.RawMessage
+var sqliteSelectField = strings.Replace(model.OutboxSelectField, "payload", "CAST(payload AS BLOB) AS payload", 1)
+
+// NewSQLiteRepository will create an object that represent the Repository interface. SQLite has no row lock,
+// the transaction of the only writer keeps other relay out of the claim
+func NewSQLiteRepository(DB *sqlx.DB) Repository {
+	return &sqlRepository{DB: DB, selectField: sqliteSelectField, timestamp: sqliteTimestamp}
+}
+
+// sqliteTimestamp will format t as CURRENT_TIMESTAMP does, so the stored timestamps compare as text
+func sqliteTimestamp(t time.Time) interface{} {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,150 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package replica
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultCheckInterval represent the wait between the health checks of replicas
+	DefaultCheckInterval = 5 * time.Second
+	// DefaultMaxLag represent the replication lag above which the replica is ejected
+	DefaultMaxLag = 10 * time.Second
+	// DefaultCheckTimeout represent the timeout of checking one replica
+	DefaultCheckTimeout = 2 * time.Second
+
+	// PostgresLagQuery represent the replication lag of PostgreSQL in seconds, zero when the replica has replayed
+	// everything it received (an idle primary doesn't look lagging) or it isn't in recovery
+	PostgresLagQuery = "SELECT COALESCE(CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0 ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) END, 0)"
+	// MySQLLagQuery represent the replication lag of MySQL in seconds, the age of the oldest transaction being
+	// applied, zero when the workers are idle or it isn't a replica
+	MySQLLagQuery = "SELECT COALESCE(MAX(IF(APPLYING_TRANSACTION <> '', TIMESTAMPDIFF(MICROSECOND, APPLYING_TRANSACTION_ORIGINAL_COMMIT_TIMESTAMP, NOW(6)), 0)), 0) / 1000000 FROM performance_schema.replication_applier_status_by_worker"
+)
+
+// Set represent the read replicas balanced round-robin, the replica failing the health check or lagging more
+// than maxLag is ejected until it passes again and the primary serves the reads when no replica is left
+type Set struct {
+	log     *logrus.Entry
+	primary *sqlx.DB
+	members []*member
+	maxLag  time.Duration
+	// lagQuery represent the replication lag in seconds, the PostgresLagQuery by default
+	lagQuery string
+	next     uint64
+}
+
+// member represent the replica of set and its health, the replica is healthy until the first check
+type member struct {
+	name    string
+	db      *sqlx.DB
+	ejected int32
+}
+
+// NewSet will create an object that represent the Set struct, zero maxLag uses the default
+func NewSet(log *logrus.Entry, primary *sqlx.DB, replicas []*sqlx.DB, maxLag time.Duration) *Set {
+	if maxLag <= 0 {
+		maxLag = DefaultMaxLag
+	}
+
+	members := []*member{}
+	for i, db := range replicas {
+		members = append(members, &member{name: fmt.Sprintf("replica-%d", i), db: db})
+	}
+
+	return &Set{log: log, primary: primary, members: members, maxLag: maxLag, lagQuery: PostgresLagQuery}
+}
+
+// WithLagQuery will set the query measuring the replication lag in seconds, like MySQLLagQuery
+func (s *Set) WithLagQuery(query string) *Set {
+	s.lagQuery = query
+	return s
+}
+
+// Next will pick the next healthy replica, the primary when there is none
+func (s *Set) Next() *sqlx.DB {
+	n := uint64(len(s.members))
+	start := atomic.AddUint64(&s.next, 1)
+	for i := uint64(0); i < n; i++ {
+		m := s.members[(start+i)%n]
+		if atomic.LoadInt32(&m.ejected) == 0 {
+			return m.db
+		}
+	}
+
+	return s.primary
+}
+
+// Healthy will return the number of replicas serving the reads
+func (s *Set) Healthy() int {
+	healthy := 0
+	for _, m := range s.members {
+		if atomic.LoadInt32(&m.ejected) == 0 {
+			healthy++
+		}
+	}
+
+	return healthy
+}
+
+// Check will ping every replica and measure its replication lag, the replica is ejected or restored by the result
+func (s *Set) Check(ctx context.Context) {
+	for _, m := range s.members {
+		err := s.check(ctx, m)
+		if err != nil && atomic.CompareAndSwapInt32(&m.ejected, 0, 1) {
+			s.log.Errorf("%s is ejected: %s", m.name, err.Error())
+		}
+		if err == nil && atomic.CompareAndSwapInt32(&m.ejected, 1, 0) {
+			s.log.Infof("%s is restored", m.name)
+		}
+	}
+}
+
+// Run will check the replicas every interval until ctx is done, zero interval uses the default
+func (s *Set) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCheckInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Check(ctx)
+		}
+	}
+}
+
+// check will return the reason of replica being unhealthy, nil when it's healthy
+func (s *Set) check(ctx context.Context, m *member) error {
+	ctx, cancel := context.WithTimeout(ctx, DefaultCheckTimeout)
+	defer cancel()
+
+	if err := m.db.PingContext(ctx); err != nil {
+		return err
+	}
+
+	var lag float64
+	if err := m.db.GetContext(ctx, &lag, s.lagQuery); err != nil {
+		return err
+	}
+	if d := time.Duration(lag * float64(time.Second)); d > s.maxLag {
+		return fmt.Errorf("replication lag %s is over %s", d, s.maxLag)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package replica_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/replica"
+	"github.com/moemoe89/go-graphql-gendhis/config"
+
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	return sqlx.NewDb(db, "sqlmock"), mock
+}
+
+func TestSetNext(t *testing.T) {
+	primary, _ := newDB(t)
+	first, _ := newDB(t)
+	second, _ := newDB(t)
+	defer primary.Close()
+	defer first.Close()
+	defer second.Close()
+
+	set := replica.NewSet(config.InitLog(), primary, []*sqlx.DB{first, second}, 0)
+
+	picked := map[*sqlx.DB]int{}
+	for i := 0; i < 4; i++ {
+		picked[set.Next()]++
+	}
+	assert.Equal(t, map[*sqlx.DB]int{first: 2, second: 2}, picked)
+
+	empty := replica.NewSet(config.InitLog(), primary, nil, 0)
+	assert.Equal(t, primary, empty.Next())
+}
+
+func TestSetCheck(t *testing.T) {
+	primary, _ := newDB(t)
+	first, firstMock := newDB(t)
+	second, secondMock := newDB(t)
+	defer primary.Close()
+	defer first.Close()
+	defer second.Close()
+
+	set := replica.NewSet(config.InitLog(), primary, []*sqlx.DB{first, second}, 0)
+
+	lag := func(seconds float64) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"lag"}).AddRow(seconds)
+	}
+
+	t.Run("eject", func(t *testing.T) {
+		firstMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		secondMock.ExpectPing()
+		secondMock.ExpectQuery("SELECT COALESCE").WillReturnRows(lag(60))
+
+		set.Check(context.Background())
+
+		assert.Equal(t, 0, set.Healthy())
+		assert.Equal(t, primary, set.Next())
+		assert.NoError(t, firstMock.ExpectationsWereMet())
+		assert.NoError(t, secondMock.ExpectationsWereMet())
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		firstMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		secondMock.ExpectPing()
+		secondMock.ExpectQuery("SELECT COALESCE").WillReturnRows(lag(0.5))
+
+		set.Check(context.Background())
+
+		assert.Equal(t, 1, set.Healthy())
+		assert.Equal(t, second, set.Next())
+		assert.Equal(t, second, set.Next())
+		assert.NoError(t, firstMock.ExpectationsWereMet())
+		assert.NoError(t, secondMock.ExpectationsWereMet())
+	})
+}
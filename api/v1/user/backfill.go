@@ -0,0 +1,46 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// BackfillPhones will normalize the phones left national by the phone_raw migration into E.164 with region, the
+// migration only converts the international ones as SQL doesn't know the calling codes. The phones which can't
+// be normalized are kept as they are and their user ids are returned to be fixed by hand
+func BackfillPhones(db *sqlx.DB, region string) (int, []string, error) {
+	rows := []struct {
+		ID    string `db:"id"`
+		Phone string `db:"phone"`
+	}{}
+	err := db.Select(&rows, `SELECT id, phone FROM users WHERE phone <> '' AND phone NOT LIKE '+%' ORDER BY id`)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	normalized := 0
+	invalid := []string{}
+	for _, row := range rows {
+		phone, err := form.NormalizePhone(row.Phone, region)
+		if err != nil {
+			invalid = append(invalid, row.ID)
+			continue
+		}
+
+		// the phone changed by a write since it was read is left to that write
+		_, err = db.Exec(db.Rebind(`UPDATE users SET phone = ? WHERE id = ? AND phone = ?`), phone, row.ID, row.Phone)
+		if err != nil {
+			return normalized, invalid, err
+		}
+		normalized++
+	}
+
+	return normalized, invalid, nil
+}
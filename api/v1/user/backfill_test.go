@@ -0,0 +1,60 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+	"github.com/moemoe89/go-graphql-gendhis/db/sqlite"
+
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackfillPhones(t *testing.T) {
+	db, err := sqlx.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening the sqlite database", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if err := sqlite.Migrate(db); err != nil {
+		t.Fatalf("an error '%s' was not expected when migrating the sqlite database", err)
+	}
+
+	// the rows as the phone_raw migration leaves them, only the international phone is normalized
+	users := [][]string{
+		{"bpielbbipt341rif5i20", "0856-4012-3456"},
+		{"bpielbbipt341rif5i21", "+6285640123456"},
+		{"bpielbbipt341rif5i22", "call me"},
+		{"bpielbbipt341rif5i23", ""},
+	}
+	for _, u := range users {
+		_, err := db.Exec(`INSERT INTO users (id, name, email, phone, phone_raw) VALUES (?, 'Momo', ?, ?, ?)`, u[0], u[0]+"@mail.com", u[1], u[1])
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when inserting the user", err)
+		}
+	}
+
+	normalized, invalid, err := user.BackfillPhones(db, "ID")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, normalized)
+	assert.Equal(t, []string{"bpielbbipt341rif5i22"}, invalid)
+
+	phones := []string{}
+	assert.NoError(t, db.Select(&phones, `SELECT phone FROM users ORDER BY id`))
+	assert.Equal(t, []string{"+6285640123456", "+6285640123456", "call me", ""}, phones)
+
+	// the backfill is idempotent
+	normalized, invalid, err = user.BackfillPhones(db, "ID")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, normalized)
+	assert.Len(t, invalid, 1)
+}
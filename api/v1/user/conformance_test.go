@@ -0,0 +1,88 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user/usertest"
+	conf "github.com/moemoe89/go-graphql-gendhis/config"
+
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestMemoryRepositoryConformance(t *testing.T) {
+	usertest.TestRepository(t, func(t *testing.T) (user.Repository, func()) {
+		return user.NewMemoryRepository(), func() {}
+	})
+}
+
+func TestSQLiteRepositoryConformance(t *testing.T) {
+	usertest.TestRepository(t, func(t *testing.T) (user.Repository, func()) {
+		configuration := *conf.Configuration
+		conf.Configuration.DialectMaster = conf.DialectSQLite
+		conf.Configuration.DsnMaster = "file::memory:"
+
+		_, db, err := conf.InitDB()
+		*conf.Configuration = configuration
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening the sqlite database", err)
+		}
+
+		return user.NewSQLiteRepository(db, nil), func() { db.Close() }
+	})
+}
+
+// openEmptyDB will connect to the migrated database of dsn and delete its users and outbox
+func openEmptyDB(t *testing.T, driver, dsn string) *sqlx.DB {
+	db, err := sqlx.Connect(driver, dsn)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening the %s database", err, driver)
+	}
+
+	for _, query := range []string{"DELETE FROM outbox", "DELETE FROM users"} {
+		if _, err := db.Exec(query); err != nil {
+			db.Close()
+			t.Fatalf("an error '%s' was not expected when emptying the %s database", err, driver)
+		}
+	}
+
+	return db
+}
+
+// TestPostgresRepositoryConformance will run the suite against the database migrated by db/migrations, it's
+// emptied by every case. There's no sqlmock run of the suite: sqlmock returns the rows scripted by the test
+// instead of evaluating the SQL, so the filters, ordering, keyset and soft delete checked by the suite would
+// only assert the script against itself. The SQL of PostgreSQL is pinned with sqlmock by repository_test.go,
+// and the implementation shared with SQLite is run by TestSQLiteRepositoryConformance
+func TestPostgresRepositoryConformance(t *testing.T) {
+	dsn := os.Getenv("USER_TEST_POSTGRES_DSN")
+	if len(dsn) == 0 {
+		t.Skip("USER_TEST_POSTGRES_DSN isn't set")
+	}
+
+	usertest.TestRepository(t, func(t *testing.T) (user.Repository, func()) {
+		db := openEmptyDB(t, conf.DialectPostgres, dsn)
+		return user.NewPostgresRepository(db, db, nil), func() { db.Close() }
+	})
+}
+
+// TestMySQLRepositoryConformance will run the suite against the database migrated by db/mysql, it's emptied
+// by every case
+func TestMySQLRepositoryConformance(t *testing.T) {
+	dsn := os.Getenv("USER_TEST_MYSQL_DSN")
+	if len(dsn) == 0 {
+		t.Skip("USER_TEST_MYSQL_DSN isn't set")
+	}
+
+	usertest.TestRepository(t, func(t *testing.T) (user.Repository, func()) {
+		db := openEmptyDB(t, conf.DialectMySQL, dsn)
+		return user.NewMySQLRepository(db, db, nil), func() { db.Close() }
+	})
+}
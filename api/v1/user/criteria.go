@@ -0,0 +1,143 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"fmt"
+	"strings"
+
+	"github.com/moemoe89/go-helpers"
+)
+
+// Sort represent the ordering of users by column
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort will parse the ordering like `name` or `-created_at` (descending), unknown column is ignored
+func ParseSort(orderBy string) []Sort {
+	field := strings.TrimPrefix(orderBy, "-")
+	if _, ok := filterColumns[field]; !ok {
+		return nil
+	}
+
+	return []Sort{{Field: field, Desc: strings.HasPrefix(orderBy, "-")}}
+}
+
+// ParseFields will parse the comma separated columns to select, unknown column is ignored
+func ParseFields(selectField string) []string {
+	var fields []string
+	for _, field := range helpers.CheckInTag(model.UserModel{}, selectField, "db") {
+		if _, ok := filterColumns[field]; ok {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+// UserCriteria represent the typed query of users, the delivery describes which users it needs and
+// the repository compiles it into SQL. The deleted users are never matched
+type UserCriteria struct {
+	// Filter represent the filter expression, nil matches every user
+	Filter *Filter
+	// Query represent the full-text search over name, email and address
+	Query string
+	// Highlight represent whether the matched snippet of Query is returned
+	Highlight bool
+	// Sort represent the ordering, newest first (the most relevant first with Query) when empty
+	Sort []Sort
+	// Keyset represent the keyset pagination replacing Sort, starting after Cursor when it's set
+	Keyset *Keyset
+	Cursor *Cursor
+	// Limit represent the page size, zero is every user
+	Limit  int
+	Offset int
+	// Fields represent the selected columns, the columns of model.UserSelectField when empty
+	Fields []string
+}
+
+// Where will add the column condition joined with AND into the filter, empty value is ignored
+// so the optional parameters can be given as is
+func (c *UserCriteria) Where(field, op string, value interface{}) *UserCriteria {
+	if s, ok := value.(string); ok && len(s) < 1 {
+		return c
+	}
+
+	return c.And(&Filter{Field: field, Op: op, Value: value})
+}
+
+// Match will add the plain list filters shared by REST and GraphQL, name, email and phone are matched
+// by substring and created_at by range
+func (c *UserCriteria) Match(name, email, phone, createdAtStart, createdAtEnd string) *UserCriteria {
+	return c.Where("name", OpContains, name).
+		Where("email", OpContains, email).
+		Where("phone", OpContains, phone).
+		Where("created_at", OpGte, createdAtStart).
+		Where("created_at", OpLte, createdAtEnd)
+}
+
+// And will add the filter joined with AND into the filter
+func (c *UserCriteria) And(f *Filter) *UserCriteria {
+	if c.Filter == nil {
+		c.Filter = &Filter{}
+	}
+	c.Filter.And = append(c.Filter.And, f)
+
+	return c
+}
+
+// Columns will return the selected columns, the key columns of keyset included
+func (c *UserCriteria) Columns() []string {
+	columns := c.Fields
+	if len(columns) == 0 {
+		columns = strings.Split(model.UserSelectField, ",")
+	}
+
+	if c.Keyset != nil {
+		columns = strings.Split(c.Keyset.SelectField(strings.Join(columns, ",")), ",")
+	}
+
+	return columns
+}
+
+// Validate will make sure the criteria can be compiled, the error wraps ErrInvalidFilter or ErrInvalidCursor
+func (c *UserCriteria) Validate() error {
+	args := map[string]interface{}{}
+
+	for _, sort := range c.Sort {
+		if _, ok := filterColumns[sort.Field]; !ok {
+			return fmt.Errorf("%w: unknown sort field %q", ErrInvalidFilter, sort.Field)
+		}
+	}
+
+	for _, field := range c.Fields {
+		if _, ok := filterColumns[field]; !ok {
+			return fmt.Errorf("%w: unknown select field %q", ErrInvalidFilter, field)
+		}
+	}
+
+	if c.Filter != nil {
+		if _, err := c.Filter.Compile(args); err != nil {
+			return err
+		}
+	}
+
+	if c.Cursor != nil {
+		if c.Keyset == nil {
+			return fmt.Errorf("%w: cursor without keyset", ErrInvalidCursor)
+		}
+		if _, err := c.Keyset.Condition(c.Cursor, args); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
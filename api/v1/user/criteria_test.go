@@ -0,0 +1,57 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSort(t *testing.T) {
+	assert.Equal(t, []user.Sort{{Field: "name"}}, user.ParseSort("name"))
+	assert.Equal(t, []user.Sort{{Field: "created_at", Desc: true}}, user.ParseSort("-created_at"))
+	assert.Nil(t, user.ParseSort(""))
+	assert.Nil(t, user.ParseSort("name; DROP TABLE users"))
+}
+
+func TestParseFields(t *testing.T) {
+	assert.Equal(t, []string{"id", "name"}, user.ParseFields("id,name,password"))
+	assert.Nil(t, user.ParseFields(""))
+}
+
+func TestCriteriaMatch(t *testing.T) {
+	criteria := (&user.UserCriteria{}).Match("momo", "", "", "2020-01-01", "")
+
+	assert.Equal(t, &user.Filter{And: []*user.Filter{
+		{Field: "name", Op: user.OpContains, Value: "momo"},
+		{Field: "created_at", Op: user.OpGte, Value: "2020-01-01"},
+	}}, criteria.Filter)
+	assert.Nil(t, (&user.UserCriteria{}).Match("", "", "", "", "").Filter)
+}
+
+func TestCriteriaColumns(t *testing.T) {
+	criteria := &user.UserCriteria{Fields: []string{"id", "name"}, Keyset: user.NewKeyset(nil)}
+
+	assert.Equal(t, []string{"id", "name", "created_at"}, criteria.Columns())
+}
+
+func TestCriteriaValidate(t *testing.T) {
+	assert.NoError(t, (&user.UserCriteria{Sort: user.ParseSort("-name")}).Validate())
+
+	err := (&user.UserCriteria{Sort: []user.Sort{{Field: "name; DROP TABLE users"}}}).Validate()
+	assert.True(t, errors.Is(err, user.ErrInvalidFilter))
+
+	err = (&user.UserCriteria{Fields: []string{"password"}}).Validate()
+	assert.True(t, errors.Is(err, user.ErrInvalidFilter))
+
+	err = (&user.UserCriteria{Cursor: &user.Cursor{}}).Validate()
+	assert.True(t, errors.Is(err, user.ErrInvalidCursor))
+}
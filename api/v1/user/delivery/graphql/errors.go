@@ -0,0 +1,113 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package graphql
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	usr "github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+
+	"context"
+	"errors"
+
+	"github.com/moemoe89/go-localization"
+)
+
+type contextKey string
+
+// localeKey represent the context key of Accept-Language header value
+const localeKey contextKey = "locale"
+
+// localeFromContext will get the requested locale stored by Handler
+func localeFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	l, _ := ctx.Value(localeKey).(string)
+	return l
+}
+
+// validationError represent the graphql error carrying field-level validation problems as extensions
+type validationError struct {
+	message string
+	details []*model.ErrorDetail
+}
+
+// newValidationError will create an object that represent the validationError struct
+func newValidationError(lang *language.Config, locale string, errs form.ValidationErrors) *validationError {
+	e := &validationError{}
+	for _, fe := range errs {
+		msg := fe.Localize(lang, locale)
+		if len(e.message) == 0 {
+			e.message = msg
+		}
+		e.details = append(e.details, &model.ErrorDetail{
+			Field:  fe.Field,
+			Rule:   fe.Rule,
+			Params: fe.Params,
+			Detail: msg,
+		})
+	}
+
+	return e
+}
+
+func (e *validationError) Error() string {
+	return e.message
+}
+
+// Extensions will expose the stable error code and field-level problems in graphql error
+func (e *validationError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":   model.CodeValidationFailed,
+		"errors": e.details,
+	}
+}
+
+// codedError represent the graphql error carrying the stable error code as extensions
+type codedError struct {
+	message string
+	code    string
+}
+
+// newServiceError will create the localized graphql error of the error returned by service, the
+// unexpected error is never exposed
+func newServiceError(lang *language.Config, locale string, err error) error {
+	var validationErr *usr.ValidationError
+	if errors.As(err, &validationErr) {
+		return newValidationError(lang, locale, validationErr.Fields)
+	}
+
+	e := &codedError{message: "Oops! Something went wrong. Please try again later", code: model.CodeInternalError}
+	switch {
+	case errors.Is(err, usr.ErrValidation):
+		e.message, e.code = usr.ErrValidation.Error(), model.CodeValidationFailed
+	case errors.Is(err, usr.ErrNotFound):
+		e.message, e.code = usr.ErrNotFound.Error(), model.CodeUserNotFound
+	case errors.Is(err, usr.ErrEmailTaken):
+		e.message, e.code = usr.ErrEmailTaken.Error(), model.CodeConflict
+	case errors.Is(err, usr.ErrConflict):
+		e.message, e.code = usr.ErrConflict.Error(), model.CodeConflict
+	case errors.Is(err, usr.ErrUnavailable):
+		e.message, e.code = usr.ErrUnavailable.Error(), model.CodeServiceUnavailable
+	}
+	e.message = lang.Lookup(locale, e.message)
+
+	return e
+}
+
+func (e *codedError) Error() string {
+	return e.message
+}
+
+// Extensions will expose the stable error code in graphql error
+func (e *codedError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code": e.code,
+	}
+}
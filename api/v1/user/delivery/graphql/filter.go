@@ -0,0 +1,71 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package graphql
+
+import (
+	usr "github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+
+	"fmt"
+	"sort"
+)
+
+// filterFromArgs will convert the UserFilter input argument into user.Filter,
+// the column conditions and groups on the same level are joined with AND
+func filterFromArgs(args map[string]interface{}) *usr.Filter {
+	keys := []string{}
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	f := &usr.Filter{}
+	for _, key := range keys {
+		switch key {
+		case "AND":
+			f.And = append(f.And, filtersFromList(args[key])...)
+		case "OR":
+			f.Or = append(f.Or, filtersFromList(args[key])...)
+		case "NOT":
+			if not, ok := args[key].(map[string]interface{}); ok {
+				f.Not = filterFromArgs(not)
+			}
+		default:
+			ops, _ := args[key].(map[string]interface{})
+			opKeys := []string{}
+			for op := range ops {
+				opKeys = append(opKeys, op)
+			}
+			sort.Strings(opKeys)
+
+			for _, op := range opKeys {
+				cond := &usr.Filter{Field: key, Op: op, Value: ops[op]}
+				if list, ok := ops[op].([]interface{}); ok {
+					values := []string{}
+					for _, v := range list {
+						values = append(values, fmt.Sprint(v))
+					}
+					cond.Value = values
+				}
+				f.And = append(f.And, cond)
+			}
+		}
+	}
+
+	return f
+}
+
+func filtersFromList(value interface{}) []*usr.Filter {
+	filters := []*usr.Filter{}
+	list, _ := value.([]interface{})
+	for _, item := range list {
+		if args, ok := item.(map[string]interface{}); ok {
+			filters = append(filters, filterFromArgs(args))
+		}
+	}
+
+	return filters
+}
@@ -9,14 +9,17 @@ package graphql
 import (
 	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
 
+	"context"
+
 	"github.com/gin-gonic/gin"
 	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/handler"
+	"github.com/moemoe89/go-localization"
 )
 
 // Handler initializes the graphql middleware.
-func Handler(userSvc user.Service) gin.HandlerFunc {
-	schema := NewSchema(NewResolver(userSvc))
+func Handler(lang *language.Config, userSvc user.Service) gin.HandlerFunc {
+	schema := NewSchema(NewResolver(lang, userSvc))
 	graphqlSchema, err := graphql.NewSchema(graphql.SchemaConfig{
 		Query:    schema.Query(),
 		Mutation: schema.Mutation(),
@@ -32,6 +35,7 @@ func Handler(userSvc user.Service) gin.HandlerFunc {
 	})
 
 	return func(c *gin.Context) {
-		h.ServeHTTP(c.Writer, c.Request)
+		ctx := context.WithValue(c.Request.Context(), localeKey, c.Request.Header.Get("Accept-Language"))
+		h.ContextHandler(ctx, c.Writer, c.Request)
 	}
 }
@@ -11,12 +11,12 @@ import (
 	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
 	usr "github.com/moemoe89/go-graphql-gendhis/api/v1/user"
 
-	"errors"
 	"math"
 	"strings"
 
 	"github.com/graphql-go/graphql"
 	"github.com/moemoe89/go-helpers"
+	"github.com/moemoe89/go-localization"
 	"github.com/rs/xid"
 )
 
@@ -24,6 +24,7 @@ import (
 type Resolver interface {
 	List(params graphql.ResolveParams) (interface{}, error)
 	Detail(params graphql.ResolveParams) (interface{}, error)
+	Search(params graphql.ResolveParams) (interface{}, error)
 
 	Update(params graphql.ResolveParams) (interface{}, error)
 	Create(params graphql.ResolveParams) (interface{}, error)
@@ -39,79 +40,55 @@ type UserList struct {
 	TotalData int
 }
 
+// UserSearchList holds information of users full-text search list.
+type UserSearchList struct {
+	List      []*model.UserSearchModel `json:"list"`
+	Page      int                      `json:"page"`
+	PerPage   int                      `json:"per_page"`
+	TotalPage int                      `json:"total_page"`
+	TotalData int                      `json:"total_data"`
+}
+
 type resolver struct {
-	svc usr.Service
+	lang *language.Config
+	svc  usr.Service
 }
 
-func NewResolver(svc usr.Service) Resolver {
+func NewResolver(lang *language.Config, svc usr.Service) Resolver {
 	return &resolver{
-		svc: svc,
+		lang: lang,
+		svc:  svc,
 	}
 }
 
 func (r resolver) List(params graphql.ResolveParams) (interface{}, error) {
-	userModel := model.UserModel{}
-
 	offset, perPage, showPage, err := helpers.PaginationSetter(params.Args["per_page"].(string), params.Args["page"].(string))
 	if err != nil {
 		return nil, err
 	}
 
-	orderBy := params.Args["order_by"].(string)
-	orderBy = helpers.OrderByHandler(orderBy, "db", userModel)
-	if len(orderBy) < 1 {
-		orderBy = "created_at DESC"
-	}
-
-	where := "WHERE deleted_at IS NULL"
-	filter := map[string]interface{}{}
-
-	name := params.Args["name"].(string)
-	if len(name) > 0 {
-		where += " AND name LIKE :name"
-		filter["name"] = "%" + name + "%"
-	}
-
-	email := params.Args["email"].(string)
-	if len(email) > 0 {
-		where += " AND email LIKE :email"
-		filter["email"] = "%" + email + "%"
-	}
-
-	phone := params.Args["phone"].(string)
-	if len(phone) > 0 {
-		where += " AND phone LIKE :phone"
-		filter["phone"] = "%" + phone + "%"
-	}
-
-	createdAtStart := params.Args["created_at_start"].(string)
-	if len(createdAtStart) > 0 {
-		where += " AND created_at >= :created_at_start"
-		filter["created_at_start"] = createdAtStart
+	criteria := &usr.UserCriteria{
+		Sort:   usr.ParseSort(params.Args["order_by"].(string)),
+		Fields: usr.ParseFields(params.Args["select_field"].(string)),
+		Limit:  perPage,
+		Offset: offset,
 	}
+	region, _ := params.Args["region"].(string)
+	phone := form.PhoneFilter(params.Args["phone"].(string), region)
+	criteria.Match(params.Args["name"].(string), params.Args["email"].(string), phone, params.Args["created_at_start"].(string), params.Args["created_at_end"].(string))
 
-	createdAtEnd := params.Args["created_at_end"].(string)
-	if len(createdAtEnd) > 0 {
-		where += " AND created_at <= :created_at_end"
-		filter["created_at_end"] = createdAtEnd
+	filterArgs, ok := params.Args["filter"].(map[string]interface{})
+	if ok && len(filterArgs) > 0 {
+		criteria.And(filterFromArgs(filterArgs))
 	}
 
-	filterCount := filter
-	filter["limit"] = perPage
-	filter["offset"] = offset
-
-	selectField := model.UserSelectField
-	filterField := params.Args["select_field"].(string)
-	if len(filterField) > 0 {
-		res := helpers.CheckInTag(userModel, filterField, "db")
-		if len(res) > 0 {
-			selectField = strings.Join(res, ",")
-		}
+	if err := criteria.Validate(); err != nil {
+		return nil, err
 	}
 
-	users, count, _, err := r.svc.List(filter, filterCount, where, orderBy, selectField)
+	users, count, err := r.svc.List(params.Context, criteria)
 	if err != nil {
-		return nil, err
+		return nil, newServiceError(r.lang, localeFromContext(params.Context), err)
 	}
 
 	totalPage := int(math.Ceil(float64(count) / float64(perPage)))
@@ -129,13 +106,49 @@ func (r resolver) List(params graphql.ResolveParams) (interface{}, error) {
 func (r resolver) Detail(params graphql.ResolveParams) (interface{}, error) {
 	id := params.Args["id"].(string)
 
-	user, _, err := r.svc.Detail(id, "")
+	user, err := r.svc.Detail(params.Context, id, "")
 	if err != nil {
-		return nil, err
+		return nil, newServiceError(r.lang, localeFromContext(params.Context), err)
 	}
 	return user, nil
 }
 
+func (r resolver) Search(params graphql.ResolveParams) (interface{}, error) {
+	offset, perPage, showPage, err := helpers.PaginationSetter(params.Args["per_page"].(string), params.Args["page"].(string))
+	if err != nil {
+		return nil, err
+	}
+
+	req := &form.UserQueryForm{Query: strings.TrimSpace(params.Args["query"].(string))}
+	errs := req.Validate()
+	if len(errs) > 0 {
+		return nil, newValidationError(r.lang, localeFromContext(params.Context), errs)
+	}
+
+	criteria := &usr.UserCriteria{
+		Query:     req.Query,
+		Highlight: params.Args["highlight"].(bool),
+		Limit:     perPage,
+		Offset:    offset,
+	}
+
+	users, count, err := r.svc.Search(params.Context, criteria)
+	if err != nil {
+		return nil, newServiceError(r.lang, localeFromContext(params.Context), err)
+	}
+
+	totalPage := int(math.Ceil(float64(count) / float64(perPage)))
+
+	resp := &UserSearchList{}
+	resp.Page = showPage
+	resp.PerPage = perPage
+	resp.TotalPage = totalPage
+	resp.TotalData = count
+	resp.List = users
+
+	return resp, nil
+}
+
 func (r resolver) Update(params graphql.ResolveParams) (interface{}, error) {
 	id := params.Args["id"].(string)
 
@@ -145,15 +158,16 @@ func (r resolver) Update(params graphql.ResolveParams) (interface{}, error) {
 		Email:   params.Args["email"].(string),
 		Address: params.Args["address"].(string),
 	}
+	req.Region, _ = params.Args["region"].(string)
 
 	errs := req.Validate()
 	if len(errs) > 0 {
-		return nil, errors.New(errs[0])
+		return nil, newValidationError(r.lang, localeFromContext(params.Context), errs)
 	}
 
-	user, _, err := r.svc.Update(req, id)
+	user, err := r.svc.Update(params.Context, req, id)
 	if err != nil {
-		return nil, err
+		return nil, newServiceError(r.lang, localeFromContext(params.Context), err)
 	}
 
 	return user, nil
@@ -167,15 +181,16 @@ func (r resolver) Create(params graphql.ResolveParams) (interface{}, error) {
 		Email:   params.Args["email"].(string),
 		Address: params.Args["address"].(string),
 	}
+	req.Region, _ = params.Args["region"].(string)
 
 	errs := req.Validate()
 	if len(errs) > 0 {
-		return nil, errors.New(errs[0])
+		return nil, newValidationError(r.lang, localeFromContext(params.Context), errs)
 	}
 
-	user, _, err := r.svc.Create(req)
+	user, err := r.svc.Create(params.Context, req)
 	if err != nil {
-		return nil, err
+		return nil, newServiceError(r.lang, localeFromContext(params.Context), err)
 	}
 
 	return user, nil
@@ -184,9 +199,9 @@ func (r resolver) Create(params graphql.ResolveParams) (interface{}, error) {
 func (r resolver) Delete(params graphql.ResolveParams) (interface{}, error) {
 	id := params.Args["id"].(string)
 
-	_, err := r.svc.Delete(id)
+	err := r.svc.Delete(params.Context, id)
 	if err != nil {
-		return nil, err
+		return nil, newServiceError(r.lang, localeFromContext(params.Context), err)
 	}
 
 	return id, nil
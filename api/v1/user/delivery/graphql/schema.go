@@ -7,6 +7,8 @@
 package graphql
 
 import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
 	"github.com/graphql-go/graphql"
 )
 
@@ -27,6 +29,9 @@ var UserGraphQL = graphql.NewObject(
 			"phone": &graphql.Field{
 				Type: graphql.String,
 			},
+			"phone_raw": &graphql.Field{
+				Type: graphql.String,
+			},
 			"address": &graphql.Field{
 				Type: graphql.String,
 			},
@@ -64,6 +69,142 @@ var UserListGraphQL = graphql.NewObject(
 	},
 )
 
+// UserSearchResultGraphQL holds user full-text search result information with graphql object
+var UserSearchResultGraphQL = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "UserSearchResult",
+		Fields: graphql.Fields{
+			"user": &graphql.Field{
+				Type: UserGraphQL,
+				Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+					if result, ok := params.Source.(*model.UserSearchModel); ok {
+						return &result.UserModel, nil
+					}
+					return nil, nil
+				},
+			},
+			"rank": &graphql.Field{
+				Type: graphql.Float,
+			},
+			"highlight": &graphql.Field{
+				Type: graphql.String,
+			},
+		},
+	},
+)
+
+// UserSearchListGraphQL holds user full-text search list information with graphql object
+var UserSearchListGraphQL = graphql.NewObject(
+	graphql.ObjectConfig{
+		Name: "UserSearchList",
+		Fields: graphql.Fields{
+			"list": &graphql.Field{
+				Type: graphql.NewList(UserSearchResultGraphQL),
+			},
+			"page": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"per_page": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"total_page": &graphql.Field{
+				Type: graphql.Int,
+			},
+			"total_data": &graphql.Field{
+				Type: graphql.Int,
+			},
+		},
+	},
+)
+
+// FieldFilterGraphQL holds the operators of single column filter with graphql input object
+var FieldFilterGraphQL = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "FieldFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"eq": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"ne": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"gt": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"gte": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"lt": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"lte": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"in": &graphql.InputObjectFieldConfig{
+				Type: graphql.NewList(graphql.String),
+			},
+			"contains": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"startswith": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"endswith": &graphql.InputObjectFieldConfig{
+				Type: graphql.String,
+			},
+			"isnull": &graphql.InputObjectFieldConfig{
+				Type: graphql.Boolean,
+			},
+		},
+	},
+)
+
+// UserFilterGraphQL holds user filter expression with graphql input object
+var UserFilterGraphQL = graphql.NewInputObject(
+	graphql.InputObjectConfig{
+		Name: "UserFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"id": &graphql.InputObjectFieldConfig{
+				Type: FieldFilterGraphQL,
+			},
+			"name": &graphql.InputObjectFieldConfig{
+				Type: FieldFilterGraphQL,
+			},
+			"email": &graphql.InputObjectFieldConfig{
+				Type: FieldFilterGraphQL,
+			},
+			"phone": &graphql.InputObjectFieldConfig{
+				Type: FieldFilterGraphQL,
+			},
+			"phone_raw": &graphql.InputObjectFieldConfig{
+				Type: FieldFilterGraphQL,
+			},
+			"address": &graphql.InputObjectFieldConfig{
+				Type: FieldFilterGraphQL,
+			},
+			"created_at": &graphql.InputObjectFieldConfig{
+				Type: FieldFilterGraphQL,
+			},
+			"updated_at": &graphql.InputObjectFieldConfig{
+				Type: FieldFilterGraphQL,
+			},
+		},
+	},
+)
+
+func init() {
+	// the logical groups refer to UserFilter itself, so they are added after it is declared
+	UserFilterGraphQL.AddFieldConfig("AND", &graphql.InputObjectFieldConfig{
+		Type: graphql.NewList(UserFilterGraphQL),
+	})
+	UserFilterGraphQL.AddFieldConfig("OR", &graphql.InputObjectFieldConfig{
+		Type: graphql.NewList(UserFilterGraphQL),
+	})
+	UserFilterGraphQL.AddFieldConfig("NOT", &graphql.InputObjectFieldConfig{
+		Type: UserFilterGraphQL,
+	})
+}
+
 // Schema is struct which has method for Query and Mutation. Please init this struct using constructor function.
 type Schema struct {
 	userResolver Resolver
@@ -103,6 +244,10 @@ func (s Schema) Query() *graphql.Object {
 					"phone": &graphql.ArgumentConfig{
 						Type: graphql.String,
 					},
+					"region": &graphql.ArgumentConfig{
+						Type:        graphql.String,
+						Description: "Region of national phone number",
+					},
 					"created_at_start": &graphql.ArgumentConfig{
 						Type: graphql.String,
 					},
@@ -112,9 +257,34 @@ func (s Schema) Query() *graphql.Object {
 					"select_field": &graphql.ArgumentConfig{
 						Type: graphql.String,
 					},
+					"filter": &graphql.ArgumentConfig{
+						Type: UserFilterGraphQL,
+					},
 				},
 				Resolve: s.userResolver.List,
 			},
+			"search": &graphql.Field{
+				Type:        UserSearchListGraphQL,
+				Description: "Full-text search user by name, email and address ranked by relevance",
+				Args: graphql.FieldConfigArgument{
+					"query": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"highlight": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						DefaultValue: false,
+					},
+					"per_page": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+					"page": &graphql.ArgumentConfig{
+						Type:         graphql.String,
+						DefaultValue: "",
+					},
+				},
+				Resolve: s.userResolver.Search,
+			},
 			"Detail": &graphql.Field{
 				Type:        UserGraphQL,
 				Description: "Get detail user",
@@ -149,6 +319,10 @@ func (s Schema) Mutation() *graphql.Object {
 					"phone": &graphql.ArgumentConfig{
 						Type: graphql.String,
 					},
+					"region": &graphql.ArgumentConfig{
+						Type:        graphql.String,
+						Description: "Region of national phone number",
+					},
 					"email": &graphql.ArgumentConfig{
 						Type: graphql.String,
 					},
@@ -168,6 +342,10 @@ func (s Schema) Mutation() *graphql.Object {
 					"phone": &graphql.ArgumentConfig{
 						Type: graphql.String,
 					},
+					"region": &graphql.ArgumentConfig{
+						Type:        graphql.String,
+						Description: "Region of national phone number",
+					},
 					"email": &graphql.ArgumentConfig{
 						Type: graphql.String,
 					},
@@ -0,0 +1,738 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: user.proto
+
+package pb
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	empty "github.com/golang/protobuf/ptypes/empty"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// User represent the user
+type User struct {
+	Id        string               `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name      string               `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Email     string               `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	Phone     string               `protobuf:"bytes,4,opt,name=phone,proto3" json:"phone,omitempty"`
+	Address   string               `protobuf:"bytes,5,opt,name=address,proto3" json:"address,omitempty"`
+	CreatedAt *timestamp.Timestamp `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt *timestamp.Timestamp `protobuf:"bytes,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// phone_raw is the phone as it was given, phone is its E.164 form
+	PhoneRaw             string   `protobuf:"bytes,8,opt,name=phone_raw,json=phoneRaw,proto3" json:"phone_raw,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *User) Reset()         { *m = User{} }
+func (m *User) String() string { return proto.CompactTextString(m) }
+func (*User) ProtoMessage()    {}
+func (*User) Descriptor() ([]byte, []int) {
+	return fileDescriptor_116e343673f7ffaf, []int{0}
+}
+
+func (m *User) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_User.Unmarshal(m, b)
+}
+func (m *User) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_User.Marshal(b, m, deterministic)
+}
+func (m *User) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_User.Merge(m, src)
+}
+func (m *User) XXX_Size() int {
+	return xxx_messageInfo_User.Size(m)
+}
+func (m *User) XXX_DiscardUnknown() {
+	xxx_messageInfo_User.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_User proto.InternalMessageInfo
+
+func (m *User) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *User) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *User) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *User) GetPhone() string {
+	if m != nil {
+		return m.Phone
+	}
+	return ""
+}
+
+func (m *User) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *User) GetCreatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.CreatedAt
+	}
+	return nil
+}
+
+func (m *User) GetUpdatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return nil
+}
+
+func (m *User) GetPhoneRaw() string {
+	if m != nil {
+		return m.PhoneRaw
+	}
+	return ""
+}
+
+// CreateUserRequest represent the request of creating user
+type CreateUserRequest struct {
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Email   string `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	Phone   string `protobuf:"bytes,3,opt,name=phone,proto3" json:"phone,omitempty"`
+	Address string `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"`
+	// region is the ISO 3166-1 alpha-2 region of national phone, the configured phone_region when it's empty
+	Region               string   `protobuf:"bytes,5,opt,name=region,proto3" json:"region,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CreateUserRequest) Reset()         { *m = CreateUserRequest{} }
+func (m *CreateUserRequest) String() string { return proto.CompactTextString(m) }
+func (*CreateUserRequest) ProtoMessage()    {}
+func (*CreateUserRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_116e343673f7ffaf, []int{1}
+}
+
+func (m *CreateUserRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CreateUserRequest.Unmarshal(m, b)
+}
+func (m *CreateUserRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CreateUserRequest.Marshal(b, m, deterministic)
+}
+func (m *CreateUserRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CreateUserRequest.Merge(m, src)
+}
+func (m *CreateUserRequest) XXX_Size() int {
+	return xxx_messageInfo_CreateUserRequest.Size(m)
+}
+func (m *CreateUserRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CreateUserRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CreateUserRequest proto.InternalMessageInfo
+
+func (m *CreateUserRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CreateUserRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *CreateUserRequest) GetPhone() string {
+	if m != nil {
+		return m.Phone
+	}
+	return ""
+}
+
+func (m *CreateUserRequest) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *CreateUserRequest) GetRegion() string {
+	if m != nil {
+		return m.Region
+	}
+	return ""
+}
+
+// GetUserRequest represent the request of getting user by id
+type GetUserRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetUserRequest) Reset()         { *m = GetUserRequest{} }
+func (m *GetUserRequest) String() string { return proto.CompactTextString(m) }
+func (*GetUserRequest) ProtoMessage()    {}
+func (*GetUserRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_116e343673f7ffaf, []int{2}
+}
+
+func (m *GetUserRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetUserRequest.Unmarshal(m, b)
+}
+func (m *GetUserRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetUserRequest.Marshal(b, m, deterministic)
+}
+func (m *GetUserRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetUserRequest.Merge(m, src)
+}
+func (m *GetUserRequest) XXX_Size() int {
+	return xxx_messageInfo_GetUserRequest.Size(m)
+}
+func (m *GetUserRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetUserRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetUserRequest proto.InternalMessageInfo
+
+func (m *GetUserRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+// ListUsersRequest represent the request of listing users, every user is streamed when page_size is 0
+type ListUsersRequest struct {
+	// filter is the filter expression like `email:endswith:@acme.com,created_at:gte:2024-01-01`
+	Filter string `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// order_by is the sort column, prefixed with - for descending
+	OrderBy string `protobuf:"bytes,2,opt,name=order_by,json=orderBy,proto3" json:"order_by,omitempty"`
+	// select_field is the comma separated columns to return
+	SelectField          string   `protobuf:"bytes,3,opt,name=select_field,json=selectField,proto3" json:"select_field,omitempty"`
+	PageSize             int32    `protobuf:"varint,4,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	Page                 int32    `protobuf:"varint,5,opt,name=page,proto3" json:"page,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListUsersRequest) Reset()         { *m = ListUsersRequest{} }
+func (m *ListUsersRequest) String() string { return proto.CompactTextString(m) }
+func (*ListUsersRequest) ProtoMessage()    {}
+func (*ListUsersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_116e343673f7ffaf, []int{3}
+}
+
+func (m *ListUsersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListUsersRequest.Unmarshal(m, b)
+}
+func (m *ListUsersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListUsersRequest.Marshal(b, m, deterministic)
+}
+func (m *ListUsersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListUsersRequest.Merge(m, src)
+}
+func (m *ListUsersRequest) XXX_Size() int {
+	return xxx_messageInfo_ListUsersRequest.Size(m)
+}
+func (m *ListUsersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListUsersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListUsersRequest proto.InternalMessageInfo
+
+func (m *ListUsersRequest) GetFilter() string {
+	if m != nil {
+		return m.Filter
+	}
+	return ""
+}
+
+func (m *ListUsersRequest) GetOrderBy() string {
+	if m != nil {
+		return m.OrderBy
+	}
+	return ""
+}
+
+func (m *ListUsersRequest) GetSelectField() string {
+	if m != nil {
+		return m.SelectField
+	}
+	return ""
+}
+
+func (m *ListUsersRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *ListUsersRequest) GetPage() int32 {
+	if m != nil {
+		return m.Page
+	}
+	return 0
+}
+
+// UpdateUserRequest represent the request of updating user
+type UpdateUserRequest struct {
+	Id      string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name    string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Email   string `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	Phone   string `protobuf:"bytes,4,opt,name=phone,proto3" json:"phone,omitempty"`
+	Address string `protobuf:"bytes,5,opt,name=address,proto3" json:"address,omitempty"`
+	// region is the ISO 3166-1 alpha-2 region of national phone, the configured phone_region when it's empty
+	Region               string   `protobuf:"bytes,6,opt,name=region,proto3" json:"region,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateUserRequest) Reset()         { *m = UpdateUserRequest{} }
+func (m *UpdateUserRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateUserRequest) ProtoMessage()    {}
+func (*UpdateUserRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_116e343673f7ffaf, []int{4}
+}
+
+func (m *UpdateUserRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateUserRequest.Unmarshal(m, b)
+}
+func (m *UpdateUserRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateUserRequest.Marshal(b, m, deterministic)
+}
+func (m *UpdateUserRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateUserRequest.Merge(m, src)
+}
+func (m *UpdateUserRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateUserRequest.Size(m)
+}
+func (m *UpdateUserRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateUserRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateUserRequest proto.InternalMessageInfo
+
+func (m *UpdateUserRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *UpdateUserRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *UpdateUserRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *UpdateUserRequest) GetPhone() string {
+	if m != nil {
+		return m.Phone
+	}
+	return ""
+}
+
+func (m *UpdateUserRequest) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *UpdateUserRequest) GetRegion() string {
+	if m != nil {
+		return m.Region
+	}
+	return ""
+}
+
+// DeleteUserRequest represent the request of deleting user by id
+type DeleteUserRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteUserRequest) Reset()         { *m = DeleteUserRequest{} }
+func (m *DeleteUserRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteUserRequest) ProtoMessage()    {}
+func (*DeleteUserRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_116e343673f7ffaf, []int{5}
+}
+
+func (m *DeleteUserRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteUserRequest.Unmarshal(m, b)
+}
+func (m *DeleteUserRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteUserRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteUserRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteUserRequest.Merge(m, src)
+}
+func (m *DeleteUserRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteUserRequest.Size(m)
+}
+func (m *DeleteUserRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteUserRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteUserRequest proto.InternalMessageInfo
+
+func (m *DeleteUserRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*User)(nil), "user.v1.User")
+	proto.RegisterType((*CreateUserRequest)(nil), "user.v1.CreateUserRequest")
+	proto.RegisterType((*GetUserRequest)(nil), "user.v1.GetUserRequest")
+	proto.RegisterType((*ListUsersRequest)(nil), "user.v1.ListUsersRequest")
+	proto.RegisterType((*UpdateUserRequest)(nil), "user.v1.UpdateUserRequest")
+	proto.RegisterType((*DeleteUserRequest)(nil), "user.v1.DeleteUserRequest")
+}
+
+func init() {
+	proto.RegisterFile("user.proto", fileDescriptor_116e343673f7ffaf)
+}
+
+var fileDescriptor_116e343673f7ffaf = []byte{
+	// 543 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x94, 0xc1, 0x73, 0xd2, 0x4e,
+	0x14, 0xc7, 0x27, 0x01, 0x02, 0x3c, 0x7e, 0xbf, 0x8e, 0xec, 0x38, 0x98, 0xa6, 0x07, 0x11, 0x2f,
+	0xbd, 0x90, 0x58, 0x7a, 0xb1, 0xa3, 0x97, 0x56, 0x6d, 0x3d, 0x78, 0x4a, 0xed, 0xc5, 0x0b, 0xb3,
+	0x21, 0x8f, 0xb0, 0x33, 0x09, 0x9b, 0xee, 0x2e, 0x74, 0xe8, 0xd5, 0xf1, 0x4f, 0x70, 0x46, 0xff,
+	0x5b, 0x67, 0x37, 0x01, 0x21, 0xd4, 0x7a, 0xf2, 0xb6, 0xef, 0xbb, 0xdf, 0xc7, 0x7e, 0xf9, 0xbc,
+	0xdd, 0x00, 0x2c, 0x24, 0x0a, 0x3f, 0x17, 0x5c, 0x71, 0xd2, 0x34, 0xeb, 0xe5, 0x89, 0x77, 0x94,
+	0x70, 0x9e, 0xa4, 0x18, 0x18, 0x39, 0x5a, 0x4c, 0x03, 0xcc, 0x72, 0xb5, 0x2a, 0x5c, 0xde, 0xf3,
+	0xea, 0xa6, 0x62, 0x19, 0x4a, 0x45, 0xb3, 0xbc, 0x30, 0x0c, 0xbe, 0xda, 0x50, 0xbf, 0x91, 0x28,
+	0xc8, 0x01, 0xd8, 0x2c, 0x76, 0xad, 0xbe, 0x75, 0xdc, 0x0e, 0x6d, 0x16, 0x13, 0x02, 0xf5, 0x39,
+	0xcd, 0xd0, 0xb5, 0x8d, 0x62, 0xd6, 0xe4, 0x29, 0x34, 0x30, 0xa3, 0x2c, 0x75, 0x6b, 0x46, 0x2c,
+	0x0a, 0xad, 0xe6, 0x33, 0x3e, 0x47, 0xb7, 0x5e, 0xa8, 0xa6, 0x20, 0x2e, 0x34, 0x69, 0x1c, 0x0b,
+	0x94, 0xd2, 0x6d, 0x18, 0x7d, 0x5d, 0x92, 0x33, 0x80, 0x89, 0x40, 0xaa, 0x30, 0x1e, 0x53, 0xe5,
+	0x3a, 0x7d, 0xeb, 0xb8, 0x33, 0xf2, 0xfc, 0x22, 0xa8, 0xbf, 0x0e, 0xea, 0x7f, 0x5e, 0x07, 0x0d,
+	0xdb, 0xa5, 0xfb, 0x5c, 0xe9, 0xd6, 0x45, 0x1e, 0xaf, 0x5b, 0x9b, 0x7f, 0x6f, 0x2d, 0xdd, 0xe7,
+	0x8a, 0x1c, 0x41, 0xdb, 0x04, 0x1b, 0x0b, 0x7a, 0xe7, 0xb6, 0x4c, 0xa2, 0x96, 0x11, 0x42, 0x7a,
+	0x37, 0xf8, 0x66, 0x41, 0xf7, 0x9d, 0x39, 0x45, 0xb3, 0x08, 0xf1, 0x76, 0x81, 0x52, 0x6d, 0x10,
+	0x58, 0x0f, 0x21, 0xb0, 0x1f, 0x44, 0x50, 0xfb, 0x03, 0x82, 0xfa, 0x2e, 0x82, 0x1e, 0x38, 0x02,
+	0x13, 0xc6, 0xe7, 0x25, 0x9b, 0xb2, 0x1a, 0xf4, 0xe1, 0xe0, 0x0a, 0xd5, 0x76, 0x86, 0xca, 0x58,
+	0x06, 0x3f, 0x2d, 0x78, 0xf2, 0x89, 0x49, 0xe3, 0x91, 0x6b, 0x53, 0x0f, 0x9c, 0x29, 0x4b, 0x15,
+	0x8a, 0xd2, 0x58, 0x56, 0xe4, 0x10, 0x5a, 0x5c, 0xc4, 0x28, 0xc6, 0xd1, 0xaa, 0xcc, 0xdb, 0x34,
+	0xf5, 0xc5, 0x8a, 0xbc, 0x80, 0xff, 0x24, 0xa6, 0x38, 0x51, 0xe3, 0x29, 0xc3, 0x34, 0x2e, 0x83,
+	0x77, 0x0a, 0xed, 0x52, 0x4b, 0x86, 0x18, 0x4d, 0x70, 0x2c, 0xd9, 0x7d, 0x31, 0xdb, 0x46, 0xd8,
+	0xd2, 0xc2, 0x35, 0xbb, 0x47, 0xcd, 0x46, 0xaf, 0x4d, 0xfe, 0x46, 0x68, 0xd6, 0x83, 0xef, 0x16,
+	0x74, 0x6f, 0x0c, 0xf0, 0x47, 0xfe, 0xc1, 0x3f, 0xbc, 0x58, 0xbf, 0xa9, 0x3a, 0x3b, 0x54, 0x5f,
+	0x42, 0xf7, 0x3d, 0xa6, 0xf8, 0x68, 0xac, 0xd1, 0x0f, 0x1b, 0x3a, 0x7a, 0xff, 0x1a, 0xc5, 0x92,
+	0x4d, 0x90, 0x9c, 0x82, 0x53, 0xdc, 0x08, 0xe2, 0xf9, 0xe5, 0x53, 0xf3, 0xf7, 0xae, 0x88, 0xf7,
+	0xff, 0x66, 0x4f, 0xab, 0x64, 0x08, 0xb5, 0x2b, 0x54, 0xe4, 0xd9, 0x46, 0xdd, 0x9d, 0x66, 0xd5,
+	0x3e, 0x82, 0xba, 0x9e, 0x25, 0x39, 0xdc, 0xc8, 0xd5, 0xd1, 0x56, 0x3a, 0x5e, 0x59, 0x3a, 0x57,
+	0xc1, 0x78, 0x2b, 0xd7, 0x1e, 0xf4, 0xea, 0x41, 0x6f, 0xc1, 0x29, 0x08, 0x6c, 0x35, 0xed, 0x21,
+	0xf1, 0x7a, 0x7b, 0x2f, 0xe9, 0x83, 0xfe, 0x94, 0x5c, 0x7c, 0xfc, 0x72, 0x99, 0x30, 0x35, 0x5b,
+	0x44, 0xfe, 0x84, 0x67, 0x41, 0xc6, 0x31, 0xe3, 0xf8, 0xfa, 0x2c, 0x48, 0xf8, 0x30, 0x11, 0x34,
+	0x9f, 0xdd, 0xa6, 0xc3, 0x04, 0xe7, 0xf1, 0x8c, 0xc9, 0x80, 0xe6, 0x2c, 0x58, 0x9e, 0x04, 0xfa,
+	0x88, 0x20, 0xc6, 0x94, 0x2d, 0x51, 0xac, 0x82, 0x44, 0xe4, 0x93, 0x20, 0x8f, 0xde, 0xe4, 0x51,
+	0xe4, 0x98, 0x5f, 0x3e, 0xfd, 0x35, 0x00, 0xd2, 0x89, 0x05, 0x5b, 0xc9, 0x04, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// UserServiceClient is the client API for UserService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type UserServiceClient interface {
+	Create(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*User, error)
+	Get(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error)
+	// List streams every user matching the request, newest first by default
+	List(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (UserService_ListClient, error)
+	Update(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*User, error)
+	Delete(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+}
+
+type userServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewUserServiceClient(cc grpc.ClientConnInterface) UserServiceClient {
+	return &userServiceClient{cc}
+}
+
+func (c *userServiceClient) Create(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/user.v1.UserService/Create", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) Get(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/user.v1.UserService/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) List(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (UserService_ListClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_UserService_serviceDesc.Streams[0], "/user.v1.UserService/List", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &userServiceListClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type UserService_ListClient interface {
+	Recv() (*User, error)
+	grpc.ClientStream
+}
+
+type userServiceListClient struct {
+	grpc.ClientStream
+}
+
+func (x *userServiceListClient) Recv() (*User, error) {
+	m := new(User)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *userServiceClient) Update(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*User, error) {
+	out := new(User)
+	err := c.cc.Invoke(ctx, "/user.v1.UserService/Update", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) Delete(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/user.v1.UserService/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserServiceServer is the server API for UserService service.
+type UserServiceServer interface {
+	Create(context.Context, *CreateUserRequest) (*User, error)
+	Get(context.Context, *GetUserRequest) (*User, error)
+	// List streams every user matching the request, newest first by default
+	List(*ListUsersRequest, UserService_ListServer) error
+	Update(context.Context, *UpdateUserRequest) (*User, error)
+	Delete(context.Context, *DeleteUserRequest) (*empty.Empty, error)
+}
+
+// UnimplementedUserServiceServer can be embedded to have forward compatible implementations.
+type UnimplementedUserServiceServer struct {
+}
+
+func (*UnimplementedUserServiceServer) Create(ctx context.Context, req *CreateUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (*UnimplementedUserServiceServer) Get(ctx context.Context, req *GetUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (*UnimplementedUserServiceServer) List(req *ListUsersRequest, srv UserService_ListServer) error {
+	return status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (*UnimplementedUserServiceServer) Update(ctx context.Context, req *UpdateUserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (*UnimplementedUserServiceServer) Delete(ctx context.Context, req *DeleteUserRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+
+func RegisterUserServiceServer(s *grpc.Server, srv UserServiceServer) {
+	s.RegisterService(&_UserService_serviceDesc, srv)
+}
+
+func _UserService_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/user.v1.UserService/Create",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).Create(ctx, req.(*CreateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/user.v1.UserService/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).Get(ctx, req.(*GetUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_List_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListUsersRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UserServiceServer).List(m, &userServiceListServer{stream})
+}
+
+type UserService_ListServer interface {
+	Send(*User) error
+	grpc.ServerStream
+}
+
+type userServiceListServer struct {
+	grpc.ServerStream
+}
+
+func (x *userServiceListServer) Send(m *User) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _UserService_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/user.v1.UserService/Update",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).Update(ctx, req.(*UpdateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/user.v1.UserService/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).Delete(ctx, req.(*DeleteUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _UserService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "user.v1.UserService",
+	HandlerType: (*UserServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Create",
+			Handler:    _UserService_Create_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _UserService_Get_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _UserService_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _UserService_Delete_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "List",
+			Handler:       _UserService_List_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "user.proto",
+}
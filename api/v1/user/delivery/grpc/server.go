@@ -0,0 +1,259 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package grpc
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	usr "github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user/delivery/grpc/pb"
+
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/moemoe89/go-helpers"
+	"github.com/moemoe89/go-localization"
+	"github.com/rs/xid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// localeMetadata represent the metadata key of requested language, the same as Accept-Language header
+const localeMetadata = "accept-language"
+
+type userServer struct {
+	lang *language.Config
+	log  *logrus.Entry
+	svc  usr.Service
+}
+
+// NewUserServer will create an object that represent the pb.UserServiceServer interface
+func NewUserServer(lang *language.Config, log *logrus.Entry, svc usr.Service) pb.UserServiceServer {
+	return &userServer{lang, log, svc}
+}
+
+// NewServer will create the grpc server serving the user service
+func NewServer(lang *language.Config, log *logrus.Entry, svc usr.Service) *grpc.Server {
+	s := grpc.NewServer()
+	pb.RegisterUserServiceServer(s, NewUserServer(lang, log, svc))
+	return s
+}
+
+func (u *userServer) Create(ctx context.Context, in *pb.CreateUserRequest) (*pb.User, error) {
+	req := &form.UserForm{
+		Name:    in.GetName(),
+		Email:   in.GetEmail(),
+		Phone:   in.GetPhone(),
+		Region:  in.GetRegion(),
+		Address: in.GetAddress(),
+	}
+
+	errs := req.Validate()
+	if len(errs) > 0 {
+		return nil, u.validationError(ctx, errs)
+	}
+
+	req.ID = xid.New().String()
+	user, err := u.svc.Create(ctx, req)
+	if err != nil {
+		return nil, u.serviceError(ctx, err)
+	}
+
+	return toProto(user), nil
+}
+
+func (u *userServer) Get(ctx context.Context, in *pb.GetUserRequest) (*pb.User, error) {
+	user, err := u.svc.Detail(ctx, in.GetId(), model.UserSelectField)
+	if err != nil {
+		return nil, u.serviceError(ctx, err)
+	}
+
+	return toProto(user), nil
+}
+
+// List will stream the page of users when page_size is set, otherwise every matching user is
+// streamed from the export cursor
+func (u *userServer) List(in *pb.ListUsersRequest, stream pb.UserService_ListServer) error {
+	ctx := stream.Context()
+
+	criteria := &usr.UserCriteria{
+		Sort:   usr.ParseSort(in.GetOrderBy()),
+		Fields: usr.ParseFields(in.GetSelectField()),
+	}
+
+	if len(in.GetFilter()) > 0 {
+		f, err := usr.ParseFilter(in.GetFilter())
+		if err != nil {
+			u.log.Errorf("can't parse filter: %s", err.Error())
+			return u.error(ctx, codes.InvalidArgument, usr.ErrInvalidFilter.Error())
+		}
+		criteria.And(f)
+	}
+
+	if err := criteria.Validate(); err != nil {
+		u.log.Errorf("can't compile filter: %s", err.Error())
+		return u.error(ctx, codes.InvalidArgument, usr.ErrInvalidFilter.Error())
+	}
+
+	send := func(user *model.UserModel) error {
+		return stream.Send(toProto(user))
+	}
+
+	if in.GetPageSize() < 1 {
+		if err := u.svc.Export(ctx, criteria, send); err != nil {
+			return u.serviceError(ctx, err)
+		}
+		return nil
+	}
+
+	offset, perPage, _, err := helpers.PaginationSetter(strconv.Itoa(int(in.GetPageSize())), strconv.Itoa(int(in.GetPage())))
+	if err != nil {
+		return u.error(ctx, codes.InvalidArgument, err.Error())
+	}
+	criteria.Limit = perPage
+	criteria.Offset = offset
+
+	users, err := u.svc.ListWithoutCount(ctx, criteria)
+	if err != nil {
+		return u.serviceError(ctx, err)
+	}
+
+	for _, user := range users {
+		if err := send(user); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u *userServer) Update(ctx context.Context, in *pb.UpdateUserRequest) (*pb.User, error) {
+	req := &form.UserForm{
+		ID:      in.GetId(),
+		Name:    in.GetName(),
+		Email:   in.GetEmail(),
+		Phone:   in.GetPhone(),
+		Region:  in.GetRegion(),
+		Address: in.GetAddress(),
+	}
+
+	errs := req.Validate()
+	if len(errs) > 0 {
+		return nil, u.validationError(ctx, errs)
+	}
+
+	user, err := u.svc.Update(ctx, req, in.GetId())
+	if err != nil {
+		return nil, u.serviceError(ctx, err)
+	}
+
+	return toProto(user), nil
+}
+
+func (u *userServer) Delete(ctx context.Context, in *pb.DeleteUserRequest) (*empty.Empty, error) {
+	err := u.svc.Delete(ctx, in.GetId())
+	if err != nil {
+		return nil, u.serviceError(ctx, err)
+	}
+
+	return &empty.Empty{}, nil
+}
+
+// locale will get the requested language from the metadata of call
+func locale(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get(localeMetadata)) == 0 {
+		return ""
+	}
+
+	return md.Get(localeMetadata)[0]
+}
+
+// error will create the grpc status error of the localized message key
+func (u *userServer) error(ctx context.Context, code codes.Code, message string) error {
+	return status.Error(code, u.lang.Lookup(locale(ctx), message))
+}
+
+// serviceError will create the grpc status error of the error returned by service, the unexpected
+// error is never exposed
+func (u *userServer) serviceError(ctx context.Context, err error) error {
+	var validationErr *usr.ValidationError
+	if errors.As(err, &validationErr) {
+		return u.validationError(ctx, validationErr.Fields)
+	}
+
+	message := "Oops! Something went wrong. Please try again later"
+	for _, kind := range []error{usr.ErrValidation, usr.ErrNotFound, usr.ErrEmailTaken, usr.ErrConflict, usr.ErrUnavailable} {
+		if errors.Is(err, kind) {
+			message = kind.Error()
+			break
+		}
+	}
+
+	return u.error(ctx, CodeFromError(err), message)
+}
+
+// validationError will create the invalid argument status carrying field violations as details
+func (u *userServer) validationError(ctx context.Context, errs form.ValidationErrors) error {
+	l := locale(ctx)
+
+	violations := []*errdetails.BadRequest_FieldViolation{}
+	for _, e := range errs {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       e.Field,
+			Description: e.Localize(u.lang, l),
+		})
+	}
+
+	st := status.New(codes.InvalidArgument, violations[0].Description)
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// CodeFromError will map the error returned by service into grpc code
+func CodeFromError(err error) codes.Code {
+	switch {
+	case errors.Is(err, usr.ErrValidation):
+		return codes.InvalidArgument
+	case errors.Is(err, usr.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, usr.ErrConflict):
+		return codes.AlreadyExists
+	case errors.Is(err, usr.ErrUnavailable):
+		return codes.Unavailable
+	}
+
+	return codes.Internal
+}
+
+// toProto will convert the user model into protobuf message
+func toProto(user *model.UserModel) *pb.User {
+	createdAt, _ := ptypes.TimestampProto(user.CreatedAt)
+	updatedAt, _ := ptypes.TimestampProto(user.UpdatedAt)
+
+	return &pb.User{
+		Id:        user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Phone:     user.Phone,
+		PhoneRaw:  user.PhoneRaw,
+		Address:   user.Address,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+	}
+}
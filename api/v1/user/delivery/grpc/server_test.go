@@ -0,0 +1,227 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package grpc_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	usr "github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+	usrGrpc "github.com/moemoe89/go-graphql-gendhis/api/v1/user/delivery/grpc"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user/delivery/grpc/pb"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user/mocks"
+	"github.com/moemoe89/go-graphql-gendhis/config"
+
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newClient will serve the user service on in-memory listener and dial it
+func newClient(t *testing.T, svc *mocks.Service) (pb.UserServiceClient, func()) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	lis := bufconn.Listen(1024 * 1024)
+	s := usrGrpc.NewServer(lang, log, svc)
+	go s.Serve(lis)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	assert.NoError(t, err)
+
+	return pb.NewUserServiceClient(conn), func() {
+		conn.Close()
+		s.Stop()
+	}
+}
+
+func TestServerCreate(t *testing.T) {
+	user := &model.UserModel{
+		ID:        xid.New().String(),
+		Name:      "Momo",
+		Email:     "momo@mail.com",
+		Phone:     "+819012345678",
+		PhoneRaw:  "090-1234-5678",
+		CreatedAt: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	mockService := new(mocks.Service)
+	mockService.On("Create", mock.Anything, mock.MatchedBy(func(req *form.UserForm) bool {
+		return req.Name == "Momo" && req.Region == "JP" && len(req.ID) > 0
+	})).Return(user, nil)
+
+	client, stop := newClient(t, mockService)
+	defer stop()
+
+	resp, err := client.Create(context.Background(), &pb.CreateUserRequest{Name: "Momo", Email: "momo@mail.com", Phone: "090-1234-5678", Region: "JP"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, resp.GetId())
+	assert.Equal(t, "+819012345678", resp.GetPhone())
+	assert.Equal(t, "090-1234-5678", resp.GetPhoneRaw())
+	assert.Equal(t, int64(1577934245), resp.GetCreatedAt().GetSeconds())
+}
+
+func TestServerCreateFailValidation(t *testing.T) {
+	mockService := new(mocks.Service)
+
+	client, stop := newClient(t, mockService)
+	defer stop()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "accept-language", "id")
+	_, err := client.Create(ctx, &pb.CreateUserRequest{Email: "momo"})
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Equal(t, "Nama tidak boleh kosong", st.Message())
+	assert.Len(t, st.Details(), 1)
+
+	badRequest := st.Details()[0].(*errdetails.BadRequest)
+	assert.Equal(t, "name", badRequest.GetFieldViolations()[0].GetField())
+	assert.Equal(t, "email", badRequest.GetFieldViolations()[1].GetField())
+	mockService.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestServerGetFail(t *testing.T) {
+	id := xid.New().String()
+
+	mockService := new(mocks.Service)
+	mockService.On("Detail", mock.Anything, id, model.UserSelectField).Return(nil, usr.ErrNotFound)
+
+	client, stop := newClient(t, mockService)
+	defer stop()
+
+	_, err := client.Get(context.Background(), &pb.GetUserRequest{Id: id})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServerList(t *testing.T) {
+	users := []*model.UserModel{
+		{ID: xid.New().String(), Name: "Momo"},
+		{ID: xid.New().String(), Name: "Gendhis"},
+	}
+
+	f, _ := usr.ParseFilter("email:endswith:@acme.com")
+	criteria := (&usr.UserCriteria{Sort: []usr.Sort{{Field: "name"}}, Fields: []string{"id", "name"}}).And(f)
+
+	mockService := new(mocks.Service)
+	mockService.On("Export", mock.Anything, criteria, mock.Anything).
+		Run(func(args mock.Arguments) {
+			fn := args.Get(2).(func(*model.UserModel) error)
+			for _, user := range users {
+				assert.NoError(t, fn(user))
+			}
+		}).
+		Return(nil)
+
+	client, stop := newClient(t, mockService)
+	defer stop()
+
+	stream, err := client.List(context.Background(), &pb.ListUsersRequest{Filter: "email:endswith:@acme.com", OrderBy: "name", SelectField: "id,name"})
+	assert.NoError(t, err)
+
+	names := []string{}
+	for {
+		user, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		names = append(names, user.GetName())
+	}
+
+	assert.Equal(t, []string{"Momo", "Gendhis"}, names)
+}
+
+func TestServerListPage(t *testing.T) {
+	user := &model.UserModel{ID: xid.New().String(), Name: "Momo"}
+
+	mockService := new(mocks.Service)
+	mockService.On("ListWithoutCount", mock.Anything, &usr.UserCriteria{Limit: 1, Offset: 1}).Return([]*model.UserModel{user}, nil)
+
+	client, stop := newClient(t, mockService)
+	defer stop()
+
+	stream, err := client.List(context.Background(), &pb.ListUsersRequest{PageSize: 1, Page: 2})
+	assert.NoError(t, err)
+
+	resp, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, resp.GetId())
+
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestServerListFailFilter(t *testing.T) {
+	mockService := new(mocks.Service)
+
+	client, stop := newClient(t, mockService)
+	defer stop()
+
+	stream, err := client.List(context.Background(), &pb.ListUsersRequest{Filter: "unknown:eq:1"})
+	assert.NoError(t, err)
+
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServerUpdate(t *testing.T) {
+	id := xid.New().String()
+	user := &model.UserModel{ID: id, Name: "Momo", Email: "momo@mail.com"}
+
+	mockService := new(mocks.Service)
+	mockService.On("Update", mock.Anything, mock.MatchedBy(func(req *form.UserForm) bool {
+		return req.Region == "JP"
+	}), id).Return(user, nil)
+
+	client, stop := newClient(t, mockService)
+	defer stop()
+
+	resp, err := client.Update(context.Background(), &pb.UpdateUserRequest{Id: id, Name: "Momo", Email: "momo@mail.com", Phone: "090-1234-5678", Region: "JP"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, id, resp.GetId())
+	mockService.AssertExpectations(t)
+}
+
+func TestServerDeleteFail(t *testing.T) {
+	id := xid.New().String()
+
+	mockService := new(mocks.Service)
+	mockService.On("Delete", mock.Anything, id).Return(errors.New("Unexpected database error"))
+
+	client, stop := newClient(t, mockService)
+	defer stop()
+
+	_, err := client.Delete(context.Background(), &pb.DeleteUserRequest{Id: id})
+
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestCodeFromError(t *testing.T) {
+	assert.Equal(t, codes.InvalidArgument, usrGrpc.CodeFromError(&usr.ValidationError{}))
+	assert.Equal(t, codes.NotFound, usrGrpc.CodeFromError(usr.ErrNotFound))
+	assert.Equal(t, codes.AlreadyExists, usrGrpc.CodeFromError(usr.NewError(usr.ErrConflict, errors.New("duplicate key"))))
+	assert.Equal(t, codes.Unavailable, usrGrpc.CodeFromError(usr.NewError(usr.ErrUnavailable, context.DeadlineExceeded)))
+	assert.Equal(t, codes.Internal, usrGrpc.CodeFromError(errors.New("Unexpected database error")))
+}
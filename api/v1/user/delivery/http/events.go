@@ -0,0 +1,95 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package http
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EventHeartbeat represent the interval of comment sent to keep idle event stream open through proxies
+var EventHeartbeat = 15 * time.Second
+
+//
+// @Summary User Events
+// @Description stream the user changes as Server-Sent Events user.created, user.updated and user.deleted
+// @Produce  text/event-stream
+// @Produce  application/problem+json
+// @Param Accept-Language header string false "language message response"
+// @Param Last-Event-ID header string false "Resume after the event id, replaying the buffered events"
+// @Param last_event_id query string false "Same as Last-Event-ID header, for clients that can't set it"
+// @Success 200 {string} string
+// @Failure 400 {object} model.GenericResponse
+// @Router /user/events [get]
+func (u *userCtrl) Events(c *gin.Context) {
+	lastEventID := c.Request.Header.Get("Last-Event-ID")
+	if len(lastEventID) < 1 {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	var lastID uint64
+	if len(lastEventID) > 0 {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			u.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, "Invalid Last-Event-ID: must be a number")
+			return
+		}
+		lastID = id
+	}
+
+	replay, events, cancel := u.svc.Subscribe(lastID)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, event := range replay {
+		u.writeEvent(c.Writer, event)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(EventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// the subscriber was dropped, the client resumes with the last received id
+				return
+			}
+			u.writeEvent(c.Writer, event)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeEvent will write the event in the Server-Sent Events format with the user as data
+func (u *userCtrl) writeEvent(w io.Writer, event *model.UserEvent) {
+	data, err := json.Marshal(event.User)
+	if err != nil {
+		u.log.Errorf("can't marshal user event: %s", err.Error())
+		return
+	}
+
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
@@ -0,0 +1,219 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package http
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	usr "github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ExportCSV represent the CSV format of users export
+	ExportCSV = "csv"
+	// ExportNDJSON represent the newline delimited JSON format of users export
+	ExportNDJSON = "ndjson"
+
+	// exportFlushRows represent the number of rows written before flushing to the client
+	exportFlushRows = 100
+)
+
+// exportHeaders holds the message keys of CSV header per column
+var exportHeaders = map[string]string{
+	"id":         "ID",
+	"name":       "Name",
+	"email":      "Email",
+	"phone":      "Phone",
+	"phone_raw":  "Raw Phone",
+	"address":    "Address",
+	"created_at": "Created At",
+	"updated_at": "Updated At",
+}
+
+//
+// @Summary User Export
+// @Description stream all user data matching the list filters as CSV or NDJSON
+// @Produce  text/csv
+// @Produce  application/x-ndjson
+// @Produce  application/problem+json
+// @Param Accept-Language header string false "Language of CSV header and message response"
+// @Param format query string false "Export format, csv (default) or ndjson"
+// @Param select_field query string false "Select field"
+// @Param order_by query string false "Sort by"
+// @Param filter query string false "Filter expression, e.g. email:endswith:@acme.com,created_at:gte:2024-01-01"
+// @Success 200 {string} string
+// @Failure 400 {object} model.GenericResponse
+// @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
+// @Router /user/export [get]
+func (u *userCtrl) Export(c *gin.Context) {
+	l := c.Request.Header.Get("Accept-Language")
+
+	format := c.DefaultQuery("format", ExportCSV)
+	if format != ExportCSV && format != ExportNDJSON {
+		u.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, "Invalid parameter format: must be csv or ndjson")
+		return
+	}
+
+	criteria, err := listCriteria(c)
+	if err != nil {
+		u.log.Errorf("can't get filter: %s", err.Error())
+		u.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, usr.ErrInvalidFilter.Error())
+		return
+	}
+
+	columns := criteria.Columns()
+	headers := []string{}
+	for _, col := range columns {
+		headers = append(headers, u.lang.Lookup(l, exportHeaders[col]))
+	}
+
+	e := &exporter{c: c, format: format, columns: columns, headers: headers}
+	err = u.svc.Export(c.Request.Context(), criteria, e.Write)
+	if err != nil {
+		// once rows are streamed the status is already sent, the broken stream is all the client gets
+		if !e.started {
+			u.serviceError(c, err)
+		}
+		return
+	}
+
+	if err := e.Close(); err != nil {
+		u.log.Errorf("can't write export: %s", err.Error())
+	}
+}
+
+// exporter will write the users into response as they are read from database
+type exporter struct {
+	c       *gin.Context
+	format  string
+	columns []string
+	headers []string
+
+	started bool
+	rows    int
+	csv     *csv.Writer
+	json    *json.Encoder
+}
+
+// start will write the response headers and the CSV header row before the first user
+func (e *exporter) start() error {
+	e.started = true
+
+	contentType := "text/csv; charset=utf-8"
+	if e.format == ExportNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	e.c.Header("Content-Type", contentType)
+	e.c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"users.%s\"", e.format))
+	e.c.Status(http.StatusOK)
+
+	if e.format == ExportNDJSON {
+		e.json = json.NewEncoder(e.c.Writer)
+		return nil
+	}
+
+	e.csv = csv.NewWriter(e.c.Writer)
+	return e.csv.Write(e.headers)
+}
+
+// Write will write the user as a row of the export
+func (e *exporter) Write(user *model.UserModel) error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	values := columnValues(user)
+
+	var err error
+	if e.format == ExportNDJSON {
+		row := map[string]interface{}{}
+		for _, col := range e.columns {
+			row[col] = values[col]
+		}
+		err = e.json.Encode(row)
+	} else {
+		record := []string{}
+		for _, col := range e.columns {
+			record = append(record, csvValue(values[col]))
+		}
+		err = e.csv.Write(record)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.rows++
+	if e.rows%exportFlushRows == 0 {
+		return e.flush()
+	}
+
+	return nil
+}
+
+// Close will finish the export, an export without rows still gets its headers
+func (e *exporter) Close() error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	return e.flush()
+}
+
+func (e *exporter) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	e.c.Writer.Flush()
+
+	return nil
+}
+
+// columnValues will map the db column into the value of user
+func columnValues(user *model.UserModel) map[string]interface{} {
+	values := map[string]interface{}{}
+	v := reflect.ValueOf(*user)
+	for i := 0; i < v.NumField(); i++ {
+		values[v.Type().Field(i).Tag.Get("db")] = v.Field(i).Interface()
+	}
+
+	return values
+}
+
+// csvFormulaPrefixes represent the leading characters which make spreadsheets evaluate the cell as formula
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvValue will format the value as a CSV field, the text starting like a formula is prefixed with ' so
+// spreadsheets show it as text
+func csvValue(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+
+	s := fmt.Sprint(v)
+	if len(s) > 0 && strings.ContainsRune(csvFormulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+
+	return s
+}
@@ -0,0 +1,179 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package http
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// negotiateFormat will choose the representation of user resources from Accept header
+func negotiateFormat(c *gin.Context) string {
+	return c.NegotiateFormat(gin.MIMEJSON, model.JSONAPIType, model.HALType)
+}
+
+// usersPath will get the path of users collection from the current request
+func usersPath(c *gin.Context) string {
+	path := c.Request.URL.Path
+	if id := c.Param("id"); len(id) > 0 {
+		path = strings.TrimSuffix(path, "/"+id)
+	}
+
+	return path
+}
+
+// queryURL will build the URL of the same request with the query params replaced
+func queryURL(c *gin.Context, params map[string]string) string {
+	link := *c.Request.URL
+	query := link.Query()
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	link.RawQuery = query.Encode()
+
+	return link.String()
+}
+
+// pageLinks will build the first, last, prev and next links of offset pagination
+func pageLinks(c *gin.Context, page, totalPage int) map[string]string {
+	links := map[string]string{
+		"self":  c.Request.URL.String(),
+		"first": queryURL(c, map[string]string{"page": "1"}),
+	}
+
+	if totalPage > 0 {
+		links["last"] = queryURL(c, map[string]string{"page": strconv.Itoa(totalPage)})
+	}
+	if page > 1 {
+		links["prev"] = queryURL(c, map[string]string{"page": strconv.Itoa(page - 1)})
+	}
+	if page < totalPage {
+		links["next"] = queryURL(c, map[string]string{"page": strconv.Itoa(page + 1)})
+	}
+
+	return links
+}
+
+// pageMeta will build the meta of offset pagination
+func pageMeta(p *model.PaginationResponse) map[string]interface{} {
+	return map[string]interface{}{
+		"total":      p.TotalData,
+		"page":       p.Page,
+		"per_page":   p.PerPage,
+		"total_page": p.TotalPage,
+	}
+}
+
+// attributes will flatten the user into its JSON fields, so embedded search fields are kept
+func attributes(user interface{}) (string, map[string]interface{}) {
+	attrs := map[string]interface{}{}
+	raw, _ := json.Marshal(user)
+	_ = json.Unmarshal(raw, &attrs)
+
+	id := fmt.Sprint(attrs["id"])
+	delete(attrs, "id")
+
+	return id, attrs
+}
+
+// jsonAPIResource will build the JSON:API resource of user honouring the sparse fieldset
+func jsonAPIResource(c *gin.Context, user interface{}) *model.JSONAPIResource {
+	id, attrs := attributes(user)
+
+	if fields, ok := c.GetQuery("fields[" + model.UserResourceType + "]"); ok {
+		sparse := map[string]interface{}{}
+		for _, f := range strings.Split(fields, ",") {
+			if v, ok := attrs[f]; ok {
+				sparse[f] = v
+			}
+		}
+		attrs = sparse
+	}
+
+	return &model.JSONAPIResource{
+		Type:       model.UserResourceType,
+		ID:         id,
+		Attributes: attrs,
+		Links:      map[string]string{"self": usersPath(c) + "/" + id},
+	}
+}
+
+// halResource will build the HAL resource of user
+func halResource(c *gin.Context, user interface{}) map[string]interface{} {
+	id, attrs := attributes(user)
+
+	attrs["id"] = id
+	attrs["_links"] = map[string]*model.HALLink{"self": {Href: usersPath(c) + "/" + id}}
+
+	return attrs
+}
+
+// halLinks will convert the links into HAL link objects
+func halLinks(links map[string]string) map[string]*model.HALLink {
+	hal := map[string]*model.HALLink{}
+	for rel, href := range links {
+		hal[rel] = &model.HALLink{Href: href}
+	}
+
+	return hal
+}
+
+// respondUser will write the single user with the negotiated representation
+func respondUser(c *gin.Context, status int, resp *model.UserResponse) {
+	switch negotiateFormat(c) {
+	case model.JSONAPIType:
+		c.Header("Content-Type", model.JSONAPIType)
+		c.JSON(status, &model.JSONAPIDocument{
+			Data:  jsonAPIResource(c, resp.Data),
+			Links: map[string]string{"self": c.Request.URL.String()},
+			Meta:  map[string]interface{}{"messages": resp.Messages},
+		})
+	case model.HALType:
+		c.Header("Content-Type", model.HALType)
+		c.JSON(status, halResource(c, resp.Data))
+	default:
+		c.JSON(status, resp)
+	}
+}
+
+// respondUsers will write the users collection with the negotiated representation,
+// resp is the legacy envelope and users are its list items
+func respondUsers(c *gin.Context, status int, resp interface{}, users []interface{}, meta map[string]interface{}, links map[string]string) {
+	switch negotiateFormat(c) {
+	case model.JSONAPIType:
+		data := []*model.JSONAPIResource{}
+		for _, user := range users {
+			data = append(data, jsonAPIResource(c, user))
+		}
+
+		c.Header("Content-Type", model.JSONAPIType)
+		c.JSON(status, &model.JSONAPIDocument{Data: data, Links: links, Meta: meta})
+	case model.HALType:
+		embedded := []map[string]interface{}{}
+		for _, user := range users {
+			embedded = append(embedded, halResource(c, user))
+		}
+
+		doc := map[string]interface{}{}
+		for k, v := range meta {
+			doc[k] = v
+		}
+		doc["_links"] = halLinks(links)
+		doc["_embedded"] = map[string]interface{}{model.UserResourceType: embedded}
+
+		c.Header("Content-Type", model.HALType)
+		c.JSON(status, doc)
+	default:
+		c.JSON(status, resp)
+	}
+}
@@ -13,8 +13,12 @@ import (
 	usr "github.com/moemoe89/go-graphql-gendhis/api/v1/user"
 	cons "github.com/moemoe89/go-graphql-gendhis/constant"
 
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -39,11 +43,16 @@ func NewUserCtrl(lang *language.Config, log *logrus.Entry, svc usr.Service) *use
 // @Description create user data
 // @Accept  json
 // @Produce  json
+// @Produce  application/problem+json
+// @Produce  application/vnd.api+json
+// @Produce  application/hal+json
 // @Param Accept-Language header string false "language message response"
 // @Param body body form.UserForm true "Request Payload"
 // @Success 201 {object} model.UserResponse
 // @Failure 400 {object} model.GenericResponse
+// @Failure 409 {object} model.GenericResponse
 // @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
 // @Router /user [post]
 func (u *userCtrl) Create(c *gin.Context) {
 	l := c.Request.Header.Get("Accept-Language")
@@ -52,41 +61,42 @@ func (u *userCtrl) Create(c *gin.Context) {
 	req := &form.UserForm{}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		u.log.Errorf("can't get json body: %s", err.Error())
-		c.JSON(http.StatusBadRequest, model.NewGenericResponse(http.StatusBadRequest, cons.ERR, []string{u.lang.Lookup(l, "Oops! Something went wrong with your request")}))
+		u.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, "Oops! Something went wrong with your request")
 		return
 	}
 
 	errs := req.Validate()
 	if len(errs) > 0 {
-		errsLocale := []string{}
-		for _, e := range errs {
-			errsLocale = append(errsLocale, u.lang.Lookup(l, e))
-		}
-		c.JSON(http.StatusBadRequest, model.NewGenericResponse(http.StatusBadRequest, cons.ERR, errsLocale))
+		u.validationResponse(c, errs)
 		return
 	}
 
 	req.ID = xid.New().String()
-	user, status, err := u.svc.Create(req)
+	user, err := u.svc.Create(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(status, model.NewGenericResponse(status, cons.ERR, []string{u.lang.Lookup(l, err.Error())}))
+		u.serviceError(c, err)
 		return
 	}
 
 	resp.GenericResponse = model.NewGenericResponse(http.StatusCreated, cons.OK, []string{u.lang.Lookup(l, "Created data successful")})
 	resp.Data = user
-	c.JSON(http.StatusCreated, resp)
+	respondUser(c, http.StatusCreated, resp)
 }
 
 //
 // @Summary User Detail
 // @Description get user data by ID
 // @Produce  json
+// @Produce  application/problem+json
+// @Produce  application/vnd.api+json
+// @Produce  application/hal+json
 // @Param Accept-Language header string false "language message response"
 // @Param id path string true "User ID"
+// @Param fields[users] query string false "JSON:API sparse fieldset, e.g. name,email"
 // @Success 200 {object} model.UserResponse
 // @Failure 404 {object} model.GenericResponse
 // @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
 // @Router /user/{id} [get]
 func (u *userCtrl) Detail(c *gin.Context) {
 	l := c.Request.Header.Get("Accept-Language")
@@ -94,97 +104,232 @@ func (u *userCtrl) Detail(c *gin.Context) {
 
 	id := c.Param("id")
 
-	user, status, err := u.svc.Detail(id, model.UserSelectField)
+	user, err := u.svc.Detail(c.Request.Context(), id, model.UserSelectField)
 	if err != nil {
-		c.JSON(status, model.NewGenericResponse(status, cons.ERR, []string{u.lang.Lookup(l, err.Error())}))
+		u.serviceError(c, err)
 		return
 	}
 
 	resp.GenericResponse = model.NewGenericResponse(http.StatusOK, cons.OK, []string{u.lang.Lookup(l, "OK")})
 	resp.Data = user
-	c.JSON(http.StatusOK, resp)
+	respondUser(c, http.StatusOK, resp)
 }
 
 //
 // @Summary User List
 // @Description get list of user data
 // @Produce  json
+// @Produce  application/problem+json
+// @Produce  application/vnd.api+json
+// @Produce  application/hal+json
 // @Param Accept-Language header string false "Language message response"
 // @Param per_page query int false "Limit per page"
 // @Param page query int false "Page number"
 // @Param select_field query string false "Select field"
 // @Param order_by query string false "Sort by"
+// @Param filter query string false "Filter expression, e.g. email:endswith:@acme.com,created_at:gte:2024-01-01"
+// @Param phone query string false "Phone number or part of it, matched regardless of formatting"
+// @Param region query string false "Region of national phone number, by default from config"
+// @Param q query string false "Full-text search over name, email and address, ranked by relevance"
+// @Param highlight query bool false "Include highlighted snippet of full-text search"
+// @Param cursor query string false "Keyset pagination cursor from Link header or next_cursor/prev_cursor"
+// @Param limit query int false "Limit per page of keyset pagination"
+// @Param total query bool false "Include total_data in keyset pagination"
+// @Param fields[users] query string false "JSON:API sparse fieldset, e.g. name,email"
 // @Success 200 {object} model.UsersResponse
+// @Failure 400 {object} model.GenericResponse
 // @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
 // @Router /user [get]
 func (u *userCtrl) List(c *gin.Context) {
 	l := c.Request.Header.Get("Accept-Language")
 	resp := &model.UsersResponse{}
 	pagination := &model.UserPaginationResponse{}
 
-	userModel := model.UserModel{}
-
 	offset, perPage, showPage, err := helpers.PaginationSetter(c.Query("per_page"), c.Query("page"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, model.NewGenericResponse(http.StatusInternalServerError, cons.ERR, []string{u.lang.Lookup(l, err.Error())}))
+		u.errorResponse(c, http.StatusInternalServerError, model.CodeInternalError, err.Error())
+		return
+	}
+
+	criteria, err := listCriteria(c)
+	if err != nil {
+		u.log.Errorf("can't get filter: %s", err.Error())
+		u.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, usr.ErrInvalidFilter.Error())
 		return
 	}
 
-	orderBy := c.Query("order_by")
-	orderBy = helpers.OrderByHandler(orderBy, "db", userModel)
-	if len(orderBy) < 1 {
-		orderBy = "created_at DESC"
+	_, withCursor := c.GetQuery("cursor")
+	_, withLimit := c.GetQuery("limit")
+	if (withCursor || withLimit) && len(criteria.Query) == 0 {
+		u.listCursor(c, criteria)
+		return
 	}
 
-	where := "WHERE deleted_at IS NULL"
-	filter := map[string]interface{}{}
+	criteria.Limit = perPage
+	criteria.Offset = offset
 
-	name := c.Query("name")
-	if len(name) > 0 {
-		where += " AND name LIKE :name"
-		filter["name"] = "%" + name + "%"
+	if len(criteria.Query) > 0 {
+		criteria.Highlight, _ = strconv.ParseBool(c.Query("highlight"))
+		u.search(c, criteria, showPage, perPage)
+		return
 	}
 
-	email := c.Query("email")
-	if len(email) > 0 {
-		where += " AND email LIKE :email"
-		filter["email"] = "%" + email + "%"
+	users, count, err := u.svc.List(c.Request.Context(), criteria)
+	if err != nil {
+		u.serviceError(c, err)
+		return
 	}
 
-	phone := c.Query("phone")
-	if len(phone) > 0 {
-		where += " AND phone LIKE :phone"
-		filter["phone"] = "%" + phone + "%"
+	totalPage := int(math.Ceil(float64(count) / float64(perPage)))
+	pagination.PaginationResponse = model.NewPaginationResponse(showPage, perPage, totalPage, count)
+	pagination.List = users
+
+	items := []interface{}{}
+	for _, user := range users {
+		items = append(items, user)
 	}
 
-	createdAtStart := c.Query("created_at_start")
-	if len(createdAtStart) > 0 {
-		where += " AND created_at >= :created_at_start"
-		filter["created_at_start"] = createdAtStart
+	resp.GenericResponse = model.NewGenericResponse(http.StatusOK, cons.OK, []string{u.lang.Lookup(l, "OK")})
+	resp.Data = pagination
+	respondUsers(c, http.StatusOK, resp, items, pageMeta(pagination.PaginationResponse), pageLinks(c, showPage, totalPage))
+}
+
+// listCriteria will build the criteria of users from the search, filters, ordering and selected columns
+// of query string
+func listCriteria(c *gin.Context) (*usr.UserCriteria, error) {
+	criteria := &usr.UserCriteria{
+		Query:  c.Query("q"),
+		Sort:   usr.ParseSort(c.Query("order_by")),
+		Fields: usr.ParseFields(c.Query("select_field")),
+	}
+	phone := form.PhoneFilter(c.Query("phone"), c.Query("region"))
+	criteria.Match(c.Query("name"), c.Query("email"), phone, c.Query("created_at_start"), c.Query("created_at_end"))
+
+	filterExpr := c.Query("filter")
+	if len(filterExpr) > 0 {
+		f, err := usr.ParseFilter(filterExpr)
+		if err != nil {
+			return nil, err
+		}
+		criteria.And(f)
 	}
 
-	createdAtEnd := c.Query("created_at_end")
-	if len(createdAtEnd) > 0 {
-		where += " AND created_at <= :created_at_end"
-		filter["created_at_end"] = createdAtEnd
+	return criteria, criteria.Validate()
+}
+
+// listCursor will respond the users with keyset pagination, so deep pages don't need offset scanning
+// and the total data is only counted when requested
+func (u *userCtrl) listCursor(c *gin.Context, criteria *usr.UserCriteria) {
+	l := c.Request.Header.Get("Accept-Language")
+	resp := &model.UsersCursorResponse{}
+	pagination := &model.UserCursorPaginationResponse{}
+
+	limit := 10
+	if limitStr := c.Query("limit"); len(limitStr) > 0 {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n < 1 {
+			u.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, "Invalid parameter limit: not a positive int")
+			return
+		}
+		limit = n
 	}
 
-	filterCount := filter
-	filter["limit"] = perPage
-	filter["offset"] = offset
+	keyset := usr.NewKeyset(criteria.Sort)
+	criteria.Keyset = keyset
+	backward := false
+
+	cursorStr := c.Query("cursor")
+	if len(cursorStr) > 0 {
+		cursor, err := usr.DecodeCursor(cursorStr)
+		if err == nil {
+			criteria.Cursor = cursor
+			err = criteria.Validate()
+			backward = cursor.Backward
+		}
+		if err != nil {
+			u.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, usr.ErrInvalidCursor.Error())
+			return
+		}
+	}
+
+	// fetch one more row to know whether there is another page
+	criteria.Limit = limit + 1
+
+	users, err := u.svc.ListWithoutCount(c.Request.Context(), criteria)
+	if err != nil {
+		u.serviceError(c, err)
+		return
+	}
+
+	hasMore := len(users) > limit
+	if hasMore {
+		users = users[:limit]
+	}
+
+	if backward {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+
+	nextCursor, prevCursor := "", ""
+	if len(users) > 0 {
+		if hasMore || backward {
+			nextCursor = keyset.Cursor(users[len(users)-1], false).Encode()
+		}
+		if (hasMore && backward) || (len(cursorStr) > 0 && !backward) {
+			prevCursor = keyset.Cursor(users[0], true).Encode()
+		}
+	}
 
-	selectField := model.UserSelectField
-	filterField := c.Query("select_field")
-	if len(filterField) > 0 {
-		res := helpers.CheckInTag(userModel, filterField, "db")
-		if len(res) > 0 {
-			selectField = strings.Join(res, ",")
+	links := map[string]string{"self": c.Request.URL.String()}
+	headerLinks := []string{}
+	if len(nextCursor) > 0 {
+		links["next"] = queryURL(c, map[string]string{"cursor": nextCursor})
+		headerLinks = append(headerLinks, fmt.Sprintf("<%s>; rel=\"next\"", links["next"]))
+	}
+	if len(prevCursor) > 0 {
+		links["prev"] = queryURL(c, map[string]string{"cursor": prevCursor})
+		headerLinks = append(headerLinks, fmt.Sprintf("<%s>; rel=\"prev\"", links["prev"]))
+	}
+	if len(headerLinks) > 0 {
+		c.Header("Link", strings.Join(headerLinks, ", "))
+	}
+
+	pagination.CursorPaginationResponse = model.NewCursorPaginationResponse(limit, nextCursor, prevCursor)
+	pagination.List = users
+
+	meta := map[string]interface{}{"limit": limit}
+	if withTotal, _ := strconv.ParseBool(c.Query("total")); withTotal {
+		count, err := u.svc.Count(c.Request.Context(), criteria)
+		if err != nil {
+			u.serviceError(c, err)
+			return
 		}
+		pagination.TotalData = &count
+		meta["total"] = count
+	}
+
+	items := []interface{}{}
+	for _, user := range users {
+		items = append(items, user)
 	}
 
-	users, count, status, err := u.svc.List(filter, filterCount, where, orderBy, selectField)
+	resp.GenericResponse = model.NewGenericResponse(http.StatusOK, cons.OK, []string{u.lang.Lookup(l, "OK")})
+	resp.Data = pagination
+	respondUsers(c, http.StatusOK, resp, items, meta, links)
+}
+
+// search will respond the full-text search result ranked by relevance
+func (u *userCtrl) search(c *gin.Context, criteria *usr.UserCriteria, showPage, perPage int) {
+	l := c.Request.Header.Get("Accept-Language")
+	resp := &model.UserSearchResponse{}
+	pagination := &model.UserSearchPaginationResponse{}
+
+	users, count, err := u.svc.Search(c.Request.Context(), criteria)
 	if err != nil {
-		c.JSON(status, model.NewGenericResponse(status, cons.ERR, []string{u.lang.Lookup(l, "Oops! Something went wrong. Please try again later")}))
+		u.serviceError(c, err)
 		return
 	}
 
@@ -192,9 +337,14 @@ func (u *userCtrl) List(c *gin.Context) {
 	pagination.PaginationResponse = model.NewPaginationResponse(showPage, perPage, totalPage, count)
 	pagination.List = users
 
+	items := []interface{}{}
+	for _, user := range users {
+		items = append(items, user)
+	}
+
 	resp.GenericResponse = model.NewGenericResponse(http.StatusOK, cons.OK, []string{u.lang.Lookup(l, "OK")})
 	resp.Data = pagination
-	c.JSON(http.StatusOK, resp)
+	respondUsers(c, http.StatusOK, resp, items, pageMeta(pagination.PaginationResponse), pageLinks(c, showPage, totalPage))
 }
 
 //
@@ -202,12 +352,18 @@ func (u *userCtrl) List(c *gin.Context) {
 // @Description update user data
 // @Accept  json
 // @Produce  json
+// @Produce  application/problem+json
+// @Produce  application/vnd.api+json
+// @Produce  application/hal+json
 // @Param Accept-Language header string false "language message response"
 // @Param id path string true "User ID"
 // @Param body body form.UserForm true "Request Payload"
 // @Success 200 {object} model.UserResponse
 // @Failure 400 {object} model.GenericResponse
+// @Failure 404 {object} model.GenericResponse
+// @Failure 409 {object} model.GenericResponse
 // @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
 // @Router /user/{id} [put]
 func (u *userCtrl) Update(c *gin.Context) {
 	l := c.Request.Header.Get("Accept-Language")
@@ -218,55 +374,131 @@ func (u *userCtrl) Update(c *gin.Context) {
 	req := &form.UserForm{}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		u.log.Errorf("can't get json body: %s", err.Error())
-		c.JSON(http.StatusBadRequest, model.NewGenericResponse(http.StatusBadRequest, cons.ERR, []string{u.lang.Lookup(l, "Oops! Something went wrong with your request")}))
+		u.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, "Oops! Something went wrong with your request")
 		return
 	}
 
 	errs := req.Validate()
 	if len(errs) > 0 {
-		errsLocale := []string{}
-		for _, e := range errs {
-			errsLocale = append(errsLocale, u.lang.Lookup(l, e))
-		}
-		c.JSON(http.StatusBadRequest, model.NewGenericResponse(http.StatusBadRequest, cons.ERR, errsLocale))
+		u.validationResponse(c, errs)
+		return
+	}
+
+	user, err := u.svc.Update(c.Request.Context(), req, id)
+	if err != nil {
+		u.serviceError(c, err)
+		return
+	}
+
+	resp.GenericResponse = model.NewGenericResponse(http.StatusOK, cons.OK, []string{u.lang.Lookup(l, "Updated data successful")})
+	resp.Data = user
+	respondUser(c, http.StatusOK, resp)
+}
+
+//
+// @Summary User Patch
+// @Description partially update user data with JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902)
+// @Accept  application/merge-patch+json
+// @Accept  application/json-patch+json
+// @Produce  json
+// @Produce  application/problem+json
+// @Produce  application/vnd.api+json
+// @Produce  application/hal+json
+// @Param Accept-Language header string false "language message response"
+// @Param id path string true "User ID"
+// @Param body body string true "Patch document"
+// @Success 200 {object} model.UserResponse
+// @Failure 400 {object} model.GenericResponse
+// @Failure 404 {object} model.GenericResponse
+// @Failure 409 {object} model.GenericResponse
+// @Failure 415 {object} model.GenericResponse
+// @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
+// @Router /user/{id} [patch]
+func (u *userCtrl) Patch(c *gin.Context) {
+	l := c.Request.Header.Get("Accept-Language")
+	resp := &model.UserResponse{}
+
+	id := c.Param("id")
+
+	contentType := c.ContentType()
+	if contentType != MergePatchType && contentType != JSONPatchType {
+		u.errorResponse(c, http.StatusUnsupportedMediaType, model.CodeUnsupportedMediaType, errUnsupportedPatch.Error())
+		return
+	}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		u.log.Errorf("can't get patch body: %s", err.Error())
+		u.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, "Oops! Something went wrong with your request")
+		return
+	}
+
+	user, err := u.svc.Detail(c.Request.Context(), id, model.UserSelectField)
+	if err != nil {
+		u.serviceError(c, err)
 		return
 	}
 
-	user, status, err := u.svc.Detail(id, "id")
+	original, err := json.Marshal(user)
 	if err != nil {
-		c.JSON(status, model.NewGenericResponse(status, cons.ERR, []string{u.lang.Lookup(l, err.Error())}))
+		u.log.Errorf("can't marshal user: %s", err.Error())
+		u.errorResponse(c, http.StatusInternalServerError, model.CodeInternalError, "Oops! Something went wrong. Please try again later")
+		return
+	}
+
+	patched, err := applyPatch(contentType, original, body)
+	if err != nil {
+		u.log.Errorf("can't apply patch: %s", err.Error())
+		u.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, "Invalid patch document")
+		return
+	}
+
+	req := &form.UserForm{}
+	if err := json.Unmarshal(patched, req); err != nil {
+		u.log.Errorf("can't get patched user: %s", err.Error())
+		u.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, "Invalid patch document")
+		return
+	}
+	req.ID = id
+
+	errs := req.Validate()
+	if len(errs) > 0 {
+		u.validationResponse(c, errs)
 		return
 	}
 
-	user, status, err = u.svc.Update(req, id)
+	user, err = u.svc.Patch(c.Request.Context(), req, user)
 	if err != nil {
-		c.JSON(status, model.NewGenericResponse(status, cons.ERR, []string{u.lang.Lookup(l, err.Error())}))
+		u.serviceError(c, err)
 		return
 	}
 
 	resp.GenericResponse = model.NewGenericResponse(http.StatusOK, cons.OK, []string{u.lang.Lookup(l, "Updated data successful")})
 	resp.Data = user
-	c.JSON(http.StatusOK, resp)
+	respondUser(c, http.StatusOK, resp)
 }
 
 //
 // @Summary User Delete
 // @Description delete user data by ID
 // @Produce  json
+// @Produce  application/problem+json
 // @Param id path string true "User ID"
 // @Param Accept-Language header string false "language message response"
 // @Success 200 {object} model.GenericResponse
 // @Failure 404 {object} model.GenericResponse
 // @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
 // @Router /user/{id} [delete]
 func (u *userCtrl) Delete(c *gin.Context) {
 	l := c.Request.Header.Get("Accept-Language")
 
 	id := c.Param("id")
 
-	status, err := u.svc.Delete(id)
+	err := u.svc.Delete(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(status, model.NewGenericResponse(status, cons.ERR, []string{u.lang.Lookup(l, err.Error())}))
+		u.serviceError(c, err)
 		return
 	}
 
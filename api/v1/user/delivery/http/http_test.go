@@ -9,19 +9,28 @@ package http_test
 import (
 	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
 	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	usr "github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+	usrHttp "github.com/moemoe89/go-graphql-gendhis/api/v1/user/delivery/http"
 	"github.com/moemoe89/go-graphql-gendhis/api/v1/user/mocks"
 	"github.com/moemoe89/go-graphql-gendhis/config"
 	"github.com/moemoe89/go-graphql-gendhis/routers"
 
+	"bufio"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rs/xid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestDeliveryCreateFail(t *testing.T) {
@@ -31,7 +40,7 @@ func TestDeliveryCreateFail(t *testing.T) {
 	userForm := &form.UserForm{
 		Name:    "Momo",
 		Email:   "momo@mail.com",
-		Phone:   "085640",
+		Phone:   "085640123456",
 		Address: "Indonesia",
 	}
 
@@ -39,9 +48,9 @@ func TestDeliveryCreateFail(t *testing.T) {
 	assert.NoError(t, err)
 
 	mockService := new(mocks.Service)
-	mockService.On("Create", userForm).Return(nil, http.StatusInternalServerError, errors.New("Unexpected database error"))
+	mockService.On("Create", mock.Anything, userForm).Return(nil, errors.New("Unexpected database error"))
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
 	req, err := http.NewRequest("POST", "/api/v1/user", strings.NewReader(string(j)))
@@ -53,6 +62,39 @@ func TestDeliveryCreateFail(t *testing.T) {
 	assert.NotNil(t, w.Body)
 }
 
+func TestDeliveryCreateFailConflict(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	userForm := &form.UserForm{
+		Name:  "Momo",
+		Email: "momo@mail.com",
+	}
+
+	j, err := json.Marshal(userForm)
+	assert.NoError(t, err)
+
+	mockService := new(mocks.Service)
+	mockService.On("Create", mock.Anything, mock.AnythingOfType("*form.UserForm")).Return(nil, usr.NewError(usr.ErrConflict, errors.New("duplicate key")))
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("POST", "/api/v1/user", strings.NewReader(string(j)))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", model.ProblemJSONType)
+	router.ServeHTTP(w, req)
+
+	problem := &model.ProblemResponse{}
+	err = json.Unmarshal(w.Body.Bytes(), problem)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, model.CodeConflict, problem.Code)
+	assert.Equal(t, "User already exists", problem.Detail)
+}
+
 func TestDeliveryCreateFailValidation(t *testing.T) {
 	lang, _ := config.InitLang()
 	log := config.InitLog()
@@ -60,7 +102,7 @@ func TestDeliveryCreateFailValidation(t *testing.T) {
 	userForm := &form.UserForm{
 		Name:    "",
 		Email:   "momo@mail.com",
-		Phone:   "085640",
+		Phone:   "085640123456",
 		Address: "Indonesia",
 	}
 
@@ -69,7 +111,7 @@ func TestDeliveryCreateFailValidation(t *testing.T) {
 
 	mockService := new(mocks.Service)
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
 	req, err := http.NewRequest("POST", "/api/v1/user", strings.NewReader(string(j)))
@@ -87,7 +129,7 @@ func TestDeliveryCreateFailBindJSON(t *testing.T) {
 
 	mockService := new(mocks.Service)
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
 	req, err := http.NewRequest("POST", "/api/v1/user", strings.NewReader(""))
@@ -99,6 +141,76 @@ func TestDeliveryCreateFailBindJSON(t *testing.T) {
 	assert.NotNil(t, w.Body)
 }
 
+func TestDeliveryCreateFailValidationProblem(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	userForm := &form.UserForm{
+		Name:  "",
+		Email: "momo",
+	}
+
+	j, err := json.Marshal(userForm)
+	assert.NoError(t, err)
+
+	mockService := new(mocks.Service)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("POST", "/api/v1/user", strings.NewReader(string(j)))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", model.ProblemJSONType)
+	router.ServeHTTP(w, req)
+
+	problem := &model.ProblemResponse{}
+	err = json.Unmarshal(w.Body.Bytes(), problem)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, model.ProblemJSONType, w.Header().Get("Content-Type"))
+	assert.Equal(t, model.CodeValidationFailed, problem.Code)
+	assert.Equal(t, "/api/v1/user", problem.Instance)
+	assert.Len(t, problem.Errors, 2)
+	assert.Equal(t, "name", problem.Errors[0].Field)
+	assert.Equal(t, "email", problem.Errors[1].Field)
+}
+
+func TestDeliveryCreateFailValidationLength(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	userForm := &form.UserForm{
+		Name:  "Momo",
+		Email: "momo@mail.com",
+		Phone: strings.Repeat("0", form.PhoneMaxLength+1),
+	}
+
+	j, err := json.Marshal(userForm)
+	assert.NoError(t, err)
+
+	mockService := new(mocks.Service)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("POST", "/api/v1/user", strings.NewReader(string(j)))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	resp := &model.GenericResponse{}
+	err = json.Unmarshal(w.Body.Bytes(), resp)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, []string{"Phone can't be longer than 20 characters"}, resp.Messages)
+	assert.Len(t, resp.Errors, 1)
+	assert.Equal(t, "phone", resp.Errors[0].Field)
+	assert.Equal(t, form.RuleMax, resp.Errors[0].Rule)
+}
+
 func TestDeliveryUpdate(t *testing.T) {
 	id := xid.New().String()
 
@@ -109,7 +221,7 @@ func TestDeliveryUpdate(t *testing.T) {
 		ID:      xid.New().String(),
 		Name:    "Momo",
 		Email:   "momo@mail.com",
-		Phone:   "085640",
+		Phone:   "085640123456",
 		Address: "Indonesia",
 	}
 	user := &model.UserModel{
@@ -124,10 +236,9 @@ func TestDeliveryUpdate(t *testing.T) {
 	assert.NoError(t, err)
 
 	mockService := new(mocks.Service)
-	mockService.On("Detail", id, "id").Return(user, 0, nil)
-	mockService.On("Update", userForm, id).Return(user, 0, nil)
+	mockService.On("Update", mock.Anything, userForm, id).Return(user, nil)
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
 	req, err := http.NewRequest("PUT", "/api/v1/user/"+id, strings.NewReader(string(j)))
@@ -137,6 +248,41 @@ func TestDeliveryUpdate(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.NotNil(t, w.Body)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeliveryUpdateFailEmailTaken(t *testing.T) {
+	id := xid.New().String()
+
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	userForm := &form.UserForm{
+		Name:  "Momo",
+		Email: "momo@mail.com",
+	}
+
+	j, err := json.Marshal(userForm)
+	assert.NoError(t, err)
+
+	mockService := new(mocks.Service)
+	mockService.On("Update", mock.Anything, userForm, id).Return(nil, usr.NewError(usr.ErrConflict, fmt.Errorf("%w: duplicate key", usr.ErrEmailTaken)))
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("PUT", "/api/v1/user/"+id, strings.NewReader(string(j)))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "id")
+	router.ServeHTTP(w, req)
+
+	resp := &model.GenericResponse{}
+	err = json.Unmarshal(w.Body.Bytes(), resp)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, []string{"Email sudah terdaftar"}, resp.Messages)
 }
 
 func TestDeliveryUpdateFail(t *testing.T) {
@@ -149,25 +295,17 @@ func TestDeliveryUpdateFail(t *testing.T) {
 		ID:      xid.New().String(),
 		Name:    "Momo",
 		Email:   "momo@mail.com",
-		Phone:   "085640",
+		Phone:   "085640123456",
 		Address: "Indonesia",
 	}
-	user := &model.UserModel{
-		ID:      userForm.ID,
-		Name:    userForm.Name,
-		Email:   userForm.Email,
-		Phone:   userForm.Phone,
-		Address: userForm.Address,
-	}
 
 	j, err := json.Marshal(userForm)
 	assert.NoError(t, err)
 
 	mockService := new(mocks.Service)
-	mockService.On("Detail", id, "id").Return(user, 0, nil)
-	mockService.On("Update", userForm, id).Return(nil, http.StatusInternalServerError, errors.New("Unexpected database error"))
+	mockService.On("Update", mock.Anything, userForm, id).Return(nil, errors.New("Unexpected database error"))
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
 	req, err := http.NewRequest("PUT", "/api/v1/user/"+id, strings.NewReader(string(j)))
@@ -179,27 +317,24 @@ func TestDeliveryUpdateFail(t *testing.T) {
 	assert.NotNil(t, w.Body)
 }
 
-func TestDeliveryUpdateFailDetail(t *testing.T) {
+func TestDeliveryUpdateFailNotFound(t *testing.T) {
 	id := xid.New().String()
 
 	lang, _ := config.InitLang()
 	log := config.InitLog()
 
 	userForm := &form.UserForm{
-		ID:      xid.New().String(),
-		Name:    "Momo",
-		Email:   "momo@mail.com",
-		Phone:   "085640",
-		Address: "Indonesia",
+		Name:  "Momo",
+		Email: "momo@mail.com",
 	}
 
 	j, err := json.Marshal(userForm)
 	assert.NoError(t, err)
 
 	mockService := new(mocks.Service)
-	mockService.On("Detail", id, "id").Return(nil, http.StatusInternalServerError, errors.New("Unexpected database error"))
+	mockService.On("Update", mock.Anything, userForm, id).Return(nil, usr.NewError(usr.ErrNotFound, sql.ErrNoRows))
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
 	req, err := http.NewRequest("PUT", "/api/v1/user/"+id, strings.NewReader(string(j)))
@@ -207,8 +342,8 @@ func TestDeliveryUpdateFailDetail(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.NotNil(t, w.Body)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
 }
 
 func TestDeliveryUpdateFailValidation(t *testing.T) {
@@ -221,7 +356,7 @@ func TestDeliveryUpdateFailValidation(t *testing.T) {
 		ID:      xid.New().String(),
 		Name:    "",
 		Email:   "momo@mail.com",
-		Phone:   "085640",
+		Phone:   "085640123456",
 		Address: "Indonesia",
 	}
 
@@ -230,7 +365,7 @@ func TestDeliveryUpdateFailValidation(t *testing.T) {
 
 	mockService := new(mocks.Service)
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
 	req, err := http.NewRequest("PUT", "/api/v1/user/"+id, strings.NewReader(string(j)))
@@ -250,7 +385,7 @@ func TestDeliveryUpdateFailBindJSON(t *testing.T) {
 
 	mockService := new(mocks.Service)
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
 	req, err := http.NewRequest("PUT", "/api/v1/user/"+id, strings.NewReader(""))
@@ -262,167 +397,1003 @@ func TestDeliveryUpdateFailBindJSON(t *testing.T) {
 	assert.NotNil(t, w.Body)
 }
 
-func TestDeliveryList(t *testing.T) {
+func TestDeliveryPatch(t *testing.T) {
+	id := xid.New().String()
+
 	lang, _ := config.InitLang()
 	log := config.InitLog()
 
 	user := &model.UserModel{
-		ID:      xid.New().String(),
+		ID:      id,
 		Name:    "Momo",
 		Email:   "momo@mail.com",
-		Phone:   "085640",
+		Phone:   "085640123456",
 		Address: "Indonesia",
 	}
-	users := []*model.UserModel{}
-	users = append(users, user)
+	userForm := &form.UserForm{
+		ID:      id,
+		Name:    "Baruno",
+		Email:   user.Email,
+		Phone:   user.Phone,
+		Address: user.Address,
+	}
 
-	name := "a"
-	email := "b"
-	phone := "0856"
-	createdAtStart := "2020-01-01"
-	createdAtEnd := "2020-01-31"
+	patches := map[string]string{
+		usrHttp.MergePatchType: `{"name":"Baruno"}`,
+		usrHttp.JSONPatchType:  `[{"op":"replace","path":"/name","value":"Baruno"}]`,
+	}
+
+	for contentType, patch := range patches {
+		mockService := new(mocks.Service)
+		mockService.On("Detail", mock.Anything, id, model.UserSelectField).Return(user, nil)
+		mockService.On("Patch", mock.Anything, userForm, user).Return(user, nil)
+
+		router := routers.GetRouter(lang, log, mockService, nil)
 
-	filter := map[string]interface{}{}
-	filter["name"] = "%" + name + "%"
-	filter["email"] = "%" + email + "%"
-	filter["phone"] = "%" + phone + "%"
-	filter["created_at_start"] = createdAtStart
-	filter["created_at_end"] = createdAtEnd
-	filterCount := filter
-	filter["limit"] = 10
-	filter["offset"] = 0
+		w := httptest.NewRecorder()
+		req, err := http.NewRequest("PATCH", "/api/v1/user/"+id, strings.NewReader(patch))
+		assert.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	}
+}
+
+func TestDeliveryPatchFailMediaType(t *testing.T) {
+	id := xid.New().String()
+
+	lang, _ := config.InitLang()
+	log := config.InitLog()
 
 	mockService := new(mocks.Service)
-	mockService.On("List", filter, filterCount, "WHERE deleted_at IS NULL AND name LIKE :name AND email LIKE :email AND phone LIKE :phone AND created_at >= :created_at_start AND created_at <= :created_at_end", "created_at DESC", model.UserSelectField).Return(users, 1, 0, nil)
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/api/v1/user?per_page=10&name="+name+"&email="+email+"&phone="+phone+"&created_at_start="+createdAtStart+"&created_at_end="+createdAtEnd, strings.NewReader(""))
+	req, err := http.NewRequest("PATCH", "/api/v1/user/"+id, strings.NewReader(`{"name":"Baruno"}`))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusOK, w.Code)
-	assert.NotNil(t, w.Body)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
 }
 
-func TestDeliveryListFail(t *testing.T) {
+func TestDeliveryPatchFailDocument(t *testing.T) {
+	id := xid.New().String()
+
 	lang, _ := config.InitLang()
 	log := config.InitLog()
 
-	filter := map[string]interface{}{}
-	filterCount := filter
-	filter["limit"] = 10
-	filter["offset"] = 0
+	user := &model.UserModel{
+		ID:    id,
+		Name:  "Momo",
+		Email: "momo@mail.com",
+	}
 
 	mockService := new(mocks.Service)
-	mockService.On("List", filter, filterCount, "WHERE deleted_at IS NULL", "created_at DESC", model.UserSelectField).Return(nil, 0, http.StatusInternalServerError, errors.New("Oops! Something went wrong. Please try again later"))
+	mockService.On("Detail", mock.Anything, id, model.UserSelectField).Return(user, nil)
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/api/v1/user?per_page=10", strings.NewReader(""))
+	req, err := http.NewRequest("PATCH", "/api/v1/user/"+id, strings.NewReader(`[{"op":"test","path":"/name","value":"Baruno"}]`))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", usrHttp.JSONPatchType)
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.NotNil(t, w.Body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
-func TestDeliveryListFailPagination(t *testing.T) {
+func TestDeliveryPatchFailValidation(t *testing.T) {
+	id := xid.New().String()
+
 	lang, _ := config.InitLang()
 	log := config.InitLog()
 
-	filter := map[string]interface{}{}
-	filterCount := filter
-	filter["limit"] = 10
-	filter["offset"] = 0
+	user := &model.UserModel{
+		ID:    id,
+		Name:  "Momo",
+		Email: "momo@mail.com",
+	}
 
 	mockService := new(mocks.Service)
-	mockService.On("List", filter, filterCount, "WHERE deleted_at IS NULL", "created_at DESC", model.UserSelectField).Return(nil, 0, http.StatusInternalServerError, errors.New("Invalid parameter per_page: not an int"))
+	mockService.On("Detail", mock.Anything, id, model.UserSelectField).Return(user, nil)
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/api/v1/user?per_page=a", strings.NewReader(""))
+	req, err := http.NewRequest("PATCH", "/api/v1/user/"+id, strings.NewReader(`{"name":null}`))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", usrHttp.MergePatchType)
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
-	assert.NotNil(t, w.Body)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
-func TestDeliveryDetail(t *testing.T) {
+func TestDeliveryPatchFailDetail(t *testing.T) {
 	id := xid.New().String()
 
 	lang, _ := config.InitLang()
 	log := config.InitLog()
 
+	mockService := new(mocks.Service)
+	mockService.On("Detail", mock.Anything, id, model.UserSelectField).Return(nil, usr.ErrNotFound)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, err := http.NewRequest("PATCH", "/api/v1/user/"+id, strings.NewReader(`{"name":"Baruno"}`))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", usrHttp.MergePatchType)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeliveryList(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
 	user := &model.UserModel{
 		ID:      xid.New().String(),
 		Name:    "Momo",
 		Email:   "momo@mail.com",
-		Phone:   "085640",
+		Phone:   "085640123456",
 		Address: "Indonesia",
 	}
+	users := []*model.UserModel{}
+	users = append(users, user)
+
+	name := "a"
+	email := "b"
+	phone := "0856-40"
+	createdAtStart := "2020-01-01"
+	createdAtEnd := "2020-01-31"
+
+	criteria := (&usr.UserCriteria{Limit: 10}).Match(name, email, "+6285640", createdAtStart, createdAtEnd)
 
 	mockService := new(mocks.Service)
-	mockService.On("Detail", id, model.UserSelectField).Return(user, 0, nil)
+	mockService.On("List", mock.Anything, criteria).Return(users, 1, nil)
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/api/v1/user/"+id, strings.NewReader(""))
+	req, _ := http.NewRequest("GET", "/api/v1/user?per_page=10&name="+name+"&email="+email+"&phone="+phone+"&created_at_start="+createdAtStart+"&created_at_end="+createdAtEnd, strings.NewReader(""))
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.NotNil(t, w.Body)
 }
 
-func TestDeliveryDetailFail(t *testing.T) {
-	id := xid.New().String()
+func TestDeliveryListFilter(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	f, _ := usr.ParseFilter("email:endswith:@acme.com,created_at:gte:2024-01-01")
+	criteria := (&usr.UserCriteria{Limit: 10}).And(f)
+
+	mockService := new(mocks.Service)
+	mockService.On("List", mock.Anything, criteria).Return([]*model.UserModel{}, 0, nil)
 
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user?per_page=10&filter=email:endswith:@acme.com,created_at:gte:2024-01-01", strings.NewReader(""))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeliveryListJSONAPI(t *testing.T) {
 	lang, _ := config.InitLang()
 	log := config.InitLog()
+
+	user := &model.UserModel{
+		ID:    xid.New().String(),
+		Name:  "Momo",
+		Email: "momo@mail.com",
+	}
+
 	mockService := new(mocks.Service)
-	mockService.On("Detail", id, model.UserSelectField).Return(nil, http.StatusInternalServerError, errors.New("Unexpected database error"))
+	mockService.On("List", mock.Anything, &usr.UserCriteria{Limit: 1}).Return([]*model.UserModel{user}, 3, nil)
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("GET", "/api/v1/user/"+id, strings.NewReader(""))
+	req, _ := http.NewRequest("GET", "/api/v1/user?per_page=1&fields[users]=name", strings.NewReader(""))
+	req.Header.Set("Accept", model.JSONAPIType)
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	doc := struct {
+		Data  []*model.JSONAPIResource `json:"data"`
+		Links map[string]string        `json:"links"`
+		Meta  map[string]interface{}   `json:"meta"`
+	}{}
+	err := json.Unmarshal(w.Body.Bytes(), &doc)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, model.JSONAPIType, w.Header().Get("Content-Type"))
+	assert.Len(t, doc.Data, 1)
+	assert.Equal(t, model.UserResourceType, doc.Data[0].Type)
+	assert.Equal(t, user.ID, doc.Data[0].ID)
+	assert.Equal(t, map[string]interface{}{"name": "Momo"}, doc.Data[0].Attributes)
+	assert.Equal(t, "/api/v1/user/"+user.ID, doc.Data[0].Links["self"])
+	assert.Equal(t, float64(3), doc.Meta["total"])
+	assert.Contains(t, doc.Links["next"], "page=2")
+	assert.Contains(t, doc.Links["last"], "page=3")
+	assert.NotContains(t, doc.Links, "prev")
 }
 
-func TestDeliveryDelete(t *testing.T) {
-	id := xid.New().String()
+func TestDeliveryListHAL(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	user := &model.UserModel{
+		ID:   xid.New().String(),
+		Name: "Momo",
+	}
+
+	mockService := new(mocks.Service)
+	mockService.On("List", mock.Anything, &usr.UserCriteria{Limit: 1, Offset: 1}).Return([]*model.UserModel{user}, 2, nil)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user?per_page=1&page=2", strings.NewReader(""))
+	req.Header.Set("Accept", model.HALType)
+	router.ServeHTTP(w, req)
+
+	doc := struct {
+		Links    map[string]*model.HALLink           `json:"_links"`
+		Embedded map[string][]map[string]interface{} `json:"_embedded"`
+		Total    int                                 `json:"total"`
+	}{}
+	err := json.Unmarshal(w.Body.Bytes(), &doc)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, model.HALType, w.Header().Get("Content-Type"))
+	assert.Equal(t, 2, doc.Total)
+	assert.Contains(t, doc.Links["prev"].Href, "page=1")
+	assert.NotContains(t, doc.Links, "next")
+	assert.Len(t, doc.Embedded[model.UserResourceType], 1)
+	assert.Equal(t, user.ID, doc.Embedded[model.UserResourceType][0]["id"])
+}
 
+func TestDeliveryListCursor(t *testing.T) {
 	lang, _ := config.InitLang()
 	log := config.InitLog()
+
+	users := []*model.UserModel{}
+	for i := 0; i < 3; i++ {
+		users = append(users, &model.UserModel{ID: xid.New().String(), Name: "Momo", CreatedAt: time.Now().UTC()})
+	}
+
+	sort := []usr.Sort{{Field: "name"}}
+	criteria := &usr.UserCriteria{Sort: sort, Keyset: usr.NewKeyset(sort), Limit: 3}
+
 	mockService := new(mocks.Service)
-	mockService.On("Delete", id).Return(0, nil)
+	mockService.On("ListWithoutCount", mock.Anything, criteria).Return(users, nil)
+	mockService.On("Count", mock.Anything, criteria).Return(5, nil)
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("DELETE", "/api/v1/user/"+id, strings.NewReader(""))
+	req, _ := http.NewRequest("GET", "/api/v1/user?limit=2&order_by=name&total=true", strings.NewReader(""))
 	router.ServeHTTP(w, req)
 
+	resp := &model.UsersCursorResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), resp)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, resp.Data.List, 2)
+	assert.Equal(t, 5, *resp.Data.TotalData)
+	assert.NotEmpty(t, resp.Data.NextCursor)
+	assert.Empty(t, resp.Data.PrevCursor)
+	assert.Contains(t, w.Header().Get("Link"), `rel="next"`)
+	assert.NotContains(t, w.Header().Get("Link"), `rel="prev"`)
+
+	cursor, err := usr.DecodeCursor(resp.Data.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"Momo", users[1].CreatedAt.Format(time.RFC3339Nano), users[1].ID}, cursor.Values)
+
+	criteria = &usr.UserCriteria{Sort: sort, Keyset: usr.NewKeyset(sort), Cursor: cursor, Limit: 3}
+	mockService.On("ListWithoutCount", mock.Anything, criteria).Return(users[2:], nil)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/user?limit=2&order_by=name&cursor="+resp.Data.NextCursor, strings.NewReader(""))
+	router.ServeHTTP(w, req)
+
+	resp = &model.UsersCursorResponse{}
+	err = json.Unmarshal(w.Body.Bytes(), resp)
+	assert.NoError(t, err)
+
 	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, resp.Data.List, 1)
+	assert.Nil(t, resp.Data.TotalData)
+	assert.Empty(t, resp.Data.NextCursor)
+	assert.NotEmpty(t, resp.Data.PrevCursor)
+	assert.Contains(t, w.Header().Get("Link"), `rel="prev"`)
+	mockService.AssertExpectations(t)
 }
 
-func TestDeliveryDeleteFail(t *testing.T) {
-	id := xid.New().String()
+func TestDeliveryListCursorFail(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	mockService := new(mocks.Service)
 
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	cursor := usr.NewKeyset(nil).Cursor(&model.UserModel{ID: xid.New().String()}, false).Encode()
+	for _, query := range []string{"limit=a", "limit=0", "cursor=abc", "order_by=name&cursor=" + cursor} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/user?"+query, strings.NewReader(""))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestDeliveryListSearch(t *testing.T) {
 	lang, _ := config.InitLang()
 	log := config.InitLog()
+
+	users := []*model.UserSearchModel{
+		{
+			UserModel: model.UserModel{ID: xid.New().String(), Name: "Momo"},
+			Rank:      0.6,
+			Highlight: "<b>Momo</b>",
+		},
+	}
+
 	mockService := new(mocks.Service)
-	mockService.On("Delete", id).Return(http.StatusInternalServerError, errors.New("Unexpected database error"))
+	mockService.On("Search", mock.Anything, &usr.UserCriteria{Query: "momo", Highlight: true, Limit: 10}).Return(users, 1, nil)
 
-	router := routers.GetRouter(lang, log, mockService)
+	router := routers.GetRouter(lang, log, mockService, nil)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("DELETE", "/api/v1/user/"+id, strings.NewReader(""))
+	req, _ := http.NewRequest("GET", "/api/v1/user?per_page=10&q=momo&highlight=true", strings.NewReader(""))
 	router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	resp := &model.UserSearchResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), resp)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, users, resp.Data.List)
+	assert.Equal(t, 1, resp.Data.TotalData)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeliveryListSearchFail(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	mockService := new(mocks.Service)
+	mockService.On("Search", mock.Anything, &usr.UserCriteria{Query: "momo", Sort: []usr.Sort{{Field: "name"}}, Limit: 10}).Return(nil, 0, errors.New("Oops! Something went wrong. Please try again later"))
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user?per_page=10&q=momo&order_by=name", strings.NewReader(""))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeliveryListFailFilter(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	mockService := new(mocks.Service)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	for _, expr := range []string{"email", "password:eq:secret"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/user?filter="+expr, strings.NewReader(""))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestDeliveryListFail(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	mockService := new(mocks.Service)
+	mockService.On("List", mock.Anything, &usr.UserCriteria{Limit: 10}).Return(nil, 0, errors.New("Oops! Something went wrong. Please try again later"))
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user?per_page=10", strings.NewReader(""))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotNil(t, w.Body)
+}
+
+func TestDeliveryListFailPagination(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	mockService := new(mocks.Service)
+	mockService.On("List", mock.Anything, &usr.UserCriteria{Limit: 10}).Return(nil, 0, errors.New("Invalid parameter per_page: not an int"))
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user?per_page=a", strings.NewReader(""))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotNil(t, w.Body)
+}
+
+func TestDeliveryDetail(t *testing.T) {
+	id := xid.New().String()
+
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	user := &model.UserModel{
+		ID:      xid.New().String(),
+		Name:    "Momo",
+		Email:   "momo@mail.com",
+		Phone:   "085640123456",
+		Address: "Indonesia",
+	}
+
+	mockService := new(mocks.Service)
+	mockService.On("Detail", mock.Anything, id, model.UserSelectField).Return(user, nil)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user/"+id, strings.NewReader(""))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotNil(t, w.Body)
+}
+
+func TestDeliveryDetailFail(t *testing.T) {
+	id := xid.New().String()
+
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	mockService := new(mocks.Service)
+	mockService.On("Detail", mock.Anything, id, model.UserSelectField).Return(nil, errors.New("Unexpected database error"))
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user/"+id, strings.NewReader(""))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestDeliveryDetailFailUnavailable(t *testing.T) {
+	id := xid.New().String()
+
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	mockService := new(mocks.Service)
+	mockService.On("Detail", mock.Anything, id, model.UserSelectField).Return(nil, usr.NewError(usr.ErrUnavailable, context.DeadlineExceeded))
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user/"+id, strings.NewReader(""))
+	req.Header.Set("Accept", model.ProblemJSONType)
+	router.ServeHTTP(w, req)
+
+	problem := &model.ProblemResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), problem)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, model.CodeServiceUnavailable, problem.Code)
+}
+
+func TestDeliveryDetailFailProblem(t *testing.T) {
+	id := xid.New().String()
+
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	mockService := new(mocks.Service)
+	mockService.On("Detail", mock.Anything, id, model.UserSelectField).Return(nil, usr.ErrNotFound)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user/"+id, strings.NewReader(""))
+	req.Header.Set("Accept", model.ProblemJSONType)
+	req.Header.Set("Accept-Language", "id")
+	router.ServeHTTP(w, req)
+
+	problem := &model.ProblemResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), problem)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, model.ProblemJSONType, w.Header().Get("Content-Type"))
+	assert.Equal(t, model.CodeUserNotFound, problem.Code)
+	assert.Equal(t, "Pengguna tidak ditemukan", problem.Detail)
+	assert.Equal(t, "/api/v1/user/"+id, problem.Instance)
+	assert.Empty(t, problem.Errors)
+}
+
+func TestDeliveryDetailJSONAPI(t *testing.T) {
+	id := xid.New().String()
+
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	user := &model.UserModel{
+		ID:      id,
+		Name:    "Momo",
+		Email:   "momo@mail.com",
+		Phone:   "085640123456",
+		Address: "Indonesia",
+	}
+
+	mockService := new(mocks.Service)
+	mockService.On("Detail", mock.Anything, id, model.UserSelectField).Return(user, nil)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user/"+id+"?fields[users]=name,email", strings.NewReader(""))
+	req.Header.Set("Accept", model.JSONAPIType)
+	router.ServeHTTP(w, req)
+
+	doc := struct {
+		Data *model.JSONAPIResource `json:"data"`
+	}{}
+	err := json.Unmarshal(w.Body.Bytes(), &doc)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, model.JSONAPIType, w.Header().Get("Content-Type"))
+	assert.Equal(t, model.UserResourceType, doc.Data.Type)
+	assert.Equal(t, id, doc.Data.ID)
+	assert.Equal(t, map[string]interface{}{"name": "Momo", "email": "momo@mail.com"}, doc.Data.Attributes)
+	assert.Equal(t, "/api/v1/user/"+id, doc.Data.Links["self"])
+}
+
+func TestDeliveryDetailHAL(t *testing.T) {
+	id := xid.New().String()
+
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	user := &model.UserModel{
+		ID:   id,
+		Name: "Momo",
+	}
+
+	mockService := new(mocks.Service)
+	mockService.On("Detail", mock.Anything, id, model.UserSelectField).Return(user, nil)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user/"+id, strings.NewReader(""))
+	req.Header.Set("Accept", model.HALType)
+	router.ServeHTTP(w, req)
+
+	doc := struct {
+		ID    string                    `json:"id"`
+		Name  string                    `json:"name"`
+		Links map[string]*model.HALLink `json:"_links"`
+	}{}
+	err := json.Unmarshal(w.Body.Bytes(), &doc)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, model.HALType, w.Header().Get("Content-Type"))
+	assert.Equal(t, id, doc.ID)
+	assert.Equal(t, "Momo", doc.Name)
+	assert.Equal(t, "/api/v1/user/"+id, doc.Links["self"].Href)
+}
+
+func TestDeliveryExportCSV(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	user := &model.UserModel{
+		ID:        xid.New().String(),
+		Name:      "Momo, Jr.",
+		Email:     "momo@mail.com",
+		CreatedAt: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	criteria := (&usr.UserCriteria{Fields: []string{"id", "name", "created_at"}}).Where("email", usr.OpContains, "mail")
+
+	mockService := new(mocks.Service)
+	mockService.On("Export", mock.Anything, criteria, mock.Anything).
+		Run(func(args mock.Arguments) {
+			fn := args.Get(2).(func(*model.UserModel) error)
+			assert.NoError(t, fn(user))
+		}).
+		Return(nil)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user/export?email=mail&select_field=id,name,created_at", strings.NewReader(""))
+	req.Header.Set("Accept-Language", "id")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=\"users.csv\"", w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "ID,Nama,Dibuat Pada\n"+user.ID+",\"Momo, Jr.\",2020-01-02T03:04:05Z\n", w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestDeliveryExportCSVFormula(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	user := &model.UserModel{Name: "=HYPERLINK(\"http://x\")", Phone: "+6285640123456", Address: "@SUM(1)"}
+
+	mockService := new(mocks.Service)
+	mockService.On("Export", mock.Anything, &usr.UserCriteria{Fields: []string{"name", "phone", "address"}}, mock.Anything).
+		Run(func(args mock.Arguments) {
+			fn := args.Get(2).(func(*model.UserModel) error)
+			assert.NoError(t, fn(user))
+		}).
+		Return(nil)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user/export?select_field=name,phone,address", strings.NewReader(""))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	lines := strings.Split(w.Body.String(), "\n")
+	assert.Equal(t, `"'=HYPERLINK(""http://x"")",'+6285640123456,'@SUM(1)`, lines[1])
+	mockService.AssertExpectations(t)
+}
+
+func TestDeliveryExportNDJSON(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	users := []*model.UserModel{
+		{ID: xid.New().String(), Name: "Momo"},
+		{ID: xid.New().String(), Name: "Gendhis"},
+	}
+
+	mockService := new(mocks.Service)
+	mockService.On("Export", mock.Anything, &usr.UserCriteria{Sort: []usr.Sort{{Field: "name"}}, Fields: []string{"id", "name"}}, mock.Anything).
+		Run(func(args mock.Arguments) {
+			fn := args.Get(2).(func(*model.UserModel) error)
+			for _, user := range users {
+				assert.NoError(t, fn(user))
+			}
+		}).
+		Return(nil)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user/export?format=ndjson&select_field=id,name&order_by=name", strings.NewReader(""))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	assert.Len(t, lines, 2)
+	for i, line := range lines {
+		row := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &row))
+		assert.Equal(t, map[string]interface{}{"id": users[i].ID, "name": users[i].Name}, row)
+	}
+}
+
+func TestDeliveryExportEmpty(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	mockService := new(mocks.Service)
+	mockService.On("Export", mock.Anything, &usr.UserCriteria{Fields: []string{"id", "email"}}, mock.Anything).Return(nil)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user/export?select_field=id,email", strings.NewReader(""))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ID,Email\n", w.Body.String())
+}
+
+func TestDeliveryExportFailFormat(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	mockService := new(mocks.Service)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user/export?format=xml", strings.NewReader(""))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "Export", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeliveryExportFail(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	mockService := new(mocks.Service)
+	mockService.On("Export", mock.Anything, &usr.UserCriteria{}, mock.Anything).Return(errors.New("Oops! Something went wrong. Please try again later"))
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user/export", strings.NewReader(""))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+}
+
+func TestDeliveryImportCSV(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	mockService := new(mocks.Service)
+	mockService.On("Import", mock.Anything, mock.MatchedBy(func(reqs []*form.UserForm) bool {
+		return len(reqs) == 2 && reqs[0].Name == "Momo" && reqs[1].Email == "gendhis@mail.com" && reqs[1].Phone == "+6285641123456" && len(reqs[0].ID) > 0
+	}), false, false).Return(&usr.ImportResult{Created: 1, Rejected: map[string]bool{"gendhis@mail.com": true}}, nil)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	body := "Name,Email,Phone,Address\n" +
+		"Momo,momo@mail.com,085640123456,Indonesia\n" +
+		",invalid,085640123456,Indonesia\n" +
+		"Gendhis,gendhis@mail.com,'+6285641123456,Indonesia\n" +
+		"Broken,row\n"
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/user/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	router.ServeHTTP(w, req)
+
+	resp := &model.ImportResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), resp)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 4, resp.Data.Total)
+	assert.Equal(t, 1, resp.Data.Created)
+	assert.Equal(t, []int{1}, resp.Data.Accepted)
+	assert.Len(t, resp.Data.Rejected, 3)
+	assert.Equal(t, 2, resp.Data.Rejected[0].Row)
+	assert.Equal(t, "name", resp.Data.Rejected[0].Errors[0].Field)
+	assert.Equal(t, "email", resp.Data.Rejected[0].Errors[1].Field)
+	assert.Equal(t, 3, resp.Data.Rejected[1].Row)
+	assert.Equal(t, "Email is already registered", resp.Data.Rejected[1].Errors[0].Detail)
+	assert.Equal(t, 4, resp.Data.Rejected[2].Row)
+	assert.Equal(t, "Invalid row format", resp.Data.Rejected[2].Errors[0].Detail)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeliveryImportNDJSONDryRun(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	mockService := new(mocks.Service)
+	mockService.On("Import", mock.Anything, mock.MatchedBy(func(reqs []*form.UserForm) bool {
+		return len(reqs) == 2
+	}), false, true).Return(&usr.ImportResult{Rejected: map[string]bool{"gendhis@mail.com": true}}, nil)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	body := `{"name":"Momo","email":"momo@mail.com"}` + "\n\n" + `{"name":` + "\n" + `{"name":"Gendhis","email":"Gendhis@mail.com"}` + "\n"
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/user/import?dry_run=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	router.ServeHTTP(w, req)
+
+	resp := &model.ImportResponse{}
+	err := json.Unmarshal(w.Body.Bytes(), resp)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, resp.Data.DryRun)
+	assert.Zero(t, resp.Data.Created)
+	assert.Equal(t, []int{1}, resp.Data.Accepted)
+	assert.Len(t, resp.Data.Rejected, 2)
+	assert.Equal(t, 3, resp.Data.Rejected[0].Row)
+	assert.Equal(t, 4, resp.Data.Rejected[1].Row)
+	assert.Equal(t, "email", resp.Data.Rejected[1].Errors[0].Field)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeliveryImportUpsert(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	mockService := new(mocks.Service)
+	mockService.On("Import", mock.Anything, mock.MatchedBy(func(reqs []*form.UserForm) bool {
+		return len(reqs) == 1
+	}), true, false).Return(&usr.ImportResult{Updated: 1, Rejected: map[string]bool{}}, nil)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	body := &strings.Builder{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "users.csv")
+	assert.NoError(t, err)
+	_, err = part.Write([]byte("name,email\nMomo,momo@mail.com\nMomo Again,MOMO@mail.com\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/user/import?upsert=true", strings.NewReader(body.String()))
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	router.ServeHTTP(w, req)
+
+	resp := &model.ImportResponse{}
+	err = json.Unmarshal(w.Body.Bytes(), resp)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, resp.Data.Updated)
+	assert.Equal(t, []int{1}, resp.Data.Accepted)
+	assert.Equal(t, 2, resp.Data.Rejected[0].Row)
+	assert.Equal(t, "email", resp.Data.Rejected[0].Errors[0].Field)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeliveryImportFailMediaType(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	mockService := new(mocks.Service)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/user/import", strings.NewReader("<users/>"))
+	req.Header.Set("Content-Type", "application/xml")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestDeliveryImportFail(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	mockService := new(mocks.Service)
+	mockService.On("Import", mock.Anything, mock.Anything, false, false).Return(nil, errors.New("Oops! Something went wrong. Please try again later"))
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/user/import?format=csv", strings.NewReader("name,email\nMomo,momo@mail.com\n"))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestDeliveryDelete(t *testing.T) {
+	id := xid.New().String()
+
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	mockService := new(mocks.Service)
+	mockService.On("Delete", mock.Anything, id).Return(nil)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/user/"+id, strings.NewReader(""))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestDeliveryDeleteFail(t *testing.T) {
+	id := xid.New().String()
+
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	mockService := new(mocks.Service)
+	mockService.On("Delete", mock.Anything, id).Return(errors.New("Unexpected database error"))
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/user/"+id, strings.NewReader(""))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestDeliveryEvents(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	replay := []*model.UserEvent{
+		{ID: 4, Type: usr.EventUserCreated, User: &model.UserModel{ID: "b1", Name: "Momo"}},
+	}
+	events := make(chan *model.UserEvent, 1)
+	cancelled := make(chan bool, 1)
+
+	mockService := new(mocks.Service)
+	mockService.On("Subscribe", uint64(3)).Return(replay, (<-chan *model.UserEvent)(events), func() { cancelled <- true })
+
+	server := httptest.NewServer(routers.GetRouter(lang, log, mockService, nil))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/v1/user/events", nil)
+	req.Header.Set("Last-Event-ID", "3")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() []string {
+		lines := []string{}
+		for {
+			line, err := reader.ReadString('\n')
+			assert.NoError(t, err)
+			if line == "\n" {
+				return lines
+			}
+			lines = append(lines, strings.TrimSuffix(line, "\n"))
+		}
+	}
+
+	lines := readEvent()
+	assert.Equal(t, "id: 4", lines[0])
+	assert.Equal(t, "event: user.created", lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], `data: {"id":"b1","name":"Momo"`))
+
+	events <- &model.UserEvent{ID: 5, Type: usr.EventUserDeleted, User: &model.UserModel{ID: "b1"}}
+	lines = readEvent()
+	assert.Equal(t, []string{"id: 5", "event: user.deleted"}, lines[:2])
+
+	resp.Body.Close()
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("subscription is not cancelled after client disconnected")
+	}
+}
+
+func TestDeliveryEventsFailLastEventID(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	mockService := new(mocks.Service)
+
+	router := routers.GetRouter(lang, log, mockService, nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/user/events?last_event_id=abc", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNotCalled(t, "Subscribe", mock.Anything)
 }
@@ -0,0 +1,269 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package http
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	usr "github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+	cons "github.com/moemoe89/go-graphql-gendhis/constant"
+
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/xid"
+)
+
+// ImportMaxBytes represent the maximum size of import upload
+const ImportMaxBytes = 10 << 20
+
+var (
+	errInvalidImport     = errors.New("Invalid import file")
+	errUnsupportedImport = errors.New("Unsupported import media type")
+)
+
+// importRow represent the parsed row of import upload, message is the rejection reason of malformed row
+type importRow struct {
+	row     int
+	form    *form.UserForm
+	message string
+}
+
+//
+// @Summary User Import
+// @Description import user data from CSV or NDJSON, every row is validated and the valid rows are inserted,
+// @Description the rows whose email is registered already are rejected unless upsert
+// @Accept  text/csv
+// @Accept  application/x-ndjson
+// @Accept  multipart/form-data
+// @Produce  json
+// @Produce  application/problem+json
+// @Param Accept-Language header string false "language message response"
+// @Param format query string false "Import format, csv or ndjson, by default from Content-Type or file extension"
+// @Param dry_run query bool false "Only validate the rows and look up the registered emails without writing"
+// @Param upsert query bool false "Update the existing user with the same email instead of inserting"
+// @Param file formData file false "Upload file"
+// @Success 200 {object} model.ImportResponse
+// @Failure 400 {object} model.GenericResponse
+// @Failure 409 {object} model.GenericResponse
+// @Failure 415 {object} model.GenericResponse
+// @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
+// @Router /user/import [post]
+func (u *userCtrl) Import(c *gin.Context) {
+	l := c.Request.Header.Get("Accept-Language")
+	resp := &model.ImportResponse{}
+
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	upsert, _ := strconv.ParseBool(c.Query("upsert"))
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, ImportMaxBytes)
+
+	body := io.Reader(c.Request.Body)
+	format := c.Query("format")
+	if c.ContentType() == gin.MIMEMultipartPOSTForm {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			u.log.Errorf("can't get import file: %s", err.Error())
+			u.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, errInvalidImport.Error())
+			return
+		}
+		defer file.Close()
+
+		body = file
+		if len(format) == 0 {
+			format = importFormat(filepath.Ext(header.Filename))
+		}
+	}
+	if len(format) == 0 {
+		format = importFormat(c.ContentType())
+	}
+
+	var rows []*importRow
+	var err error
+	switch format {
+	case ExportCSV:
+		rows, err = parseImportCSV(body)
+	case ExportNDJSON:
+		rows, err = parseImportNDJSON(body)
+	default:
+		u.errorResponse(c, http.StatusUnsupportedMediaType, model.CodeUnsupportedMediaType, errUnsupportedImport.Error())
+		return
+	}
+	if err != nil {
+		u.log.Errorf("can't parse import file: %s", err.Error())
+		u.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, errInvalidImport.Error())
+		return
+	}
+
+	report := &model.ImportReport{DryRun: dryRun, Total: len(rows), Accepted: []int{}, Rejected: []*model.ImportRejection{}}
+	accepted := []*form.UserForm{}
+	acceptedRows := []int{}
+	emails := map[string]bool{}
+	for _, r := range rows {
+		details := []*model.ErrorDetail{}
+		if len(r.message) > 0 {
+			details = append(details, &model.ErrorDetail{Detail: u.lang.Lookup(l, r.message)})
+		} else if errs := r.form.Validate(); len(errs) > 0 {
+			details = u.errorDetails(l, errs)
+		} else if email := strings.ToLower(r.form.Email); emails[email] {
+			details = append(details, &model.ErrorDetail{Field: "email", Detail: u.lang.Lookup(l, "Duplicate email in import file")})
+		} else {
+			emails[email] = true
+		}
+
+		if len(details) > 0 {
+			report.Rejected = append(report.Rejected, &model.ImportRejection{Row: r.row, Errors: details})
+			continue
+		}
+
+		r.form.ID = xid.New().String()
+		accepted = append(accepted, r.form)
+		acceptedRows = append(acceptedRows, r.row)
+	}
+
+	message := "OK"
+	if len(accepted) > 0 {
+		result, err := u.svc.Import(c.Request.Context(), accepted, upsert, dryRun)
+		if err != nil {
+			u.serviceError(c, err)
+			return
+		}
+		report.Created = result.Created
+		report.Updated = result.Updated
+		if !dryRun {
+			message = "Imported data successful"
+		}
+
+		// the rows whose email is registered already are rejected, the others are imported
+		for i, req := range accepted {
+			if !result.Rejected[strings.ToLower(req.Email)] {
+				report.Accepted = append(report.Accepted, acceptedRows[i])
+				continue
+			}
+			report.Rejected = append(report.Rejected, &model.ImportRejection{Row: acceptedRows[i], Errors: []*model.ErrorDetail{
+				{Field: "email", Detail: u.lang.Lookup(l, usr.ErrEmailTaken.Error())},
+			}})
+		}
+		sort.Slice(report.Rejected, func(i, j int) bool {
+			return report.Rejected[i].Row < report.Rejected[j].Row
+		})
+	}
+
+	resp.GenericResponse = model.NewGenericResponse(http.StatusOK, cons.OK, []string{u.lang.Lookup(l, message)})
+	resp.Data = report
+	c.JSON(http.StatusOK, resp)
+}
+
+// importFormat will get the import format from file extension or media type
+func importFormat(s string) string {
+	switch strings.ToLower(s) {
+	case ".csv", "text/csv":
+		return ExportCSV
+	case ".ndjson", ".jsonl", "application/x-ndjson":
+		return ExportNDJSON
+	}
+
+	return ""
+}
+
+// parseImportCSV will parse the CSV with header row, the columns are matched by the json name of UserForm
+// and the rows are numbered from the first record after header
+func parseImportCSV(body io.Reader) ([]*importRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch name {
+		case "name", "email", "phone", "region", "address":
+			columns[name] = i
+		}
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("no known column in CSV header")
+	}
+
+	rows := []*importRow{}
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if perr, ok := err.(*csv.ParseError); ok && perr.Err == csv.ErrFieldCount {
+			rows = append(rows, &importRow{row: n, message: "Invalid row format"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		value := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return csvUnescape(strings.TrimSpace(record[i]))
+			}
+			return ""
+		}
+
+		rows = append(rows, &importRow{row: n, form: &form.UserForm{
+			Name:    value("name"),
+			Email:   value("email"),
+			Phone:   value("phone"),
+			Address: value("address"),
+			Region:  value("region"),
+		}})
+	}
+
+	return rows, nil
+}
+
+// csvUnescape will drop the ' written by export before the text starting like a formula, so the exported CSV
+// imports as is
+func csvUnescape(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(csvFormulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+
+	return s
+}
+
+// parseImportNDJSON will parse one UserForm object per line, the rows are numbered by line and blank lines are skipped
+func parseImportNDJSON(body io.Reader) ([]*importRow, error) {
+	scanner := bufio.NewScanner(body)
+
+	rows := []*importRow{}
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		req := &form.UserForm{}
+		if err := json.Unmarshal([]byte(line), req); err != nil {
+			rows = append(rows, &importRow{row: n, message: "Invalid row format"})
+			continue
+		}
+		rows = append(rows, &importRow{row: n, form: req})
+	}
+
+	return rows, scanner.Err()
+}
@@ -0,0 +1,38 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package http
+
+import (
+	"errors"
+
+	"github.com/evanphx/json-patch"
+)
+
+const (
+	// MergePatchType represent the JSON Merge Patch media type (RFC 7396)
+	MergePatchType = "application/merge-patch+json"
+	// JSONPatchType represent the JSON Patch media type (RFC 6902)
+	JSONPatchType = "application/json-patch+json"
+)
+
+var errUnsupportedPatch = errors.New("Unsupported patch media type")
+
+// applyPatch will apply the patch document to the original document based on the patch media type
+func applyPatch(contentType string, original, patch []byte) ([]byte, error) {
+	switch contentType {
+	case MergePatchType:
+		return jsonpatch.MergePatch(original, patch)
+	case JSONPatchType:
+		p, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, err
+		}
+		return p.Apply(original)
+	}
+
+	return nil, errUnsupportedPatch
+}
@@ -0,0 +1,100 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package http
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	usr "github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+	cons "github.com/moemoe89/go-graphql-gendhis/constant"
+
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serviceError will write the error returned by service with the status and code of its kind, the
+// unexpected error is never exposed
+func (u *userCtrl) serviceError(c *gin.Context, err error) {
+	var validationErr *usr.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		u.validationResponse(c, validationErr.Fields)
+	case errors.Is(err, usr.ErrValidation):
+		u.errorResponse(c, http.StatusBadRequest, model.CodeValidationFailed, usr.ErrValidation.Error())
+	case errors.Is(err, usr.ErrNotFound):
+		u.errorResponse(c, http.StatusNotFound, model.CodeUserNotFound, usr.ErrNotFound.Error())
+	case errors.Is(err, usr.ErrEmailTaken):
+		u.errorResponse(c, http.StatusConflict, model.CodeConflict, usr.ErrEmailTaken.Error())
+	case errors.Is(err, usr.ErrConflict):
+		u.errorResponse(c, http.StatusConflict, model.CodeConflict, usr.ErrConflict.Error())
+	case errors.Is(err, usr.ErrUnavailable):
+		u.errorResponse(c, http.StatusServiceUnavailable, model.CodeServiceUnavailable, usr.ErrUnavailable.Error())
+	default:
+		u.errorResponse(c, http.StatusInternalServerError, model.CodeInternalError, "Oops! Something went wrong. Please try again later")
+	}
+}
+
+// errorResponse will write the localized error response from the message keys
+func (u *userCtrl) errorResponse(c *gin.Context, status int, code string, messages ...string) {
+	l := c.Request.Header.Get("Accept-Language")
+
+	messagesLocale := []string{}
+	for _, m := range messages {
+		messagesLocale = append(messagesLocale, u.lang.Lookup(l, m))
+	}
+
+	u.writeError(c, status, code, messagesLocale, nil)
+}
+
+// validationResponse will write the localized field-level validation problems
+func (u *userCtrl) validationResponse(c *gin.Context, errs form.ValidationErrors) {
+	l := c.Request.Header.Get("Accept-Language")
+
+	messagesLocale := []string{}
+	details := u.errorDetails(l, errs)
+	for _, d := range details {
+		messagesLocale = append(messagesLocale, d.Detail)
+	}
+
+	u.writeError(c, http.StatusBadRequest, model.CodeValidationFailed, messagesLocale, details)
+}
+
+// errorDetails will localize the field-level validation problems
+func (u *userCtrl) errorDetails(l string, errs form.ValidationErrors) []*model.ErrorDetail {
+	details := []*model.ErrorDetail{}
+	for _, e := range errs {
+		details = append(details, &model.ErrorDetail{
+			Field:  e.Field,
+			Rule:   e.Rule,
+			Params: e.Params,
+			Detail: e.Localize(u.lang, l),
+		})
+	}
+
+	return details
+}
+
+// writeError will use problem+json when requested by Accept header and the legacy
+// GenericResponse envelope otherwise
+func (u *userCtrl) writeError(c *gin.Context, status int, code string, messages []string, details []*model.ErrorDetail) {
+	if c.NegotiateFormat(gin.MIMEJSON, model.ProblemJSONType) != model.ProblemJSONType {
+		resp := model.NewGenericResponse(status, cons.ERR, messages)
+		resp.Errors = details
+		c.JSON(status, resp)
+		return
+	}
+
+	detail := ""
+	if len(messages) > 0 {
+		detail = messages[0]
+	}
+
+	c.Header("Content-Type", model.ProblemJSONType)
+	c.JSON(status, model.NewProblemResponse(status, code, detail, c.Request.URL.Path, details))
+}
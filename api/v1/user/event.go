@@ -0,0 +1,108 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"sync"
+)
+
+const (
+	// EventUserCreated represent the event type of created user
+	EventUserCreated = "user.created"
+	// EventUserUpdated represent the event type of updated or patched user
+	EventUserUpdated = "user.updated"
+	// EventUserDeleted represent the event type of deleted user
+	EventUserDeleted = "user.deleted"
+
+	// EventBufferSize represent the number of latest events kept for replay
+	EventBufferSize = 1000
+	// eventSubscriberSize represent the number of events queued for one subscriber before it's dropped
+	eventSubscriberSize = 64
+)
+
+// Broker represent the in-memory fan-out of user events with bounded replay buffer
+type Broker struct {
+	mu          sync.Mutex
+	size        int
+	lastID      uint64
+	buffer      []*model.UserEvent
+	subscribers map[chan *model.UserEvent]struct{}
+}
+
+// NewBroker will create the broker keeping the latest size events for replay
+func NewBroker(size int) *Broker {
+	if size < 1 {
+		size = EventBufferSize
+	}
+
+	return &Broker{
+		size:        size,
+		subscribers: map[chan *model.UserEvent]struct{}{},
+	}
+}
+
+// Publish will assign the next id to event, keep it for replay and send it to every subscriber,
+// the subscriber whose queue is full is closed so it can resume with the last received id
+func (b *Broker) Publish(eventType string, user *model.UserModel) *model.UserEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := &model.UserEvent{ID: b.lastID, Type: eventType, User: user}
+
+	b.buffer = append(b.buffer, event)
+	if len(b.buffer) > b.size {
+		b.buffer = b.buffer[len(b.buffer)-b.size:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return event
+}
+
+// Subscribe will return the buffered events after lastID and the channel of next events, the
+// channel is closed by cancel or when the subscriber is too slow. Zero lastID replays nothing,
+// while lastID newer than the latest event, e.g. issued before restart, replays the whole buffer
+func (b *Broker) Subscribe(lastID uint64) ([]*model.UserEvent, <-chan *model.UserEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stale := lastID > b.lastID
+
+	replay := []*model.UserEvent{}
+	if lastID > 0 {
+		for _, event := range b.buffer {
+			if stale || event.ID > lastID {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	ch := make(chan *model.UserEvent, eventSubscriberSize)
+	b.subscribers[ch] = struct{}{}
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return replay, ch, cancel
+}
@@ -0,0 +1,86 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func eventIDs(events []*model.UserEvent) []uint64 {
+	ids := []uint64{}
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+
+	return ids
+}
+
+func TestBrokerReplay(t *testing.T) {
+	b := user.NewBroker(3)
+	for i := 0; i < 5; i++ {
+		b.Publish(user.EventUserCreated, &model.UserModel{})
+	}
+
+	replay, _, cancel := b.Subscribe(0)
+	defer cancel()
+	assert.Empty(t, replay)
+
+	replay, _, cancel = b.Subscribe(3)
+	defer cancel()
+	assert.Equal(t, []uint64{4, 5}, eventIDs(replay))
+
+	// older than the buffer, only the kept events are replayed
+	replay, _, cancel = b.Subscribe(1)
+	defer cancel()
+	assert.Equal(t, []uint64{3, 4, 5}, eventIDs(replay))
+
+	// newer than the latest, e.g. issued before restart
+	replay, _, cancel = b.Subscribe(100)
+	defer cancel()
+	assert.Equal(t, []uint64{3, 4, 5}, eventIDs(replay))
+}
+
+func TestBrokerSubscribe(t *testing.T) {
+	b := user.NewBroker(user.EventBufferSize)
+
+	_, events, cancel := b.Subscribe(0)
+
+	created := b.Publish(user.EventUserCreated, &model.UserModel{ID: "1"})
+	deleted := b.Publish(user.EventUserDeleted, &model.UserModel{ID: "1"})
+
+	assert.Equal(t, created, <-events)
+	assert.Equal(t, deleted, <-events)
+	assert.True(t, deleted.ID > created.ID)
+
+	cancel()
+	cancel()
+
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestBrokerDropSlowSubscriber(t *testing.T) {
+	b := user.NewBroker(user.EventBufferSize)
+
+	_, events, cancel := b.Subscribe(0)
+	defer cancel()
+
+	for i := 0; i < 100; i++ {
+		b.Publish(user.EventUserUpdated, &model.UserModel{})
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	assert.True(t, received < 100)
+}
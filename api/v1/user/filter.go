@@ -0,0 +1,290 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// OpEq represent the equal filter operator
+	OpEq = "eq"
+	// OpNe represent the not equal filter operator
+	OpNe = "ne"
+	// OpGt represent the greater than filter operator
+	OpGt = "gt"
+	// OpGte represent the greater than or equal filter operator
+	OpGte = "gte"
+	// OpLt represent the less than filter operator
+	OpLt = "lt"
+	// OpLte represent the less than or equal filter operator
+	OpLte = "lte"
+	// OpIn represent the one of values filter operator
+	OpIn = "in"
+	// OpContains represent the substring filter operator
+	OpContains = "contains"
+	// OpStartsWith represent the prefix filter operator
+	OpStartsWith = "startswith"
+	// OpEndsWith represent the suffix filter operator
+	OpEndsWith = "endswith"
+	// OpIsNull represent the null check filter operator
+	OpIsNull = "isnull"
+)
+
+// ErrInvalidFilter represent the error of malformed or unsupported filter expression
+var ErrInvalidFilter = errors.New("Invalid filter expression")
+
+var comparisonOps = map[string]string{
+	OpEq:  "=",
+	OpNe:  "<>",
+	OpGt:  ">",
+	OpGte: ">=",
+	OpLt:  "<",
+	OpLte: "<=",
+}
+
+var likeOps = map[string]string{
+	OpContains:   "%%%s%%",
+	OpStartsWith: "%s%%",
+	OpEndsWith:   "%%%s",
+}
+
+// filterColumns holds the filterable columns of model.UserModel and whether it is a text column
+var filterColumns = userFilterColumns()
+
+func userFilterColumns() map[string]bool {
+	columns := map[string]bool{}
+	t := reflect.TypeOf(model.UserModel{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Tag.Get("json") == "-" {
+			continue
+		}
+		columns[f.Tag.Get("db")] = f.Type.Kind() == reflect.String
+	}
+
+	return columns
+}
+
+// timeColumns holds the time columns of model.UserModel, the arguments bound to them are timestamps
+var timeColumns = userTimeColumns()
+
+func userTimeColumns() map[string]bool {
+	columns := map[string]bool{}
+	t := reflect.TypeOf(model.UserModel{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type == reflect.TypeOf(time.Time{}) || f.Type == reflect.TypeOf(&time.Time{}) {
+			columns[f.Tag.Get("db")] = true
+		}
+	}
+
+	return columns
+}
+
+// Filter represent the node of user filter expression, either a column condition or a logical group
+type Filter struct {
+	Field string
+	Op    string
+	Value interface{}
+
+	And []*Filter
+	Or  []*Filter
+	Not *Filter
+}
+
+// ParseFilter will parse the REST filter expression like `email:endswith:@acme.com,created_at:gte:2024-01-01`,
+// the conditions are joined with AND and the values of `in` operator are separated by `|`
+func ParseFilter(expr string) (*Filter, error) {
+	root := &Filter{}
+	for _, cond := range strings.Split(expr, ",") {
+		parts := strings.SplitN(cond, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("%w: %q must be field:operator:value", ErrInvalidFilter, cond)
+		}
+
+		var value interface{} = parts[2]
+		switch parts[1] {
+		case OpIn:
+			value = strings.Split(parts[2], "|")
+		case OpIsNull:
+			isNull, err := strconv.ParseBool(parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("%w: %q isnull value must be a boolean", ErrInvalidFilter, cond)
+			}
+			value = isNull
+		}
+
+		root.And = append(root.And, &Filter{Field: parts[0], Op: parts[1], Value: value})
+	}
+
+	return root, nil
+}
+
+// Compile will compile the filter into parameterized SQL condition, the named arguments are put into args
+func (f *Filter) Compile(args map[string]interface{}) (string, error) {
+	return f.compileLike(args, nil, "%s LIKE %s")
+}
+
+// compileLike will compile the filter with like as the format of case-sensitive LIKE condition of dialect,
+// taking the field and the pattern escaped with backslash. The names of arguments bound to time columns are
+// put into timeArgs when it's given
+func (f *Filter) compileLike(args map[string]interface{}, timeArgs map[string]bool, like string) (string, error) {
+	c := &filterCompiler{args: args, timeArgs: timeArgs, like: like}
+	return c.compile(f)
+}
+
+type filterCompiler struct {
+	args     map[string]interface{}
+	timeArgs map[string]bool
+	like     string
+	count    int
+}
+
+// bind will put the value compared with field into named arguments and return the placeholder
+func (c *filterCompiler) bind(field string, value interface{}) string {
+	name := fmt.Sprintf("filter_%d", c.count)
+	c.count++
+	c.args[name] = value
+	if c.timeArgs != nil && timeColumns[field] {
+		c.timeArgs[name] = true
+	}
+	return ":" + name
+}
+
+func (c *filterCompiler) compile(f *Filter) (string, error) {
+	conds := []string{}
+
+	if len(f.Field) > 0 {
+		cond, err := c.condition(f)
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, cond)
+	}
+
+	if len(f.And) > 0 {
+		cond, err := c.group(f.And, " AND ")
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, cond)
+	}
+
+	if len(f.Or) > 0 {
+		cond, err := c.group(f.Or, " OR ")
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, cond)
+	}
+
+	if f.Not != nil {
+		cond, err := c.compile(f.Not)
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, "NOT "+cond)
+	}
+
+	if len(conds) == 0 {
+		return "", fmt.Errorf("%w: empty filter", ErrInvalidFilter)
+	}
+
+	if len(conds) == 1 {
+		return conds[0], nil
+	}
+
+	return "(" + strings.Join(conds, " AND ") + ")", nil
+}
+
+func (c *filterCompiler) group(filters []*Filter, sep string) (string, error) {
+	conds := []string{}
+	for _, f := range filters {
+		cond, err := c.compile(f)
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, cond)
+	}
+
+	return "(" + strings.Join(conds, sep) + ")", nil
+}
+
+func (c *filterCompiler) condition(f *Filter) (string, error) {
+	isText, ok := filterColumns[f.Field]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown field %q", ErrInvalidFilter, f.Field)
+	}
+
+	if op, ok := comparisonOps[f.Op]; ok {
+		value, err := scalarValue(f)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %s %s", f.Field, op, c.bind(f.Field, value)), nil
+	}
+
+	if pattern, ok := likeOps[f.Op]; ok {
+		if !isText {
+			return "", fmt.Errorf("%w: operator %q needs text field, got %q", ErrInvalidFilter, f.Op, f.Field)
+		}
+		value, err := scalarValue(f)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(c.like, f.Field, c.bind(f.Field, fmt.Sprintf(pattern, escapeLike(fmt.Sprint(value))))), nil
+	}
+
+	switch f.Op {
+	case OpIn:
+		values, ok := f.Value.([]string)
+		if !ok || len(values) == 0 {
+			return "", fmt.Errorf("%w: operator in needs a list of values for %q", ErrInvalidFilter, f.Field)
+		}
+		placeholders := []string{}
+		for _, v := range values {
+			placeholders = append(placeholders, c.bind(f.Field, v))
+		}
+		return fmt.Sprintf("%s IN (%s)", f.Field, strings.Join(placeholders, ", ")), nil
+	case OpIsNull:
+		isNull, ok := f.Value.(bool)
+		if !ok {
+			return "", fmt.Errorf("%w: operator isnull needs a boolean for %q", ErrInvalidFilter, f.Field)
+		}
+		if isNull {
+			return f.Field + " IS NULL", nil
+		}
+		return f.Field + " IS NOT NULL", nil
+	}
+
+	return "", fmt.Errorf("%w: unknown operator %q", ErrInvalidFilter, f.Op)
+}
+
+// scalarValue will make sure the condition value is a single value
+func scalarValue(f *Filter) (interface{}, error) {
+	switch v := f.Value.(type) {
+	case string:
+		return v, nil
+	case time.Time:
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("%w: operator %q needs a single value for %q", ErrInvalidFilter, f.Op, f.Field)
+}
+
+// escapeLike will escape the LIKE wildcards inside value so it is matched literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
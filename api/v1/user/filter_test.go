@@ -0,0 +1,81 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	f, err := user.ParseFilter("email:endswith:@acme.com,created_at:gte:2024-01-01T10:00:00Z,id:in:a|b,deleted:isnull:true")
+
+	assert.NoError(t, err)
+	assert.Len(t, f.And, 4)
+	assert.Equal(t, &user.Filter{Field: "email", Op: user.OpEndsWith, Value: "@acme.com"}, f.And[0])
+	assert.Equal(t, "2024-01-01T10:00:00Z", f.And[1].Value)
+	assert.Equal(t, []string{"a", "b"}, f.And[2].Value)
+	assert.Equal(t, true, f.And[3].Value)
+}
+
+func TestParseFilterFail(t *testing.T) {
+	for _, expr := range []string{"email", "email:eq", "phone:isnull:maybe"} {
+		_, err := user.ParseFilter(expr)
+
+		assert.True(t, errors.Is(err, user.ErrInvalidFilter), expr)
+	}
+}
+
+func TestFilterCompile(t *testing.T) {
+	f := &user.Filter{
+		And: []*user.Filter{
+			{Field: "email", Op: user.OpEndsWith, Value: "@acme.com"},
+			{
+				Or: []*user.Filter{
+					{Field: "name", Op: user.OpIn, Value: []string{"Momo", "Baruno"}},
+					{Field: "phone", Op: user.OpIsNull, Value: false},
+				},
+			},
+		},
+		Not: &user.Filter{Field: "name", Op: user.OpContains, Value: "50%_off"},
+	}
+
+	args := map[string]interface{}{}
+	cond, err := f.Compile(args)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "((email LIKE :filter_0 AND (name IN (:filter_1, :filter_2) OR phone IS NOT NULL)) AND NOT name LIKE :filter_3)", cond)
+	assert.Equal(t, map[string]interface{}{
+		"filter_0": "%@acme.com",
+		"filter_1": "Momo",
+		"filter_2": "Baruno",
+		"filter_3": `%50\%\_off%`,
+	}, args)
+}
+
+func TestFilterCompileFail(t *testing.T) {
+	filters := []*user.Filter{
+		{},
+		{Field: "deleted_at", Op: user.OpIsNull, Value: true},
+		{Field: "name; DROP TABLE users", Op: user.OpEq, Value: "Momo"},
+		{Field: "name", Op: "like", Value: "Momo"},
+		{Field: "created_at", Op: user.OpContains, Value: "2020"},
+		{Field: "name", Op: user.OpEq, Value: []string{"Momo"}},
+		{Field: "name", Op: user.OpIn, Value: "Momo"},
+		{Field: "name", Op: user.OpIsNull, Value: "true"},
+	}
+
+	for _, f := range filters {
+		_, err := f.Compile(map[string]interface{}{})
+
+		assert.True(t, errors.Is(err, user.ErrInvalidFilter), f.Field)
+	}
+}
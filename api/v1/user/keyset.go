@@ -0,0 +1,147 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrInvalidCursor represent the error of malformed cursor or cursor from another ordering
+var ErrInvalidCursor = errors.New("Invalid pagination cursor")
+
+// keysetTieBreakers holds the columns appended after custom ordering, so the ordering is stable
+var keysetTieBreakers = []string{"created_at", "id"}
+
+// Keyset represent the keyset pagination of users ordered by a stable key
+type Keyset struct {
+	Columns []string
+	Desc    bool
+}
+
+// NewKeyset will create keyset from the first ordering, created_at and id are appended as tie-breakers
+// and the default ordering is newest first
+func NewKeyset(sort []Sort) *Keyset {
+	k := &Keyset{Desc: true}
+
+	if len(sort) > 0 && sort[0].Field != "deleted_at" {
+		k.Desc = sort[0].Desc
+		if sort[0].Field != "created_at" && sort[0].Field != "id" {
+			k.Columns = append(k.Columns, sort[0].Field)
+		}
+	}
+	k.Columns = append(k.Columns, keysetTieBreakers...)
+
+	return k
+}
+
+// OrderBy will return the ordering clause, reversed when paging backward
+func (k *Keyset) OrderBy(backward bool) string {
+	dir := "ASC"
+	if k.Desc != backward {
+		dir = "DESC"
+	}
+
+	orders := []string{}
+	for _, col := range k.Columns {
+		orders = append(orders, col+" "+dir)
+	}
+
+	return strings.Join(orders, ", ")
+}
+
+// SelectField will make sure the key columns are part of selected columns, so the cursor can be built
+func (k *Keyset) SelectField(selectField string) string {
+	selected := map[string]bool{}
+	for _, col := range strings.Split(selectField, ",") {
+		selected[col] = true
+	}
+
+	for _, col := range k.Columns {
+		if !selected[col] {
+			selectField += "," + col
+		}
+	}
+
+	return selectField
+}
+
+// Condition will compile the cursor into row comparison condition, the named arguments are put into args
+func (k *Keyset) Condition(cursor *Cursor, args map[string]interface{}) (string, error) {
+	if cursor.OrderBy != k.OrderBy(false) || len(cursor.Values) != len(k.Columns) {
+		return "", ErrInvalidCursor
+	}
+
+	placeholders := []string{}
+	for i, v := range cursor.Values {
+		name := cursorArg(i)
+		args[name] = v
+		placeholders = append(placeholders, ":"+name)
+	}
+
+	op := ">"
+	if k.Desc != cursor.Backward {
+		op = "<"
+	}
+
+	return fmt.Sprintf("(%s) %s (%s)", strings.Join(k.Columns, ", "), op, strings.Join(placeholders, ", ")), nil
+}
+
+// cursorArg will return the name of argument bound to the i-th key column
+func cursorArg(i int) string {
+	return fmt.Sprintf("cursor_%d", i)
+}
+
+// Cursor will build the cursor pointing at the user row
+func (k *Keyset) Cursor(user *model.UserModel, backward bool) *Cursor {
+	values := map[string]interface{}{}
+	v := reflect.ValueOf(*user)
+	for i := 0; i < v.NumField(); i++ {
+		values[v.Type().Field(i).Tag.Get("db")] = v.Field(i).Interface()
+	}
+
+	cursor := &Cursor{OrderBy: k.OrderBy(false), Backward: backward}
+	for _, col := range k.Columns {
+		cursor.Values = append(cursor.Values, values[col])
+	}
+
+	return cursor
+}
+
+// Cursor represent the position inside keyset pagination
+type Cursor struct {
+	OrderBy  string        `json:"o"`
+	Values   []interface{} `json:"v"`
+	Backward bool          `json:"b,omitempty"`
+}
+
+// Encode will encode the cursor into opaque URL-safe string
+func (c *Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor will decode the opaque cursor string
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &Cursor{}
+	if err := json.Unmarshal(raw, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,81 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKeyset(t *testing.T) {
+	keysets := map[string]*user.Keyset{
+		"":            &user.Keyset{Columns: []string{"created_at", "id"}, Desc: true},
+		"name":        &user.Keyset{Columns: []string{"name", "created_at", "id"}, Desc: false},
+		"-email":      &user.Keyset{Columns: []string{"email", "created_at", "id"}, Desc: true},
+		"created_at":  &user.Keyset{Columns: []string{"created_at", "id"}, Desc: false},
+		"-deleted_at": &user.Keyset{Columns: []string{"created_at", "id"}, Desc: true},
+	}
+
+	for orderBy, expected := range keysets {
+		assert.Equal(t, expected, user.NewKeyset(user.ParseSort(orderBy)), orderBy)
+	}
+}
+
+func TestKeysetOrderBy(t *testing.T) {
+	k := user.NewKeyset([]user.Sort{{Field: "name"}})
+
+	assert.Equal(t, "name ASC, created_at ASC, id ASC", k.OrderBy(false))
+	assert.Equal(t, "name DESC, created_at DESC, id DESC", k.OrderBy(true))
+}
+
+func TestKeysetSelectField(t *testing.T) {
+	k := user.NewKeyset([]user.Sort{{Field: "name"}})
+
+	assert.Equal(t, "name,email,created_at,id", k.SelectField("name,email"))
+	assert.Equal(t, model.UserSelectField, k.SelectField(model.UserSelectField))
+}
+
+func TestKeysetCursor(t *testing.T) {
+	k := user.NewKeyset(nil)
+	createdAt := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	u := &model.UserModel{ID: "bph2mlript32plmed820", Name: "Momo", CreatedAt: createdAt}
+
+	cursor, err := user.DecodeCursor(k.Cursor(u, false).Encode())
+	assert.NoError(t, err)
+
+	args := map[string]interface{}{}
+	cond, err := k.Condition(cursor, args)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "(created_at, id) < (:cursor_0, :cursor_1)", cond)
+	assert.Equal(t, "2020-03-01T10:00:00Z", args["cursor_0"])
+	assert.Equal(t, u.ID, args["cursor_1"])
+
+	cursor, err = user.DecodeCursor(k.Cursor(u, true).Encode())
+	assert.NoError(t, err)
+
+	cond, err = k.Condition(cursor, args)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "(created_at, id) > (:cursor_0, :cursor_1)", cond)
+}
+
+func TestKeysetCursorFail(t *testing.T) {
+	_, err := user.DecodeCursor("not a cursor")
+	assert.Equal(t, user.ErrInvalidCursor, err)
+
+	u := &model.UserModel{ID: "bph2mlript32plmed820", Name: "Momo"}
+	cursor := user.NewKeyset([]user.Sort{{Field: "name"}}).Cursor(u, false)
+
+	_, err = user.NewKeyset(nil).Condition(cursor, map[string]interface{}{})
+	assert.Equal(t, user.ErrInvalidCursor, err)
+}
@@ -0,0 +1,579 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryTimeLayouts represent the layouts of text compared with the time columns, like the literals
+// accepted by the databases
+var memoryTimeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+// memoryStore represent the users kept in memory in inserted order, the deleted users are kept with
+// deleted_at set like the soft delete of database
+type memoryStore struct {
+	users []*model.UserModel
+	index map[string]int
+}
+
+// clone will copy the store, so the copy can be changed without touching the original
+func (s *memoryStore) clone() *memoryStore {
+	c := &memoryStore{index: map[string]int{}}
+	for i, user := range s.users {
+		copied := *user
+		c.users = append(c.users, &copied)
+		c.index[user.ID] = i
+	}
+
+	return c
+}
+
+// find will return the stored user of id which isn't deleted
+func (s *memoryStore) find(id string) (*model.UserModel, bool) {
+	i, ok := s.index[id]
+	if !ok || s.users[i].DeletedAt != nil {
+		return nil, false
+	}
+
+	return s.users[i], true
+}
+
+// findEmail will return the stored user of lowercased email which isn't deleted, nil when there's none
+func (s *memoryStore) findEmail(email string) *model.UserModel {
+	for _, user := range s.users {
+		if user.DeletedAt == nil && strings.ToLower(user.Email) == email {
+			return user
+		}
+	}
+
+	return nil
+}
+
+// checkEmail will make sure no other user which isn't deleted has the email, compared case-insensitively
+// like the unique index of database
+func (s *memoryStore) checkEmail(id, email string) error {
+	for _, user := range s.users {
+		if user.ID != id && user.DeletedAt == nil && strings.EqualFold(user.Email, email) {
+			return NewError(ErrConflict, fmt.Errorf("%w: %q", ErrEmailTaken, email))
+		}
+	}
+
+	return nil
+}
+
+// insert will store the new user with created_at and updated_at set to now
+func (s *memoryStore) insert(user *model.UserModel) (*model.UserModel, error) {
+	if _, ok := s.index[user.ID]; ok {
+		return nil, NewError(ErrConflict, fmt.Errorf("duplicate id %q", user.ID))
+	}
+	if err := s.checkEmail(user.ID, user.Email); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	stored := &model.UserModel{
+		ID:        user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Phone:     user.Phone,
+		PhoneRaw:  user.PhoneRaw,
+		Address:   user.Address,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.index[stored.ID] = len(s.users)
+	s.users = append(s.users, stored)
+
+	copied := *stored
+	return &copied, nil
+}
+
+// update will set the columns of user into the stored user, sql.ErrNoRows is returned when the user is
+// missing or deleted
+func (s *memoryStore) update(user *model.UserModel, columns []string) (*model.UserModel, error) {
+	stored, ok := s.find(user.ID)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	changed := *stored
+	from := reflect.ValueOf(user).Elem()
+	to := reflect.ValueOf(&changed).Elem()
+	for _, column := range columns {
+		for i := 0; i < from.NumField(); i++ {
+			if from.Type().Field(i).Tag.Get("db") == column {
+				to.Field(i).Set(from.Field(i))
+			}
+		}
+	}
+
+	if err := s.checkEmail(changed.ID, changed.Email); err != nil {
+		return nil, err
+	}
+
+	changed.UpdatedAt = time.Now().UTC()
+	*stored = changed
+
+	return &changed, nil
+}
+
+type memoryRepository struct {
+	mu    sync.RWMutex
+	store *memoryStore
+}
+
+// NewMemoryRepository will create an object that represent the Repository interface keeping the users in memory,
+// it's safe for concurrent use and meant for tests and local development. It matches the users like the
+// database repositories do, the search matches the query as case-insensitive text like SQLite
+func NewMemoryRepository() Repository {
+	return &memoryRepository{store: &memoryStore{index: map[string]int{}}}
+}
+
+// userColumns will return the values of user by column
+func userColumns(user *model.UserModel) map[string]interface{} {
+	values := map[string]interface{}{}
+	v := reflect.ValueOf(*user)
+	for i := 0; i < v.NumField(); i++ {
+		values[v.Type().Field(i).Tag.Get("db")] = v.Field(i).Interface()
+	}
+
+	return values
+}
+
+// selectColumns will copy the given columns of user, the other columns are left empty like the
+// unselected columns of query
+func selectColumns(user *model.UserModel, columns []string) *model.UserModel {
+	selected := &model.UserModel{}
+	from := reflect.ValueOf(user).Elem()
+	to := reflect.ValueOf(selected).Elem()
+	for _, column := range columns {
+		for i := 0; i < from.NumField(); i++ {
+			if from.Type().Field(i).Tag.Get("db") == column {
+				to.Field(i).Set(from.Field(i))
+			}
+		}
+	}
+
+	return selected
+}
+
+// compareColumn will compare the column value with the argument, the text argument of time column is
+// parsed first. False is returned when they can't be compared
+func compareColumn(value, arg interface{}) (int, bool) {
+	switch v := value.(type) {
+	case string:
+		s, ok := arg.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(v, s), true
+	case time.Time:
+		t, ok := arg.(time.Time)
+		if s, isText := arg.(string); isText {
+			for _, layout := range memoryTimeLayouts {
+				if parsed, err := time.Parse(layout, s); err == nil {
+					t, ok = parsed, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case v.Before(t):
+			return -1, true
+		case v.After(t):
+			return 1, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// matchFilter will evaluate the validated filter against the column values of user
+func matchFilter(f *Filter, values map[string]interface{}) bool {
+	if len(f.Field) > 0 && !matchCondition(f, values[f.Field]) {
+		return false
+	}
+
+	for _, and := range f.And {
+		if !matchFilter(and, values) {
+			return false
+		}
+	}
+
+	if len(f.Or) > 0 {
+		matched := false
+		for _, or := range f.Or {
+			if matchFilter(or, values) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if f.Not != nil && matchFilter(f.Not, values) {
+		return false
+	}
+
+	return true
+}
+
+// matchCondition will evaluate the column condition against the value, LIKE operators are case-sensitive
+func matchCondition(f *Filter, value interface{}) bool {
+	switch f.Op {
+	case OpContains:
+		return strings.Contains(fmt.Sprint(value), fmt.Sprint(f.Value))
+	case OpStartsWith:
+		return strings.HasPrefix(fmt.Sprint(value), fmt.Sprint(f.Value))
+	case OpEndsWith:
+		return strings.HasSuffix(fmt.Sprint(value), fmt.Sprint(f.Value))
+	case OpIn:
+		for _, v := range f.Value.([]string) {
+			if cmp, ok := compareColumn(value, v); ok && cmp == 0 {
+				return true
+			}
+		}
+		return false
+	case OpIsNull:
+		// the filterable columns are never null
+		return !f.Value.(bool)
+	}
+
+	cmp, ok := compareColumn(value, f.Value)
+	if !ok {
+		return false
+	}
+
+	switch f.Op {
+	case OpEq:
+		return cmp == 0
+	case OpNe:
+		return cmp != 0
+	case OpGt:
+		return cmp > 0
+	case OpGte:
+		return cmp >= 0
+	case OpLt:
+		return cmp < 0
+	case OpLte:
+		return cmp <= 0
+	}
+
+	return false
+}
+
+// searchRank will rank the user matching the query, weighted by the matched column like the search of
+// database (name, email, then address). Zero is no match
+func searchRank(user *model.UserModel, query string) float64 {
+	query = strings.ToLower(query)
+
+	rank := 0.0
+	for weight, column := range []string{user.Address, user.Email, user.Name} {
+		if strings.Contains(strings.ToLower(column), query) {
+			rank += float64(weight + 1)
+		}
+	}
+
+	return rank
+}
+
+// matchKeyset will compare the key columns of user with the cursor, the user after the cursor in the
+// direction of paging matches
+func matchKeyset(k *Keyset, cursor *Cursor, values map[string]interface{}) bool {
+	for i, column := range k.Columns {
+		cmp, ok := compareColumn(values[column], cursor.Values[i])
+		if !ok {
+			return false
+		}
+		if cmp == 0 {
+			continue
+		}
+		if k.Desc != cursor.Backward {
+			return cmp < 0
+		}
+		return cmp > 0
+	}
+
+	return false
+}
+
+// query will return the users matching the criteria in order with their search rank, on count the cursor,
+// ordering and page are left out like compileCriteria does
+func (p *memoryRepository) query(c *UserCriteria, count bool) ([]*model.UserSearchModel, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	users := []*model.UserSearchModel{}
+	for _, user := range p.store.users {
+		if user.DeletedAt != nil {
+			continue
+		}
+
+		values := userColumns(user)
+		result := &model.UserSearchModel{UserModel: *user}
+		if len(c.Query) > 0 {
+			result.Rank = searchRank(user, c.Query)
+			if result.Rank == 0 {
+				continue
+			}
+		}
+		if c.Filter != nil && !matchFilter(c.Filter, values) {
+			continue
+		}
+		if !count && c.Cursor != nil && !matchKeyset(c.Keyset, c.Cursor, values) {
+			continue
+		}
+
+		users = append(users, result)
+	}
+
+	if count {
+		return users, nil
+	}
+
+	orders := []Sort{}
+	switch {
+	case c.Keyset != nil:
+		backward := c.Cursor != nil && c.Cursor.Backward
+		for _, column := range c.Keyset.Columns {
+			orders = append(orders, Sort{Field: column, Desc: c.Keyset.Desc != backward})
+		}
+	case len(c.Sort) > 0:
+		orders = c.Sort
+	case len(c.Query) > 0:
+		orders = []Sort{{Field: "rank", Desc: true}, {Field: "created_at", Desc: true}}
+	default:
+		orders = []Sort{{Field: "created_at", Desc: true}}
+	}
+
+	sort.SliceStable(users, func(i, j int) bool {
+		a, b := userColumns(&users[i].UserModel), userColumns(&users[j].UserModel)
+		for _, order := range orders {
+			cmp := 0
+			switch {
+			case order.Field != "rank":
+				cmp, _ = compareColumn(a[order.Field], b[order.Field])
+			case users[i].Rank < users[j].Rank:
+				cmp = -1
+			case users[i].Rank > users[j].Rank:
+				cmp = 1
+			}
+			if cmp != 0 {
+				return (cmp < 0) != order.Desc
+			}
+		}
+		return false
+	})
+
+	if c.Limit > 0 {
+		if c.Offset >= len(users) {
+			return []*model.UserSearchModel{}, nil
+		}
+		users = users[c.Offset:]
+		if c.Limit < len(users) {
+			users = users[:c.Limit]
+		}
+	}
+
+	columns := c.Columns()
+	for _, user := range users {
+		user.UserModel = *selectColumns(&user.UserModel, columns)
+		if c.Highlight {
+			user.Highlight = strings.Join([]string{user.Name, user.Email, user.Address}, " ")
+		}
+	}
+
+	return users, nil
+}
+
+func (p *memoryRepository) Get(ctx context.Context, criteria *UserCriteria) ([]*model.UserModel, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	found, err := p.query(criteria, false)
+	if err != nil {
+		return nil, err
+	}
+
+	users := []*model.UserModel{}
+	for _, user := range found {
+		copied := user.UserModel
+		users = append(users, &copied)
+	}
+
+	return users, nil
+}
+
+func (p *memoryRepository) Count(ctx context.Context, criteria *UserCriteria) (int, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	found, err := p.query(criteria, true)
+	return len(found), err
+}
+
+func (p *memoryRepository) Search(ctx context.Context, criteria *UserCriteria) ([]*model.UserSearchModel, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.query(criteria, false)
+}
+
+// Export will hand the users through fn, the users are collected before fn is called so fn can use the
+// repository
+func (p *memoryRepository) Export(ctx context.Context, criteria *UserCriteria, fn func(*model.UserModel) error) error {
+	users, err := p.Get(ctx, criteria)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *memoryRepository) Create(ctx context.Context, user *model.UserModel) (*model.UserModel, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.store.insert(user)
+}
+
+// Import will insert the users all or nothing, on upsert the users matching an existing email are updated
+// instead and their lowercased emails are returned
+func (p *memoryRepository) Import(ctx context.Context, users []*model.UserModel, upsert bool) (map[string]bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	updated := map[string]bool{}
+	store := p.store.clone()
+
+	for _, user := range users {
+		email := strings.ToLower(user.Email)
+		if upsert {
+			if existing := store.findEmail(email); existing != nil {
+				changed := *user
+				changed.ID = existing.ID
+				if _, err := store.update(&changed, []string{"name", "phone", "phone_raw", "address"}); err != nil {
+					return nil, err
+				}
+				updated[email] = true
+				continue
+			}
+		}
+
+		if _, err := store.insert(user); err != nil {
+			return nil, err
+		}
+	}
+
+	p.store = store
+
+	return updated, nil
+}
+
+// ExistingEmails will return the lowercased emails of the users not deleted among emails
+func (p *memoryRepository) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	existing := map[string]bool{}
+	for _, email := range emails {
+		email = strings.ToLower(email)
+		if p.store.findEmail(email) != nil {
+			existing[email] = true
+		}
+	}
+
+	return existing, nil
+}
+
+func (p *memoryRepository) GetByID(ctx context.Context, id, selectField string) (*model.UserModel, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	user, ok := p.store.find(id)
+	if !ok {
+		return &model.UserModel{}, sql.ErrNoRows
+	}
+
+	if len(selectField) == 0 {
+		selectField = model.UserSelectField
+	}
+
+	return selectColumns(user, strings.Split(selectField, ",")), nil
+}
+
+func (p *memoryRepository) Update(ctx context.Context, user *model.UserModel) (*model.UserModel, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.store.update(user, []string{"name", "email", "phone", "phone_raw", "address"})
+}
+
+func (p *memoryRepository) Patch(ctx context.Context, user *model.UserModel, fields []string) (*model.UserModel, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.store.update(user, fields)
+}
+
+// Delete will soft delete the user, sql.ErrNoRows is returned when the user is missing or already deleted
+func (p *memoryRepository) Delete(ctx context.Context, id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	user, ok := p.store.find(id)
+	if !ok {
+		return sql.ErrNoRows
+	}
+
+	now := time.Now().UTC()
+	user.DeletedAt = &now
+
+	return nil
+}
+
+// WithTx will run fn with the repository bound to a copy of the users, the copy replaces the users when fn
+// succeeds and it's dropped otherwise. The other calls wait until fn returns, so fn must only use the
+// given repository
+func (p *memoryRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	tx := &memoryRepository{store: p.store.clone()}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	p.store = tx.store
+
+	return nil
+}
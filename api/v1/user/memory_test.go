@@ -0,0 +1,61 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryConcurrent(t *testing.T) {
+	ctx := context.Background()
+	u := user.NewMemoryRepository()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			stored, err := u.Create(ctx, &model.UserModel{ID: xid.New().String(), Name: "Momo", Email: fmt.Sprintf("momo%d@mail.com", i)})
+			assert.NoError(t, err)
+
+			_, err = u.Get(ctx, (&user.UserCriteria{}).Where("name", user.OpEq, "Momo"))
+			assert.NoError(t, err)
+
+			stored.Name = "Momo Update"
+			_, err = u.Update(ctx, stored)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	n, err := u.Count(ctx, (&user.UserCriteria{}).Where("name", user.OpEq, "Momo Update"))
+	assert.NoError(t, err)
+	assert.Equal(t, 50, n)
+}
+
+func TestMemoryReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	u := user.NewMemoryRepository()
+
+	stored, err := u.Create(ctx, &model.UserModel{ID: xid.New().String(), Name: "Momo", Email: "momo@mail.com"})
+	assert.NoError(t, err)
+	stored.Name = "Changed"
+
+	found, err := u.GetByID(ctx, stored.ID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Momo", found.Name)
+}
@@ -2,28 +2,30 @@
 
 package mocks
 
+import context "context"
 import mock "github.com/stretchr/testify/mock"
 import model "github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+import user "github.com/moemoe89/go-graphql-gendhis/api/v1/user"
 
 // Repository is an autogenerated mock type for the Repository type
 type Repository struct {
 	mock.Mock
 }
 
-// Count provides a mock function with given fields: filter, where
-func (_m *Repository) Count(filter map[string]interface{}, where string) (int, error) {
-	ret := _m.Called(filter, where)
+// Count provides a mock function with given fields: ctx, criteria
+func (_m *Repository) Count(ctx context.Context, criteria *user.UserCriteria) (int, error) {
+	ret := _m.Called(ctx, criteria)
 
 	var r0 int
-	if rf, ok := ret.Get(0).(func(map[string]interface{}, string) int); ok {
-		r0 = rf(filter, where)
+	if rf, ok := ret.Get(0).(func(context.Context, *user.UserCriteria) int); ok {
+		r0 = rf(ctx, criteria)
 	} else {
 		r0 = ret.Get(0).(int)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(map[string]interface{}, string) error); ok {
-		r1 = rf(filter, where)
+	if rf, ok := ret.Get(1).(func(context.Context, *user.UserCriteria) error); ok {
+		r1 = rf(ctx, criteria)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -31,13 +33,13 @@ func (_m *Repository) Count(filter map[string]interface{}, where string) (int, e
 	return r0, r1
 }
 
-// Create provides a mock function with given fields: userReq
-func (_m *Repository) Create(userReq *model.UserModel) (*model.UserModel, error) {
-	ret := _m.Called(userReq)
+// Create provides a mock function with given fields: ctx, userReq
+func (_m *Repository) Create(ctx context.Context, userReq *model.UserModel) (*model.UserModel, error) {
+	ret := _m.Called(ctx, userReq)
 
 	var r0 *model.UserModel
-	if rf, ok := ret.Get(0).(func(*model.UserModel) *model.UserModel); ok {
-		r0 = rf(userReq)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.UserModel) *model.UserModel); ok {
+		r0 = rf(ctx, userReq)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*model.UserModel)
@@ -45,8 +47,8 @@ func (_m *Repository) Create(userReq *model.UserModel) (*model.UserModel, error)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*model.UserModel) error); ok {
-		r1 = rf(userReq)
+	if rf, ok := ret.Get(1).(func(context.Context, *model.UserModel) error); ok {
+		r1 = rf(ctx, userReq)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -54,13 +56,13 @@ func (_m *Repository) Create(userReq *model.UserModel) (*model.UserModel, error)
 	return r0, r1
 }
 
-// Delete provides a mock function with given fields: id
-func (_m *Repository) Delete(id string) error {
-	ret := _m.Called(id)
+// Delete provides a mock function with given fields: ctx, id
+func (_m *Repository) Delete(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -68,13 +70,50 @@ func (_m *Repository) Delete(id string) error {
 	return r0
 }
 
-// Get provides a mock function with given fields: filter, where, orderBy, selectField
-func (_m *Repository) Get(filter map[string]interface{}, where string, orderBy string, selectField string) ([]*model.UserModel, error) {
-	ret := _m.Called(filter, where, orderBy, selectField)
+// ExistingEmails provides a mock function with given fields: ctx, emails
+func (_m *Repository) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	ret := _m.Called(ctx, emails)
+
+	var r0 map[string]bool
+	if rf, ok := ret.Get(0).(func(context.Context, []string) map[string]bool); ok {
+		r0 = rf(ctx, emails)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]bool)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, emails)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Export provides a mock function with given fields: ctx, criteria, fn
+func (_m *Repository) Export(ctx context.Context, criteria *user.UserCriteria, fn func(*model.UserModel) error) error {
+	ret := _m.Called(ctx, criteria, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *user.UserCriteria, func(*model.UserModel) error) error); ok {
+		r0 = rf(ctx, criteria, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Get provides a mock function with given fields: ctx, criteria
+func (_m *Repository) Get(ctx context.Context, criteria *user.UserCriteria) ([]*model.UserModel, error) {
+	ret := _m.Called(ctx, criteria)
 
 	var r0 []*model.UserModel
-	if rf, ok := ret.Get(0).(func(map[string]interface{}, string, string, string) []*model.UserModel); ok {
-		r0 = rf(filter, where, orderBy, selectField)
+	if rf, ok := ret.Get(0).(func(context.Context, *user.UserCriteria) []*model.UserModel); ok {
+		r0 = rf(ctx, criteria)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*model.UserModel)
@@ -82,8 +121,31 @@ func (_m *Repository) Get(filter map[string]interface{}, where string, orderBy s
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(map[string]interface{}, string, string, string) error); ok {
-		r1 = rf(filter, where, orderBy, selectField)
+	if rf, ok := ret.Get(1).(func(context.Context, *user.UserCriteria) error); ok {
+		r1 = rf(ctx, criteria)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetByID provides a mock function with given fields: ctx, id, selectField
+func (_m *Repository) GetByID(ctx context.Context, id string, selectField string) (*model.UserModel, error) {
+	ret := _m.Called(ctx, id, selectField)
+
+	var r0 *model.UserModel
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.UserModel); ok {
+		r0 = rf(ctx, id, selectField)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UserModel)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, id, selectField)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -91,13 +153,36 @@ func (_m *Repository) Get(filter map[string]interface{}, where string, orderBy s
 	return r0, r1
 }
 
-// GetByID provides a mock function with given fields: id, selectField
-func (_m *Repository) GetByID(id string, selectField string) (*model.UserModel, error) {
-	ret := _m.Called(id, selectField)
+// Import provides a mock function with given fields: ctx, users, upsert
+func (_m *Repository) Import(ctx context.Context, users []*model.UserModel, upsert bool) (map[string]bool, error) {
+	ret := _m.Called(ctx, users, upsert)
+
+	var r0 map[string]bool
+	if rf, ok := ret.Get(0).(func(context.Context, []*model.UserModel, bool) map[string]bool); ok {
+		r0 = rf(ctx, users, upsert)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]bool)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []*model.UserModel, bool) error); ok {
+		r1 = rf(ctx, users, upsert)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Patch provides a mock function with given fields: ctx, userReq, fields
+func (_m *Repository) Patch(ctx context.Context, userReq *model.UserModel, fields []string) (*model.UserModel, error) {
+	ret := _m.Called(ctx, userReq, fields)
 
 	var r0 *model.UserModel
-	if rf, ok := ret.Get(0).(func(string, string) *model.UserModel); ok {
-		r0 = rf(id, selectField)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.UserModel, []string) *model.UserModel); ok {
+		r0 = rf(ctx, userReq, fields)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*model.UserModel)
@@ -105,8 +190,31 @@ func (_m *Repository) GetByID(id string, selectField string) (*model.UserModel,
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(id, selectField)
+	if rf, ok := ret.Get(1).(func(context.Context, *model.UserModel, []string) error); ok {
+		r1 = rf(ctx, userReq, fields)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Search provides a mock function with given fields: ctx, criteria
+func (_m *Repository) Search(ctx context.Context, criteria *user.UserCriteria) ([]*model.UserSearchModel, error) {
+	ret := _m.Called(ctx, criteria)
+
+	var r0 []*model.UserSearchModel
+	if rf, ok := ret.Get(0).(func(context.Context, *user.UserCriteria) []*model.UserSearchModel); ok {
+		r0 = rf(ctx, criteria)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.UserSearchModel)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *user.UserCriteria) error); ok {
+		r1 = rf(ctx, criteria)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -114,13 +222,13 @@ func (_m *Repository) GetByID(id string, selectField string) (*model.UserModel,
 	return r0, r1
 }
 
-// Update provides a mock function with given fields: userReq
-func (_m *Repository) Update(userReq *model.UserModel) (*model.UserModel, error) {
-	ret := _m.Called(userReq)
+// Update provides a mock function with given fields: ctx, userReq
+func (_m *Repository) Update(ctx context.Context, userReq *model.UserModel) (*model.UserModel, error) {
+	ret := _m.Called(ctx, userReq)
 
 	var r0 *model.UserModel
-	if rf, ok := ret.Get(0).(func(*model.UserModel) *model.UserModel); ok {
-		r0 = rf(userReq)
+	if rf, ok := ret.Get(0).(func(context.Context, *model.UserModel) *model.UserModel); ok {
+		r0 = rf(ctx, userReq)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*model.UserModel)
@@ -128,11 +236,25 @@ func (_m *Repository) Update(userReq *model.UserModel) (*model.UserModel, error)
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(*model.UserModel) error); ok {
-		r1 = rf(userReq)
+	if rf, ok := ret.Get(1).(func(context.Context, *model.UserModel) error); ok {
+		r1 = rf(ctx, userReq)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
+
+// WithTx provides a mock function with given fields: ctx, fn
+func (_m *Repository) WithTx(ctx context.Context, fn func(user.Repository) error) error {
+	ret := _m.Called(ctx, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(user.Repository) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
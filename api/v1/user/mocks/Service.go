@@ -2,59 +2,105 @@
 
 package mocks
 
+import context "context"
 import form "github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
 import mock "github.com/stretchr/testify/mock"
 import model "github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+import user "github.com/moemoe89/go-graphql-gendhis/api/v1/user"
 
 // Service is an autogenerated mock type for the Service type
 type Service struct {
 	mock.Mock
 }
 
-// Create provides a mock function with given fields: req
-func (_m *Service) Create(req *form.UserForm) (*model.UserModel, int, error) {
-	ret := _m.Called(req)
+// Atomic provides a mock function with given fields: ctx, fn
+func (_m *Service) Atomic(ctx context.Context, fn func(user.Service) error) error {
+	ret := _m.Called(ctx, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(user.Service) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Count provides a mock function with given fields: ctx, criteria
+func (_m *Service) Count(ctx context.Context, criteria *user.UserCriteria) (int, error) {
+	ret := _m.Called(ctx, criteria)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context, *user.UserCriteria) int); ok {
+		r0 = rf(ctx, criteria)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *user.UserCriteria) error); ok {
+		r1 = rf(ctx, criteria)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Create provides a mock function with given fields: ctx, req
+func (_m *Service) Create(ctx context.Context, req *form.UserForm) (*model.UserModel, error) {
+	ret := _m.Called(ctx, req)
 
 	var r0 *model.UserModel
-	if rf, ok := ret.Get(0).(func(*form.UserForm) *model.UserModel); ok {
-		r0 = rf(req)
+	if rf, ok := ret.Get(0).(func(context.Context, *form.UserForm) *model.UserModel); ok {
+		r0 = rf(ctx, req)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*model.UserModel)
 		}
 	}
 
-	var r1 int
-	if rf, ok := ret.Get(1).(func(*form.UserForm) int); ok {
-		r1 = rf(req)
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *form.UserForm) error); ok {
+		r1 = rf(ctx, req)
 	} else {
-		r1 = ret.Get(1).(int)
+		r1 = ret.Error(1)
 	}
 
-	var r2 error
-	if rf, ok := ret.Get(2).(func(*form.UserForm) error); ok {
-		r2 = rf(req)
+	return r0, r1
+}
+
+// Delete provides a mock function with given fields: ctx, id
+func (_m *Service) Delete(ctx context.Context, id string) error {
+	ret := _m.Called(ctx, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, id)
 	} else {
-		r2 = ret.Error(2)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1, r2
+	return r0
 }
 
-// Delete provides a mock function with given fields: id
-func (_m *Service) Delete(id string) (int, error) {
-	ret := _m.Called(id)
+// Detail provides a mock function with given fields: ctx, id, selectField
+func (_m *Service) Detail(ctx context.Context, id string, selectField string) (*model.UserModel, error) {
+	ret := _m.Called(ctx, id, selectField)
 
-	var r0 int
-	if rf, ok := ret.Get(0).(func(string) int); ok {
-		r0 = rf(id)
+	var r0 *model.UserModel
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *model.UserModel); ok {
+		r0 = rf(ctx, id, selectField)
 	} else {
-		r0 = ret.Get(0).(int)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UserModel)
+		}
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, id, selectField)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -62,29 +108,66 @@ func (_m *Service) Delete(id string) (int, error) {
 	return r0, r1
 }
 
-// Detail provides a mock function with given fields: id, selectField
-func (_m *Service) Detail(id string, selectField string) (*model.UserModel, int, error) {
-	ret := _m.Called(id, selectField)
+// Export provides a mock function with given fields: ctx, criteria, fn
+func (_m *Service) Export(ctx context.Context, criteria *user.UserCriteria, fn func(*model.UserModel) error) error {
+	ret := _m.Called(ctx, criteria, fn)
 
-	var r0 *model.UserModel
-	if rf, ok := ret.Get(0).(func(string, string) *model.UserModel); ok {
-		r0 = rf(id, selectField)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *user.UserCriteria, func(*model.UserModel) error) error); ok {
+		r0 = rf(ctx, criteria, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Import provides a mock function with given fields: ctx, reqs, upsert, dryRun
+func (_m *Service) Import(ctx context.Context, reqs []*form.UserForm, upsert bool, dryRun bool) (*user.ImportResult, error) {
+	ret := _m.Called(ctx, reqs, upsert, dryRun)
+
+	var r0 *user.ImportResult
+	if rf, ok := ret.Get(0).(func(context.Context, []*form.UserForm, bool, bool) *user.ImportResult); ok {
+		r0 = rf(ctx, reqs, upsert, dryRun)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.UserModel)
+			r0 = ret.Get(0).(*user.ImportResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []*form.UserForm, bool, bool) error); ok {
+		r1 = rf(ctx, reqs, upsert, dryRun)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields: ctx, criteria
+func (_m *Service) List(ctx context.Context, criteria *user.UserCriteria) ([]*model.UserModel, int, error) {
+	ret := _m.Called(ctx, criteria)
+
+	var r0 []*model.UserModel
+	if rf, ok := ret.Get(0).(func(context.Context, *user.UserCriteria) []*model.UserModel); ok {
+		r0 = rf(ctx, criteria)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.UserModel)
 		}
 	}
 
 	var r1 int
-	if rf, ok := ret.Get(1).(func(string, string) int); ok {
-		r1 = rf(id, selectField)
+	if rf, ok := ret.Get(1).(func(context.Context, *user.UserCriteria) int); ok {
+		r1 = rf(ctx, criteria)
 	} else {
 		r1 = ret.Get(1).(int)
 	}
 
 	var r2 error
-	if rf, ok := ret.Get(2).(func(string, string) error); ok {
-		r2 = rf(id, selectField)
+	if rf, ok := ret.Get(2).(func(context.Context, *user.UserCriteria) error); ok {
+		r2 = rf(ctx, criteria)
 	} else {
 		r2 = ret.Error(2)
 	}
@@ -92,69 +175,135 @@ func (_m *Service) Detail(id string, selectField string) (*model.UserModel, int,
 	return r0, r1, r2
 }
 
-// List provides a mock function with given fields: filter, filterCount, where, orderBy, selectField
-func (_m *Service) List(filter map[string]interface{}, filterCount map[string]interface{}, where string, orderBy string, selectField string) ([]*model.UserModel, int, int, error) {
-	ret := _m.Called(filter, filterCount, where, orderBy, selectField)
+// ListWithoutCount provides a mock function with given fields: ctx, criteria
+func (_m *Service) ListWithoutCount(ctx context.Context, criteria *user.UserCriteria) ([]*model.UserModel, error) {
+	ret := _m.Called(ctx, criteria)
 
 	var r0 []*model.UserModel
-	if rf, ok := ret.Get(0).(func(map[string]interface{}, map[string]interface{}, string, string, string) []*model.UserModel); ok {
-		r0 = rf(filter, filterCount, where, orderBy, selectField)
+	if rf, ok := ret.Get(0).(func(context.Context, *user.UserCriteria) []*model.UserModel); ok {
+		r0 = rf(ctx, criteria)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*model.UserModel)
 		}
 	}
 
-	var r1 int
-	if rf, ok := ret.Get(1).(func(map[string]interface{}, map[string]interface{}, string, string, string) int); ok {
-		r1 = rf(filter, filterCount, where, orderBy, selectField)
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *user.UserCriteria) error); ok {
+		r1 = rf(ctx, criteria)
 	} else {
-		r1 = ret.Get(1).(int)
+		r1 = ret.Error(1)
 	}
 
-	var r2 int
-	if rf, ok := ret.Get(2).(func(map[string]interface{}, map[string]interface{}, string, string, string) int); ok {
-		r2 = rf(filter, filterCount, where, orderBy, selectField)
+	return r0, r1
+}
+
+// Patch provides a mock function with given fields: ctx, req, current
+func (_m *Service) Patch(ctx context.Context, req *form.UserForm, current *model.UserModel) (*model.UserModel, error) {
+	ret := _m.Called(ctx, req, current)
+
+	var r0 *model.UserModel
+	if rf, ok := ret.Get(0).(func(context.Context, *form.UserForm, *model.UserModel) *model.UserModel); ok {
+		r0 = rf(ctx, req, current)
 	} else {
-		r2 = ret.Get(2).(int)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UserModel)
+		}
 	}
 
-	var r3 error
-	if rf, ok := ret.Get(3).(func(map[string]interface{}, map[string]interface{}, string, string, string) error); ok {
-		r3 = rf(filter, filterCount, where, orderBy, selectField)
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *form.UserForm, *model.UserModel) error); ok {
+		r1 = rf(ctx, req, current)
 	} else {
-		r3 = ret.Error(3)
+		r1 = ret.Error(1)
 	}
 
-	return r0, r1, r2, r3
+	return r0, r1
 }
 
-// Update provides a mock function with given fields: req, id
-func (_m *Service) Update(req *form.UserForm, id string) (*model.UserModel, int, error) {
-	ret := _m.Called(req, id)
+// Search provides a mock function with given fields: ctx, criteria
+func (_m *Service) Search(ctx context.Context, criteria *user.UserCriteria) ([]*model.UserSearchModel, int, error) {
+	ret := _m.Called(ctx, criteria)
 
-	var r0 *model.UserModel
-	if rf, ok := ret.Get(0).(func(*form.UserForm, string) *model.UserModel); ok {
-		r0 = rf(req, id)
+	var r0 []*model.UserSearchModel
+	if rf, ok := ret.Get(0).(func(context.Context, *user.UserCriteria) []*model.UserSearchModel); ok {
+		r0 = rf(ctx, criteria)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*model.UserModel)
+			r0 = ret.Get(0).([]*model.UserSearchModel)
 		}
 	}
 
 	var r1 int
-	if rf, ok := ret.Get(1).(func(*form.UserForm, string) int); ok {
-		r1 = rf(req, id)
+	if rf, ok := ret.Get(1).(func(context.Context, *user.UserCriteria) int); ok {
+		r1 = rf(ctx, criteria)
 	} else {
 		r1 = ret.Get(1).(int)
 	}
 
 	var r2 error
-	if rf, ok := ret.Get(2).(func(*form.UserForm, string) error); ok {
-		r2 = rf(req, id)
+	if rf, ok := ret.Get(2).(func(context.Context, *user.UserCriteria) error); ok {
+		r2 = rf(ctx, criteria)
 	} else {
 		r2 = ret.Error(2)
 	}
 
 	return r0, r1, r2
 }
+
+// Subscribe provides a mock function with given fields: lastEventID
+func (_m *Service) Subscribe(lastEventID uint64) ([]*model.UserEvent, <-chan *model.UserEvent, func()) {
+	ret := _m.Called(lastEventID)
+
+	var r0 []*model.UserEvent
+	if rf, ok := ret.Get(0).(func(uint64) []*model.UserEvent); ok {
+		r0 = rf(lastEventID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*model.UserEvent)
+		}
+	}
+
+	var r1 <-chan *model.UserEvent
+	if rf, ok := ret.Get(1).(func(uint64) <-chan *model.UserEvent); ok {
+		r1 = rf(lastEventID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(<-chan *model.UserEvent)
+		}
+	}
+
+	var r2 func()
+	if rf, ok := ret.Get(2).(func(uint64) func()); ok {
+		r2 = rf(lastEventID)
+	} else {
+		if ret.Get(2) != nil {
+			r2 = ret.Get(2).(func())
+		}
+	}
+
+	return r0, r1, r2
+}
+
+// Update provides a mock function with given fields: ctx, req, id
+func (_m *Service) Update(ctx context.Context, req *form.UserForm, id string) (*model.UserModel, error) {
+	ret := _m.Called(ctx, req, id)
+
+	var r0 *model.UserModel
+	if rf, ok := ret.Get(0).(func(context.Context, *form.UserForm, string) *model.UserModel); ok {
+		r0 = rf(ctx, req, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*model.UserModel)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *form.UserForm, string) error); ok {
+		r1 = rf(ctx, req, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
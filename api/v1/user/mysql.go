@@ -0,0 +1,107 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/replica"
+
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// mysqlSearchMatch represent the full-text search condition and rank of users on MySQL, bound with :q
+	mysqlSearchMatch = "MATCH (name, email, address) AGAINST (:q IN NATURAL LANGUAGE MODE)"
+	// mysqlOutboxPayload represent the JSON of stored user row written as outbox payload on MySQL, the timestamps
+	// are formatted like PostgreSQL does
+	mysqlOutboxPayload = "JSON_OBJECT('id', id, 'name', name, 'email', email, 'phone', phone, 'phone_raw', phone_raw, 'address', address, 'created_at', DATE_FORMAT(created_at, '%Y-%m-%dT%H:%i:%s'), 'updated_at', DATE_FORMAT(updated_at, '%Y-%m-%dT%H:%i:%s'))"
+
+	// duplicateEntry represent the mysql error number of violated unique key
+	duplicateEntry = 1062
+)
+
+// mysqlDialect represent the SQL of repository on MySQL. MySQL has no snippet of full-text match, so the highlight
+// is the searched text as is. LIKE is case-insensitive by the default collation, the binary collation keeps it
+// case-sensitive like PostgreSQL. MySQL has no RETURNING and doesn't tell the replayed position of replica
+var mysqlDialect = dialect{
+	searchCondition: mysqlSearchMatch,
+	searchRank:      mysqlSearchMatch,
+	searchHighlight: "concat_ws(' ', name, email, address)",
+	rank:            "`rank`",
+	like:            "%s COLLATE utf8mb4_bin LIKE %s",
+	importUpdate:    mysqlImportUpdate,
+	outbox:          inOutbox(mysqlOutboxPayload),
+	storeError:      mysqlStoreError,
+}
+
+// NewMySQLRepository will create an object that represent the Repository interface
+func NewMySQLRepository(DBRead *sqlx.DB, DBWrite *sqlx.DB, timeouts Timeouts) Repository {
+	return &sqlRepository{DBRead: DBRead, DBWrite: DBWrite, timeouts: timeouts, dialect: mysqlDialect}
+}
+
+// NewMySQLReplicaRepository will create an object that represent the Repository interface reading from
+// the replicas
+func NewMySQLReplicaRepository(replicas *replica.Set, DBWrite *sqlx.DB, timeouts Timeouts) Repository {
+	return &sqlRepository{DBRead: DBWrite, DBWrite: DBWrite, timeouts: timeouts, replicas: replicas, dialect: mysqlDialect}
+}
+
+// mysqlImportUpdate will update the existing users matching the email of batch and return their ids by
+// lowercased email. MySQL has no UPDATE ... FROM with RETURNING, so the matching users are locked and read
+// first and then updated by id
+func mysqlImportUpdate(ctx context.Context, tx dbExecutor, users []*model.UserModel) (map[string]string, error) {
+	emails := []string{}
+	for _, user := range users {
+		emails = append(emails, strings.ToLower(user.Email))
+	}
+
+	query, args, err := sqlx.In(`SELECT id, lower(email) AS email FROM users WHERE lower(email) IN (?) AND deleted_at IS NULL FOR UPDATE`, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []struct {
+		ID    string `db:"id"`
+		Email string `db:"email"`
+	}{}
+	if err := tx.SelectContext(ctx, &rows, tx.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	updated := map[string]string{}
+	for _, row := range rows {
+		updated[row.Email] = row.ID
+	}
+
+	update := tx.Rebind(`UPDATE users SET name = ?, phone = ?, phone_raw = ?, address = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`)
+	for _, user := range users {
+		id, ok := updated[strings.ToLower(user.Email)]
+		if !ok {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, update, user.Name, user.Phone, user.PhoneRaw, user.Address, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return updated, nil
+}
+
+// mysqlStoreError will translate the mysql error into domain error, so the service doesn't depend on the driver
+func mysqlStoreError(err error) error {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) && myErr.Number == duplicateEntry {
+		// MySQL names the violated key only in the message, like `Duplicate entry 'x' for key 'users.idx_users_lower_email'`
+		return conflictError(err, strings.Contains(myErr.Message, "'"+emailIndex+"'") || strings.Contains(myErr.Message, "."+emailIndex+"'"))
+	}
+
+	return err
+}
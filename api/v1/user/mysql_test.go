@@ -0,0 +1,218 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	// mysqlOutboxQuery represent the expected insert of user events into outbox on MySQL
+	mysqlOutboxQuery = "INSERT INTO outbox \\(aggregate_type, aggregate_id, event_type, payload\\) SELECT \\?, id, \\?, JSON_OBJECT\\(.+\\) FROM users WHERE id IN \\(.+\\)"
+	// mysqlStoredQuery represent the expected read back of written user on MySQL
+	mysqlStoredQuery = "SELECT " + model.UserSelectField + " FROM users WHERE id = \\? AND deleted_at IS NULL"
+)
+
+func TestMySQLGet(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "mysql")
+
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "phone", "address", "created_at", "updated_at"}).
+		AddRow(xid.New().String(), "Momo", "momo@mail.com", "+6285640123456", "Indonesia", time.Now().UTC(), time.Now().UTC())
+
+	query := "SELECT " + model.UserSelectField + " FROM users WHERE deleted_at IS NULL AND \\(name COLLATE utf8mb4_bin LIKE \\?\\) ORDER BY created_at DESC LIMIT \\? OFFSET \\?"
+
+	mock.ExpectQuery(query).WithArgs("%Mo%", 10, 0).WillReturnRows(rows)
+	u := user.NewMySQLRepository(sqlxDB, sqlxDB, nil)
+
+	criteria := (&user.UserCriteria{Limit: 10}).Where("name", user.OpContains, "Mo")
+
+	users, err := u.Get(context.Background(), criteria)
+
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLSearch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "mysql")
+
+	rows := sqlmock.NewRows([]string{"id", "name", "rank"}).AddRow(xid.New().String(), "Momo", 0.6)
+
+	query := "SELECT id,name, MATCH \\(name, email, address\\) AGAINST \\(\\? IN NATURAL LANGUAGE MODE\\) AS `rank` FROM users WHERE deleted_at IS NULL AND MATCH \\(name, email, address\\) AGAINST \\(\\? IN NATURAL LANGUAGE MODE\\) ORDER BY MATCH \\(name, email, address\\) AGAINST \\(\\? IN NATURAL LANGUAGE MODE\\) DESC, created_at DESC"
+
+	mock.ExpectQuery(query).WithArgs("momo", "momo", "momo").WillReturnRows(rows)
+	u := user.NewMySQLRepository(sqlxDB, sqlxDB, nil)
+
+	users, err := u.Search(context.Background(), &user.UserCriteria{Query: "momo", Fields: []string{"id", "name"}})
+
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, 0.6, users[0].Rank)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "mysql")
+
+	req := &model.UserModel{
+		ID:       xid.New().String(),
+		Name:     "Momo",
+		Email:    "momo@mail.com",
+		Phone:    "+6285640123456",
+		PhoneRaw: "085640123456",
+		Address:  "Indonesia",
+	}
+
+	query := "INSERT INTO users \\(id, name, email, phone, phone_raw, address, created_at, updated_at\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP\\)$"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WithArgs(req.ID, req.Name, req.Email, req.Phone, req.PhoneRaw, req.Address).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(mysqlStoredQuery).WithArgs(req.ID).WillReturnRows(storedRows(req))
+	mock.ExpectExec(mysqlOutboxQuery).WithArgs(user.OutboxAggregate, user.EventUserCreated, req.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	u := user.NewMySQLRepository(sqlxDB, sqlxDB, nil)
+	userRow, err := u.Create(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, req.ID, userRow.ID)
+	assert.False(t, userRow.CreatedAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLCreateFailEmailTaken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "mysql")
+
+	req := &model.UserModel{ID: xid.New().String(), Name: "Momo", Email: "Momo@mail.com"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users").WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'momo@mail.com' for key 'users.idx_users_lower_email'"})
+	mock.ExpectRollback()
+
+	u := user.NewMySQLRepository(sqlxDB, sqlxDB, nil)
+	_, err = u.Create(context.Background(), req)
+
+	assert.True(t, errors.Is(err, user.ErrConflict))
+	assert.True(t, errors.Is(err, user.ErrEmailTaken))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLUpdateFailNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "mysql")
+
+	req := &model.UserModel{ID: xid.New().String(), Name: "Momo", Email: "momo@mail.com"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET .+ WHERE id = \\? AND deleted_at IS NULL").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(mysqlStoredQuery).WithArgs(req.ID).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	u := user.NewMySQLRepository(sqlxDB, sqlxDB, nil)
+	_, err = u.Update(context.Background(), req)
+
+	assert.Equal(t, sql.ErrNoRows, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLImportUpsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "mysql")
+
+	users := []*model.UserModel{
+		{ID: xid.New().String(), Name: "Momo", Email: "Momo@mail.com", Phone: "+6285640123456", PhoneRaw: "085640123456", Address: "Indonesia"},
+		{ID: xid.New().String(), Name: "Gendhis", Email: "gendhis@mail.com", Phone: "+6285641123456", PhoneRaw: "085641123456", Address: "Indonesia"},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id, lower\\(email\\) AS email FROM users WHERE lower\\(email\\) IN \\(\\?, \\?\\) AND deleted_at IS NULL FOR UPDATE").
+		WithArgs("momo@mail.com", "gendhis@mail.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow("bpielbbipt341rif5i20", "momo@mail.com"))
+	mock.ExpectExec("UPDATE users SET name = \\?, phone = \\?, phone_raw = \\?, address = \\?, updated_at = CURRENT_TIMESTAMP WHERE id = \\?").
+		WithArgs(users[0].Name, users[0].Phone, users[0].PhoneRaw, users[0].Address, "bpielbbipt341rif5i20").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(mysqlOutboxQuery).
+		WithArgs(user.OutboxAggregate, user.EventUserUpdated, "bpielbbipt341rif5i20").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO users").
+		WithArgs(users[1].ID, users[1].Name, users[1].Email, users[1].Phone, users[1].PhoneRaw, users[1].Address).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(mysqlOutboxQuery).
+		WithArgs(user.OutboxAggregate, user.EventUserCreated, users[1].ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	u := user.NewMySQLRepository(sqlxDB, sqlxDB, nil)
+	updated, err := u.Import(context.Background(), users, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"momo@mail.com": true}, updated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "mysql")
+
+	id := xid.New().String()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET deleted_at = CURRENT_TIMESTAMP WHERE id = \\? AND deleted_at IS NULL").WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(mysqlOutboxQuery).WithArgs(user.OutboxAggregate, user.EventUserDeleted, id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	u := user.NewMySQLRepository(sqlxDB, sqlxDB, nil)
+	err = u.Delete(context.Background(), id)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -8,82 +8,846 @@ package user
 
 import (
 	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/consistency"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/replica"
 
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
+const (
+	// SearchCondition represent the full-text search condition of users, bound with :q
+	SearchCondition = "search_vector @@ plainto_tsquery('simple', :q)"
+	// SearchRank represent the full-text search rank of users, bound with :q
+	SearchRank = "ts_rank(search_vector, plainto_tsquery('simple', :q))"
+	// SearchHighlight represent the full-text search snippet of users, bound with :q
+	SearchHighlight = "ts_headline('simple', concat_ws(' ', name, email, address), plainto_tsquery('simple', :q))"
+
+	// ExportBatchSize represent the number of rows fetched from the export cursor at once
+	ExportBatchSize = 500
+	// ImportBatchSize represent the number of rows written by one statement of import
+	ImportBatchSize = 500
+	// ReplayCacheTTL represent how long the position replayed by replica is used before it's read again
+	ReplayCacheTTL = 100 * time.Millisecond
+
+	// OutboxAggregate represent the aggregate type of user events in outbox
+	OutboxAggregate = "user"
+	// outboxPayload represent the JSON of stored user row written as outbox payload, so it holds the
+	// values set by database
+	outboxPayload = "json_build_object('id', id, 'name', name, 'email', email, 'phone', phone, 'phone_raw', phone_raw, 'address', address, 'created_at', created_at AT TIME ZONE 'UTC', 'updated_at', updated_at AT TIME ZONE 'UTC')"
+
+	// TimeoutDefault represent the operation of timeout used by the operations without their own
+	TimeoutDefault = "default"
+
+	// uniqueViolation represent the postgres error code of violated unique constraint
+	uniqueViolation = "23505"
+	// emailIndex represent the case-insensitive unique index of email of users not deleted
+	emailIndex = "idx_users_lower_email"
+)
+
+// Timeouts represent the query timeouts of repository by operation, which are get, count, search, export,
+// create, import, get_by_id, update, patch and delete. Zero is no timeout
+type Timeouts map[string]time.Duration
+
+// NewTimeouts will create the timeouts from milliseconds by operation
+func NewTimeouts(ms map[string]int) Timeouts {
+	timeouts := Timeouts{}
+	for op, v := range ms {
+		timeouts[op] = time.Duration(v) * time.Millisecond
+	}
+	return timeouts
+}
+
+// withTimeout will derive the context of operation bounded by its timeout
+func (t Timeouts) withTimeout(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	d, ok := t[op]
+	if !ok {
+		d = t[TimeoutDefault]
+	}
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
 // Repository represent the repositories
 type Repository interface {
-	Get(filter map[string]interface{}, where, orderBy, selectField string) ([]*model.UserModel, error)
-	Count(filter map[string]interface{}, where string) (int, error)
-	Create(userReq *model.UserModel) (*model.UserModel, error)
-	GetByID(id, selectField string) (*model.UserModel, error)
-	Update(userReq *model.UserModel) (*model.UserModel, error)
-	Delete(id string) error
+	Get(ctx context.Context, criteria *UserCriteria) ([]*model.UserModel, error)
+	Count(ctx context.Context, criteria *UserCriteria) (int, error)
+	Search(ctx context.Context, criteria *UserCriteria) ([]*model.UserSearchModel, error)
+	Export(ctx context.Context, criteria *UserCriteria, fn func(*model.UserModel) error) error
+	Create(ctx context.Context, userReq *model.UserModel) (*model.UserModel, error)
+	Import(ctx context.Context, users []*model.UserModel, upsert bool) (map[string]bool, error)
+	ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error)
+	GetByID(ctx context.Context, id, selectField string) (*model.UserModel, error)
+	Update(ctx context.Context, userReq *model.UserModel) (*model.UserModel, error)
+	Patch(ctx context.Context, userReq *model.UserModel, fields []string) (*model.UserModel, error)
+	Delete(ctx context.Context, id string) error
+	WithTx(ctx context.Context, fn func(Repository) error) error
 }
 
-type postgresRepository struct {
-	DBRead  *sqlx.DB
-	DBWrite *sqlx.DB
+// dbExecutor represent the common methods of sqlx.DB and sqlx.Tx used by repository
+type dbExecutor interface {
+	sqlx.ExtContext
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// txExecutor represent the transaction started by repository
+type txExecutor interface {
+	dbExecutor
+	Commit() error
+	Rollback() error
+}
+
+// joinedTx represent the running transaction joined by repository, it is finished by its owner
+type joinedTx struct {
+	*sqlx.Tx
+}
+
+func (joinedTx) Commit() error   { return nil }
+func (joinedTx) Rollback() error { return nil }
+
+// beginx will begin the transaction on db bound to ctx, inside running transaction the transaction is joined instead
+func beginx(ctx context.Context, db dbExecutor) (txExecutor, error) {
+	switch tx := db.(type) {
+	case *sqlx.Tx:
+		return joinedTx{tx}, nil
+	case joinedTx:
+		return tx, nil
+	}
+
+	return db.(*sqlx.DB).BeginTxx(ctx, nil)
+}
+
+type sqlRepository struct {
+	DBRead   dbExecutor
+	DBWrite  dbExecutor
+	timeouts Timeouts
+	// replicas represent the balanced replicas serving the reads instead of DBRead when it's set
+	replicas *replica.Set
+	// replayed represent the cached positions replayed by the replicas, nil reads them every time
+	replayed *consistency.ReplayCache
+	dialect  dialect
 }
 
 // NewPostgresRepository will create an object that represent the Repository interface
-func NewPostgresRepository(DBRead *sqlx.DB, DBWrite *sqlx.DB) Repository {
-	return &postgresRepository{DBRead, DBWrite}
+func NewPostgresRepository(DBRead *sqlx.DB, DBWrite *sqlx.DB, timeouts Timeouts) Repository {
+	return &sqlRepository{DBRead: DBRead, DBWrite: DBWrite, timeouts: timeouts, replayed: consistency.NewReplayCache(ReplayCacheTTL), dialect: postgresDialect}
+}
+
+// NewPostgresReplicaRepository will create an object that represent the Repository interface reading from
+// the replicas
+func NewPostgresReplicaRepository(replicas *replica.Set, DBWrite *sqlx.DB, timeouts Timeouts) Repository {
+	return &sqlRepository{DBRead: DBWrite, DBWrite: DBWrite, timeouts: timeouts, replicas: replicas, replayed: consistency.NewReplayCache(ReplayCacheTTL), dialect: postgresDialect}
+}
+
+// dialect represent the SQL and behaviour of repository which differs by database
+type dialect struct {
+	searchCondition string
+	searchRank      string
+	searchHighlight string
+	// rank represent the alias of search rank, quoted where it's a reserved word
+	rank string
+	// like represent the format of case-sensitive LIKE condition taking the field and the pattern escaped with
+	// backslash, so the filters match the same rows on every database
+	like string
+	// timeArg represent the conversion of arguments bound to time columns, nil keeps them as is
+	timeArg func(arg interface{}) interface{}
+	// cursor represent whether the export is fetched in batches from a server-side cursor instead of streamed
+	cursor bool
+	// returning represent whether the write returns the stored row with RETURNING, otherwise it's read back
+	// inside the transaction
+	returning bool
+	// importUpdate represent the update of existing users matching the email of import batch, returning their
+	// ids by lowercased email
+	importUpdate func(ctx context.Context, tx dbExecutor, users []*model.UserModel) (map[string]string, error)
+	// outbox represent the insert of user events into the outbox taking the event type and the ids of users
+	outbox func(eventType string, ids []string) (string, []interface{}, error)
+	// replayPosition represent the read of position replayed by replica, MaxLSN on primary. Nil sends the reads
+	// of session which has written to primary
+	replayPosition func(ctx context.Context, db dbExecutor) (consistency.LSN, error)
+	// writePosition represent the read of current write position of primary, nil records the write as MaxLSN
+	writePosition func(ctx context.Context, db dbExecutor) (consistency.LSN, error)
+	// storeError represent the translation of driver error into domain error
+	storeError func(err error) error
+}
+
+// postgresDialect represent the SQL of repository on PostgreSQL
+var postgresDialect = dialect{
+	searchCondition: SearchCondition,
+	searchRank:      SearchRank,
+	searchHighlight: SearchHighlight,
+	rank:            "rank",
+	like:            "%s LIKE %s",
+	cursor:          true,
+	returning:       true,
+	importUpdate:    importUpdate,
+	outbox:          postgresOutbox,
+	replayPosition:  postgresReplayPosition,
+	writePosition:   postgresWritePosition,
+	storeError:      postgresStoreError,
+}
+
+// compiledCriteria represent the criteria compiled into the clauses of query and their named arguments,
+// timeArgs holds the names of arguments bound to time columns
+type compiledCriteria struct {
+	selectField string
+	where       string
+	orderBy     string
+	limit       string
+	args        map[string]interface{}
+	timeArgs    map[string]bool
+}
+
+// compileCriteria will compile the criteria into SQL of d, on count the cursor, ordering and page are left out
+func compileCriteria(c *UserCriteria, count bool, d dialect) (*compiledCriteria, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	q := &compiledCriteria{
+		selectField: strings.Join(c.Columns(), ","),
+		where:       "WHERE deleted_at IS NULL",
+		args:        map[string]interface{}{},
+		timeArgs:    map[string]bool{},
+	}
+
+	if len(c.Query) > 0 {
+		q.where += " AND " + d.searchCondition
+		q.args["q"] = c.Query
+	}
+
+	if c.Filter != nil {
+		cond, err := c.Filter.compileLike(q.args, q.timeArgs, d.like)
+		if err != nil {
+			return nil, err
+		}
+		q.where += " AND " + cond
+	}
+
+	if count {
+		return q, nil
+	}
+
+	backward := false
+	if c.Cursor != nil {
+		cond, err := c.Keyset.Condition(c.Cursor, q.args)
+		if err != nil {
+			return nil, err
+		}
+		q.where += " AND " + cond
+		for i, col := range c.Keyset.Columns {
+			if timeColumns[col] {
+				q.timeArgs[cursorArg(i)] = true
+			}
+		}
+		backward = c.Cursor.Backward
+	}
+
+	switch {
+	case c.Keyset != nil:
+		q.orderBy = c.Keyset.OrderBy(backward)
+	case len(c.Sort) > 0:
+		orders := []string{}
+		for _, sort := range c.Sort {
+			dir := "ASC"
+			if sort.Desc {
+				dir = "DESC"
+			}
+			orders = append(orders, sort.Field+" "+dir)
+		}
+		q.orderBy = strings.Join(orders, ", ")
+	case len(c.Query) > 0:
+		q.orderBy = d.searchRank + " DESC, created_at DESC"
+	default:
+		q.orderBy = "created_at DESC"
+	}
+
+	if c.Limit > 0 {
+		q.limit = " LIMIT :limit OFFSET :offset"
+		q.args["limit"] = c.Limit
+		q.args["offset"] = c.Offset
+	}
+
+	return q, nil
+}
+
+// compile will compile the criteria into SQL of the dialect, the arguments bound to time columns are converted
+// by its timeArg
+func (p *sqlRepository) compile(c *UserCriteria, count bool) (*compiledCriteria, error) {
+	q, err := compileCriteria(c, count, p.dialect)
+	if err != nil || p.dialect.timeArg == nil {
+		return q, err
+	}
+
+	for name := range q.timeArgs {
+		q.args[name] = p.dialect.timeArg(q.args[name])
+	}
+
+	return q, nil
 }
 
-func (p *postgresRepository) Get(filter map[string]interface{}, where, orderBy, selectField string) ([]*model.UserModel, error) {
+func (p *sqlRepository) Get(ctx context.Context, criteria *UserCriteria) ([]*model.UserModel, error) {
+	ctx, cancel := p.timeouts.withTimeout(ctx, "get")
+	defer cancel()
+
+	q, err := p.compile(criteria, false)
+	if err != nil {
+		return nil, err
+	}
+
 	users := []*model.UserModel{}
-	if len(selectField) == 0 {
-		selectField = model.UserSelectField
+	query := fmt.Sprintf("SELECT %s FROM users %s ORDER BY %s%s", q.selectField, q.where, q.orderBy, q.limit)
+	db := p.reader(ctx)
+	namedQuery, args, err := db.BindNamed(query, q.args)
+	if err != nil {
+		return nil, err
 	}
-	query := fmt.Sprintf("SELECT %s FROM users %s ORDER BY %s LIMIT :limit OFFSET :offset", selectField, where, orderBy)
-	namedQuery, args, _ := p.DBRead.BindNamed(query, filter)
-	err := p.DBRead.Select(&users, namedQuery, args...)
+	err = db.SelectContext(ctx, &users, namedQuery, args...)
 	return users, err
 }
 
-func (p *postgresRepository) Count(filter map[string]interface{}, where string) (int, error) {
+func (p *sqlRepository) Count(ctx context.Context, criteria *UserCriteria) (int, error) {
+	ctx, cancel := p.timeouts.withTimeout(ctx, "count")
+	defer cancel()
+
+	q, err := p.compile(criteria, true)
+	if err != nil {
+		return 0, err
+	}
+
 	var count int
-	query := fmt.Sprintf("SELECT COUNT(id) FROM users %s", where)
-	namedQuery, args, _ := p.DBRead.BindNamed(query, filter)
-	err := p.DBRead.Get(&count, namedQuery, args...)
+	query := fmt.Sprintf("SELECT COUNT(id) FROM users %s", q.where)
+	db := p.reader(ctx)
+	namedQuery, args, err := db.BindNamed(query, q.args)
+	if err != nil {
+		return 0, err
+	}
+	err = db.GetContext(ctx, &count, namedQuery, args...)
 	return count, err
 }
 
-func (p *postgresRepository) Create(user *model.UserModel) (*model.UserModel, error) {
-	_, err := p.DBWrite.NamedExec(`INSERT INTO users (id, name, email, phone, address, created_at, updated_at) VALUES (:id, :name, :email, :phone, :address, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`, user)
-	return user, err
+func (p *sqlRepository) Search(ctx context.Context, criteria *UserCriteria) ([]*model.UserSearchModel, error) {
+	ctx, cancel := p.timeouts.withTimeout(ctx, "search")
+	defer cancel()
+
+	q, err := p.compile(criteria, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// without query every user ranks the same and there's nothing to highlight
+	users := []*model.UserSearchModel{}
+	rank := p.dialect.searchRank
+	if len(criteria.Query) == 0 {
+		rank = "0"
+	}
+	selectField := q.selectField + ", " + rank + " AS " + p.dialect.rank
+	if criteria.Highlight && len(criteria.Query) > 0 {
+		selectField += ", " + p.dialect.searchHighlight + " AS highlight"
+	}
+	query := fmt.Sprintf("SELECT %s FROM users %s ORDER BY %s%s", selectField, q.where, q.orderBy, q.limit)
+	db := p.reader(ctx)
+	namedQuery, args, err := db.BindNamed(query, q.args)
+	if err != nil {
+		return nil, err
+	}
+	err = db.SelectContext(ctx, &users, namedQuery, args...)
+	return users, err
+}
+
+// Export will stream the users through fn, so the whole result is never held in memory. With cursor of dialect
+// the rows are fetched in batches from server-side cursor, otherwise the driver reads the rows from the
+// connection as they're scanned
+func (p *sqlRepository) Export(ctx context.Context, criteria *UserCriteria, fn func(*model.UserModel) error) error {
+	ctx, cancel := p.timeouts.withTimeout(ctx, "export")
+	defer cancel()
+
+	q, err := p.compile(criteria, false)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("SELECT %s FROM users %s ORDER BY %s%s", q.selectField, q.where, q.orderBy, q.limit)
+
+	db := p.reader(ctx)
+	namedQuery, args, err := db.BindNamed(query, q.args)
+	if err != nil {
+		return err
+	}
+	if !p.dialect.cursor {
+		_, err := exportRows(ctx, db, fn, namedQuery, args...)
+		return err
+	}
+
+	tx, err := beginx(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DECLARE users_export NO SCROLL CURSOR FOR "+namedQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	fetch := fmt.Sprintf("FETCH %d FROM users_export", ExportBatchSize)
+	for {
+		n, err := exportRows(ctx, tx, fn, fetch)
+		if err != nil {
+			return err
+		}
+		if n < ExportBatchSize {
+			break
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "CLOSE users_export")
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// exportRows will pass the users of query through fn and return the number of rows
+func exportRows(ctx context.Context, db dbExecutor, fn func(*model.UserModel) error, query string, args ...interface{}) (int, error) {
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		user := &model.UserModel{}
+		if err := rows.StructScan(user); err != nil {
+			return n, err
+		}
+		if err := fn(user); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, rows.Err()
+}
+
+func (p *sqlRepository) Create(ctx context.Context, user *model.UserModel) (*model.UserModel, error) {
+	return p.writeWithOutbox(ctx, "create", `INSERT INTO users (id, name, email, phone, phone_raw, address, created_at, updated_at) VALUES (:id, :name, :email, :phone, :phone_raw, :address, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`, user, EventUserCreated)
 }
 
-func (p *postgresRepository) GetByID(id, selectField string) (*model.UserModel, error) {
-	where := "WHERE deleted_at IS NULL"
-	filter := map[string]interface{}{}
+// Import will insert the users with multi-row inserts inside one transaction, on upsert the users
+// matching an existing email are updated instead and their lowercased emails are returned
+func (p *sqlRepository) Import(ctx context.Context, users []*model.UserModel, upsert bool) (map[string]bool, error) {
+	ctx, cancel := p.timeouts.withTimeout(ctx, "import")
+	defer cancel()
+
+	updated := map[string]bool{}
+
+	tx, err := beginx(ctx, p.DBWrite)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(users); start += ImportBatchSize {
+		end := start + ImportBatchSize
+		if end > len(users) {
+			end = len(users)
+		}
+		batch := users[start:end]
+
+		if upsert {
+			emails, err := p.dialect.importUpdate(ctx, tx, batch)
+			if err != nil {
+				return nil, err
+			}
+
+			updatedIDs := []string{}
+			for _, id := range emails {
+				updatedIDs = append(updatedIDs, id)
+			}
+			if err := p.writeOutbox(ctx, tx, EventUserUpdated, updatedIDs...); err != nil {
+				return nil, err
+			}
+
+			inserts := []*model.UserModel{}
+			for _, user := range batch {
+				if _, ok := emails[strings.ToLower(user.Email)]; ok {
+					updated[strings.ToLower(user.Email)] = true
+					continue
+				}
+				inserts = append(inserts, user)
+			}
+			batch = inserts
+		}
+
+		if err := importInsert(ctx, tx, batch); err != nil {
+			return nil, p.dialect.storeError(err)
+		}
+
+		insertedIDs := []string{}
+		for _, user := range batch {
+			insertedIDs = append(insertedIDs, user.ID)
+		}
+		if err := p.writeOutbox(ctx, tx, EventUserCreated, insertedIDs...); err != nil {
+			return nil, err
+		}
+	}
+
+	return updated, p.commit(ctx, tx)
+}
+
+// ExistingEmails will return the lowercased emails of the users not deleted among emails, compared
+// case-insensitively like the unique index. They're read from DBWrite, so the users just written are found
+func (p *sqlRepository) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	ctx, cancel := p.timeouts.withTimeout(ctx, "import")
+	defer cancel()
+
+	existing := map[string]bool{}
+	for start := 0; start < len(emails); start += ImportBatchSize {
+		end := start + ImportBatchSize
+		if end > len(emails) {
+			end = len(emails)
+		}
 
-	where += " AND id = :id"
-	filter["id"] = id
+		lowered := []string{}
+		for _, email := range emails[start:end] {
+			lowered = append(lowered, strings.ToLower(email))
+		}
+
+		query, args, err := sqlx.In(`SELECT lower(email) FROM users WHERE lower(email) IN (?) AND deleted_at IS NULL`, lowered)
+		if err != nil {
+			return nil, err
+		}
+
+		found := []string{}
+		if err := p.DBWrite.SelectContext(ctx, &found, p.DBWrite.Rebind(query), args...); err != nil {
+			return nil, err
+		}
+		for _, email := range found {
+			existing[email] = true
+		}
+	}
+
+	return existing, nil
+}
+
+// importUpdate will update the existing users matching the email of batch and return their ids by
+// lowercased email
+func importUpdate(ctx context.Context, tx dbExecutor, users []*model.UserModel) (map[string]string, error) {
+	values := []string{}
+	args := []interface{}{}
+	for _, user := range users {
+		values = append(values, "(?, ?, ?, ?, ?)")
+		args = append(args, user.Email, user.Name, user.Phone, user.PhoneRaw, user.Address)
+	}
+
+	query := fmt.Sprintf(`UPDATE users AS u SET name = v.name, phone = v.phone, phone_raw = v.phone_raw, address = v.address, updated_at = CURRENT_TIMESTAMP FROM (VALUES %s) AS v (email, name, phone, phone_raw, address) WHERE lower(u.email) = lower(v.email) AND u.deleted_at IS NULL RETURNING u.id, lower(u.email) AS email`, strings.Join(values, ", "))
+
+	return selectUpdated(ctx, tx, query, args...)
+}
+
+// selectUpdated will run the update returning the id and lowercased email of users, and return the ids by
+// lowercased email
+func selectUpdated(ctx context.Context, tx dbExecutor, query string, args ...interface{}) (map[string]string, error) {
+	rows := []struct {
+		ID    string `db:"id"`
+		Email string `db:"email"`
+	}{}
+	if err := tx.SelectContext(ctx, &rows, tx.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	updated := map[string]string{}
+	for _, row := range rows {
+		updated[row.Email] = row.ID
+	}
+
+	return updated, nil
+}
+
+// importInsert will insert the batch of users with one statement
+func importInsert(ctx context.Context, tx dbExecutor, users []*model.UserModel) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	values := []string{}
+	args := []interface{}{}
+	for _, user := range users {
+		values = append(values, "(?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)")
+		args = append(args, user.ID, user.Name, user.Email, user.Phone, user.PhoneRaw, user.Address)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO users (id, name, email, phone, phone_raw, address, created_at, updated_at) VALUES %s`, strings.Join(values, ", "))
+	_, err := tx.ExecContext(ctx, tx.Rebind(query), args...)
+	return err
+}
+
+func (p *sqlRepository) GetByID(ctx context.Context, id, selectField string) (*model.UserModel, error) {
+	ctx, cancel := p.timeouts.withTimeout(ctx, "get_by_id")
+	defer cancel()
 
 	user := &model.UserModel{}
 	if len(selectField) == 0 {
 		selectField = model.UserSelectField
 	}
-	query := fmt.Sprintf("SELECT %s FROM users %s", selectField, where)
-	namedQuery, args, _ := p.DBRead.BindNamed(query, filter)
-	err := p.DBRead.Get(user, namedQuery, args...)
+	query := fmt.Sprintf("SELECT %s FROM users WHERE deleted_at IS NULL AND id = ?", selectField)
+	db := p.reader(ctx)
+	err := db.GetContext(ctx, user, db.Rebind(query), id)
 	return user, err
 }
 
-func (p *postgresRepository) Update(user *model.UserModel) (*model.UserModel, error) {
-	_, err := p.DBWrite.NamedExec(`UPDATE users SET name = :name, email = :email, phone = :phone, address = :address, updated_at = CURRENT_TIMESTAMP WHERE id = :id`, user)
-	return user, err
+func (p *sqlRepository) Update(ctx context.Context, user *model.UserModel) (*model.UserModel, error) {
+	return p.writeWithOutbox(ctx, "update", `UPDATE users SET name = :name, email = :email, phone = :phone, phone_raw = :phone_raw, address = :address, updated_at = CURRENT_TIMESTAMP WHERE id = :id AND deleted_at IS NULL`, user, EventUserUpdated)
+}
+
+func (p *sqlRepository) Patch(ctx context.Context, user *model.UserModel, fields []string) (*model.UserModel, error) {
+	sets := []string{}
+	for _, field := range fields {
+		sets = append(sets, fmt.Sprintf("%s = :%s", field, field))
+	}
+	sets = append(sets, "updated_at = CURRENT_TIMESTAMP")
+
+	query := fmt.Sprintf("UPDATE users SET %s WHERE id = :id AND deleted_at IS NULL", strings.Join(sets, ", "))
+	return p.writeWithOutbox(ctx, "patch", query, user, EventUserUpdated)
+}
+
+// Delete will soft delete the user, sql.ErrNoRows is returned when the user is missing or already deleted
+func (p *sqlRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := p.timeouts.withTimeout(ctx, "delete")
+	defer cancel()
+
+	tx, err := beginx(ctx, p.DBWrite)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, tx.Rebind(`UPDATE users SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL`), id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := p.writeOutbox(ctx, tx, EventUserDeleted, id); err != nil {
+		return err
+	}
+
+	return p.commit(ctx, tx)
+}
+
+// WithTx will run fn with the repository bound to one transaction of DBWrite, the transaction
+// is committed when fn succeeds and rolled back otherwise. Every operation of fn keeps its own timeout
+func (p *sqlRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	tx, err := beginx(ctx, p.DBWrite)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&sqlRepository{DBRead: tx, DBWrite: tx, timeouts: p.timeouts, dialect: p.dialect}); err != nil {
+		return err
+	}
+
+	return p.commit(ctx, tx)
 }
 
-func (p *postgresRepository) Delete(id string) error {
-	user := &model.UserModel{
-		ID: id,
+// writeWithOutbox will execute the named write of user and record its event in the outbox within one transaction,
+// the stored row is returned so the values set by database are included. Without returning of dialect the row
+// is read back inside the transaction. sql.ErrNoRows is returned when no row is written
+func (p *sqlRepository) writeWithOutbox(ctx context.Context, op, query string, user *model.UserModel, eventType string) (*model.UserModel, error) {
+	ctx, cancel := p.timeouts.withTimeout(ctx, op)
+	defer cancel()
+
+	tx, err := beginx(ctx, p.DBWrite)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if p.dialect.returning {
+		query += " RETURNING " + model.UserSelectField
+	}
+	query, args, err := tx.BindNamed(query, user)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := &model.UserModel{}
+	if p.dialect.returning {
+		err = tx.GetContext(ctx, stored, query, args...)
+	} else if _, err = tx.ExecContext(ctx, query, args...); err == nil {
+		query = fmt.Sprintf("SELECT %s FROM users WHERE id = ? AND deleted_at IS NULL", model.UserSelectField)
+		if err := tx.GetContext(ctx, stored, tx.Rebind(query), user.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, p.dialect.storeError(err)
+	}
+
+	if err := p.writeOutbox(ctx, tx, eventType, stored.ID); err != nil {
+		return nil, err
 	}
-	_, err := p.DBWrite.NamedExec(`UPDATE users SET deleted_at = CURRENT_TIMESTAMP WHERE id = :id`, user)
+
+	if err := p.commit(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+// reader will return DBRead (the next replica when there are replicas), or DBWrite when the replica hasn't
+// replayed the write position the session of ctx must read, so the client reads its own writes under replica
+// lag. Without replayPosition of dialect the session reads from primary for the whole window after its write
+func (p *sqlRepository) reader(ctx context.Context) dbExecutor {
+	db := p.DBRead
+	if p.replicas != nil {
+		db = p.replicas.Next()
+	}
+
+	session := consistency.FromContext(ctx)
+	if session == nil || session.MinRead() == 0 || db == p.DBWrite {
+		return db
+	}
+
+	if !p.caughtUp(ctx, db, session.MinRead()) {
+		return p.DBWrite
+	}
+
+	return db
+}
+
+// caughtUp will check whether the replica db has replayed lsn, its replayed position is cached for
+// ReplayCacheTTL so the reads of session don't ask the replica every time
+func (p *sqlRepository) caughtUp(ctx context.Context, db dbExecutor, lsn consistency.LSN) bool {
+	if p.dialect.replayPosition == nil {
+		return false
+	}
+
+	read := func() (consistency.LSN, error) {
+		return p.dialect.replayPosition(ctx, db)
+	}
+	if p.replayed == nil {
+		position, err := read()
+		return err == nil && position >= lsn
+	}
+
+	caughtUp, err := p.replayed.Replayed(db, lsn, read)
+	return err == nil && caughtUp
+}
+
+// commit will commit the transaction and record the write position into the session of ctx, the joined
+// transaction is recorded by its owner. The position which can't be read is recorded as MaxLSN, so the
+// session reads from primary
+func (p *sqlRepository) commit(ctx context.Context, tx txExecutor) error {
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	session := consistency.FromContext(ctx)
+	if _, joined := tx.(joinedTx); joined || session == nil || (p.replicas == nil && p.DBRead == p.DBWrite) {
+		return nil
+	}
+
+	lsn := consistency.MaxLSN
+	if p.dialect.writePosition != nil {
+		if position, err := p.dialect.writePosition(ctx, p.DBWrite); err == nil {
+			lsn = position
+		}
+	}
+	session.Wrote(lsn)
+
+	return nil
+}
+
+// writeOutbox will record the event of users in the outbox, it must run in the transaction of the
+// write so the event is stored if and only if the change is committed
+func (p *sqlRepository) writeOutbox(ctx context.Context, tx dbExecutor, eventType string, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query, args, err := p.dialect.outbox(eventType, ids)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, tx.Rebind(query), args...)
 	return err
 }
+
+// postgresOutbox will build the insert of user events into the outbox on PostgreSQL
+func postgresOutbox(eventType string, ids []string) (string, []interface{}, error) {
+	query := fmt.Sprintf(`INSERT INTO outbox (aggregate_type, aggregate_id, event_type, payload) SELECT ?, id, ?, %s FROM users WHERE id = ANY(?)`, outboxPayload)
+	return query, []interface{}{OutboxAggregate, eventType, pq.Array(ids)}, nil
+}
+
+// inOutbox will create the insert of user events into the outbox selecting the users with IN, payload is the
+// JSON of stored user row
+func inOutbox(payload string) func(eventType string, ids []string) (string, []interface{}, error) {
+	return func(eventType string, ids []string) (string, []interface{}, error) {
+		return sqlx.In(`INSERT INTO outbox (aggregate_type, aggregate_id, event_type, payload) SELECT ?, id, ?, `+payload+` FROM users WHERE id IN (?)`, OutboxAggregate, eventType, ids)
+	}
+}
+
+// postgresReplayPosition will read the position replayed by replica, the primary (not in recovery) has
+// replayed everything
+func postgresReplayPosition(ctx context.Context, db dbExecutor) (consistency.LSN, error) {
+	var position sql.NullString
+	if err := db.GetContext(ctx, &position, "SELECT pg_last_wal_replay_lsn()::text"); err != nil {
+		return 0, err
+	}
+	if !position.Valid {
+		return consistency.MaxLSN, nil
+	}
+
+	return consistency.ParseLSN(position.String)
+}
+
+// postgresWritePosition will read the current write position of primary
+func postgresWritePosition(ctx context.Context, db dbExecutor) (consistency.LSN, error) {
+	var position string
+	if err := db.GetContext(ctx, &position, "SELECT pg_current_wal_lsn()::text"); err != nil {
+		return 0, err
+	}
+
+	return consistency.ParseLSN(position)
+}
+
+// postgresStoreError will translate the postgres error into domain error, so the service doesn't depend on the driver
+func postgresStoreError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return conflictError(err, pqErr.Constraint == emailIndex)
+	}
+
+	return err
+}
+
+// conflictError will translate the violated unique constraint into ErrConflict, wrapping ErrEmailTaken when
+// it's the email index
+func conflictError(err error, email bool) error {
+	if email {
+		return NewError(ErrConflict, fmt.Errorf("%w: %v", ErrEmailTaken, err))
+	}
+
+	return NewError(ErrConflict, err)
+}
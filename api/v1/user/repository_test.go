@@ -8,17 +8,35 @@ package user_test
 
 import (
 	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/consistency"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/replica"
 	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
 
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/rs/xid"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
+// outboxQuery represent the expected insert of user events into outbox
+const outboxQuery = "INSERT INTO outbox \\(aggregate_type, aggregate_id, event_type, payload\\) SELECT \\?, id, \\?, json_build_object\\(.+\\) FROM users WHERE id = ANY\\(\\?\\)"
+
+// storedRows will return the row of user returned by the write, with the timestamps set by database
+func storedRows(u *model.UserModel) *sqlmock.Rows {
+	now := time.Now().UTC()
+	return sqlmock.NewRows(strings.Split(model.UserSelectField, ",")).
+		AddRow(u.ID, u.Name, u.Email, u.Phone, u.PhoneRaw, u.Address, now, now)
+}
+
 func TestGet(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -28,26 +46,122 @@ func TestGet(t *testing.T) {
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
 
 	rows := sqlmock.NewRows([]string{"id", "name", "email", "phone", "address", "created_at", "updated_at", "deleted_at"}).
-		AddRow(xid.New().String(), "Momo", "momo@mail.com", "085640", "Indonesia", time.Now().UTC(), time.Now().UTC(), nil)
+		AddRow(xid.New().String(), "Momo", "momo@mail.com", "085640123456", "Indonesia", time.Now().UTC(), time.Now().UTC(), nil)
 
 	query := "SELECT " + model.UserSelectField + " FROM users WHERE deleted_at IS NULL ORDER BY id ASC LIMIT \\? OFFSET \\?"
 
 	mock.ExpectQuery(query).WillReturnRows(rows)
-	u := user.NewPostgresRepository(sqlxDB, sqlxDB)
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
 
-	orderBy := "id ASC"
-	where := "WHERE deleted_at IS NULL"
-	filter := map[string]interface{}{}
-	filter["limit"] = "10"
-	filter["offset"] = "0"
+	criteria := &user.UserCriteria{Sort: []user.Sort{{Field: "id"}}, Limit: 10}
 
-	users, err := u.Get(filter, where, orderBy, "")
+	users, err := u.Get(context.Background(), criteria)
 
 	assert.NotEmpty(t, users)
 	assert.NoError(t, err)
 	assert.Len(t, users, 1)
 }
 
+func TestGetTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	rows := sqlmock.NewRows([]string{"id"}).AddRow(xid.New().String())
+	mock.ExpectQuery("SELECT id FROM users").WillDelayFor(time.Second).WillReturnRows(rows)
+
+	// get has its own timeout while count falls back to the default one
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, user.NewTimeouts(map[string]int{user.TimeoutDefault: 5000, "get": 10}))
+
+	start := time.Now()
+	_, err = u.Get(context.Background(), &user.UserCriteria{Fields: []string{"id"}, Limit: 10})
+
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestGetCanceled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectQuery("SELECT id FROM users").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = u.GetByID(ctx, xid.New().String(), "id")
+
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestSearch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "phone", "address", "created_at", "updated_at", "rank", "highlight"}).
+		AddRow(xid.New().String(), "Momo", "momo@mail.com", "085640123456", "Indonesia", time.Now().UTC(), time.Now().UTC(), 0.6, "<b>Momo</b> momo@mail.com Indonesia")
+
+	query := "SELECT " + model.UserSelectField + ", ts_rank\\(search_vector, plainto_tsquery\\('simple', \\?\\)\\) AS rank, ts_headline\\(.+\\) AS highlight FROM users WHERE deleted_at IS NULL AND search_vector @@ plainto_tsquery\\('simple', \\?\\) ORDER BY id ASC LIMIT \\? OFFSET \\?"
+
+	mock.ExpectQuery(query).WillReturnRows(rows)
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
+
+	criteria := &user.UserCriteria{Query: "momo", Highlight: true, Sort: []user.Sort{{Field: "id"}}, Limit: 10}
+
+	users, err := u.Search(context.Background(), criteria)
+
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, "Momo", users[0].Name)
+	assert.Equal(t, 0.6, users[0].Rank)
+	assert.Equal(t, "<b>Momo</b> momo@mail.com Indonesia", users[0].Highlight)
+}
+
+func TestExport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	rows := sqlmock.NewRows([]string{"id", "name", "email"}).
+		AddRow(xid.New().String(), "Momo", "momo@mail.com").
+		AddRow(xid.New().String(), "Gendhis", "gendhis@mail.com")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DECLARE users_export NO SCROLL CURSOR FOR SELECT id,name,email FROM users WHERE deleted_at IS NULL AND \\(name LIKE \\?\\) ORDER BY created_at DESC").
+		WithArgs("%o%").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FETCH 500 FROM users_export").WillReturnRows(rows)
+	mock.ExpectExec("CLOSE users_export").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
+
+	criteria := (&user.UserCriteria{Fields: []string{"id", "name", "email"}}).Where("name", user.OpContains, "o")
+	names := []string{}
+	err = u.Export(context.Background(), criteria, func(user *model.UserModel) error {
+		names = append(names, user.Name)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Momo", "Gendhis"}, names)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestCount(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -61,12 +175,10 @@ func TestCount(t *testing.T) {
 	query := "SELECT COUNT\\(id\\) FROM users WHERE deleted_at IS NULL"
 
 	mock.ExpectQuery(query).WillReturnRows(rows)
-	u := user.NewPostgresRepository(sqlxDB, sqlxDB)
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
 
-	where := "WHERE deleted_at IS NULL"
-	filter := map[string]interface{}{}
-
-	count, err := u.Count(filter, where)
+	// the count doesn't depend on the ordering and page
+	count, err := u.Count(context.Background(), &user.UserCriteria{Sort: []user.Sort{{Field: "name"}}, Limit: 10})
 
 	assert.NoError(t, err)
 	assert.Equal(t, count, 1)
@@ -81,22 +193,147 @@ func TestCreate(t *testing.T) {
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
 
 	req := &model.UserModel{
-		ID:      xid.New().String(),
-		Name:    "Momo",
-		Email:   "momo@mail.com",
-		Phone:   "085640",
-		Address: "Indonesia",
+		ID:       xid.New().String(),
+		Name:     "Momo",
+		Email:    "momo@mail.com",
+		Phone:    "+6285640123456",
+		PhoneRaw: "085640123456",
+		Address:  "Indonesia",
 	}
 
-	query := "INSERT INTO users \\(id, name, email, phone, address, created_at, updated_at\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP"
+	query := "INSERT INTO users \\(id, name, email, phone, phone_raw, address, created_at, updated_at\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP\\) RETURNING " + model.UserSelectField
 
-	mock.ExpectExec(query).WithArgs(req.ID, req.Name, req.Email, req.Phone, req.Address).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs(req.ID, req.Name, req.Email, req.Phone, req.PhoneRaw, req.Address).WillReturnRows(storedRows(req))
+	mock.ExpectExec(outboxQuery).WithArgs(user.OutboxAggregate, user.EventUserCreated, "{\""+req.ID+"\"}").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
-	u := user.NewPostgresRepository(sqlxDB, sqlxDB)
-	userRow, err := u.Create(req)
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
+	userRow, err := u.Create(context.Background(), req)
 
 	assert.NoError(t, err)
 	assert.Equal(t, req.ID, userRow.ID)
+	assert.False(t, userRow.CreatedAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateFailOutbox(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	req := &model.UserModel{ID: xid.New().String(), Name: "Momo", Email: "momo@mail.com"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO users").WillReturnRows(storedRows(req))
+	mock.ExpectExec(outboxQuery).WillReturnError(errors.New("Unexpected database error"))
+	mock.ExpectRollback()
+
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
+	_, err = u.Create(context.Background(), req)
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateFailConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	req := &model.UserModel{ID: xid.New().String(), Name: "Momo", Email: "momo@mail.com"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO users").WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
+	_, err = u.Create(context.Background(), req)
+
+	assert.True(t, errors.Is(err, user.ErrConflict))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateFailEmailTaken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	req := &model.UserModel{ID: xid.New().String(), Name: "Momo", Email: "Momo@mail.com"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE users").WillReturnError(&pq.Error{Code: "23505", Constraint: "idx_users_lower_email"})
+	mock.ExpectRollback()
+
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
+	_, err = u.Update(context.Background(), req)
+
+	assert.True(t, errors.Is(err, user.ErrConflict))
+	assert.True(t, errors.Is(err, user.ErrEmailTaken))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	users := []*model.UserModel{
+		{ID: xid.New().String(), Name: "Momo", Email: "Momo@mail.com", Phone: "+6285640123456", PhoneRaw: "085640123456", Address: "Indonesia"},
+		{ID: xid.New().String(), Name: "Gendhis", Email: "gendhis@mail.com", Phone: "+6285641123456", PhoneRaw: "085641123456", Address: "Indonesia"},
+	}
+
+	t.Run("insert", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO users \\(id, name, email, phone, phone_raw, address, created_at, updated_at\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP\\), \\(\\?, \\?, \\?, \\?, \\?, \\?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP\\)").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(outboxQuery).
+			WithArgs(user.OutboxAggregate, user.EventUserCreated, "{\""+users[0].ID+"\",\""+users[1].ID+"\"}").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
+		updated, err := u.Import(context.Background(), users, false)
+
+		assert.NoError(t, err)
+		assert.Empty(t, updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("upsert", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("UPDATE users AS u SET name = v.name, phone = v.phone, phone_raw = v.phone_raw, address = v.address, updated_at = CURRENT_TIMESTAMP FROM \\(VALUES \\(\\?, \\?, \\?, \\?, \\?\\), \\(\\?, \\?, \\?, \\?, \\?\\)\\) AS v").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow("bpielbbipt341rif5i20", "momo@mail.com"))
+		mock.ExpectExec(outboxQuery).
+			WithArgs(user.OutboxAggregate, user.EventUserUpdated, "{\"bpielbbipt341rif5i20\"}").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO users").
+			WithArgs(users[1].ID, users[1].Name, users[1].Email, users[1].Phone, users[1].PhoneRaw, users[1].Address).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(outboxQuery).
+			WithArgs(user.OutboxAggregate, user.EventUserCreated, "{\""+users[1].ID+"\"}").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
+		updated, err := u.Import(context.Background(), users, true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]bool{"momo@mail.com": true}, updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func TestGetByID(t *testing.T) {
@@ -111,26 +348,144 @@ func TestGetByID(t *testing.T) {
 		ID:        xid.New().String(),
 		Name:      "Momo",
 		Email:     "momo@mail.com",
-		Phone:     "085640",
+		Phone:     "+6285640123456",
+		PhoneRaw:  "085640123456",
 		Address:   "Indonesia",
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "name", "email", "phone", "address", "created_at", "updated_at"}).
-		AddRow(req.ID, req.Name, req.Email, req.Phone, req.Address, req.CreatedAt, req.UpdatedAt)
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "phone", "phone_raw", "address", "created_at", "updated_at"}).
+		AddRow(req.ID, req.Name, req.Email, req.Phone, req.PhoneRaw, req.Address, req.CreatedAt, req.UpdatedAt)
 
 	query := "SELECT " + model.UserSelectField + " FROM users WHERE deleted_at IS NULL AND id = \\?"
 
 	mock.ExpectQuery(query).WithArgs(req.ID).WillReturnRows(rows)
-	u := user.NewPostgresRepository(sqlxDB, sqlxDB)
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
 
-	userRow, err := u.GetByID(req.ID, "")
+	userRow, err := u.GetByID(context.Background(), req.ID, "")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, userRow)
 }
 
+func TestGetByIDReadYourWrites(t *testing.T) {
+	dbRead, mockRead, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer dbRead.Close()
+	dbWrite, mockWrite, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer dbWrite.Close()
+
+	u := user.NewPostgresRepository(sqlx.NewDb(dbRead, "sqlmock"), sqlx.NewDb(dbWrite, "sqlmock"), nil)
+	req := &model.UserModel{ID: xid.New().String(), Name: "Momo", Email: "momo@mail.com"}
+	replayQuery := "SELECT pg_last_wal_replay_lsn\\(\\)::text"
+	query := "SELECT " + model.UserSelectField + " FROM users"
+
+	t.Run("replica-behind", func(t *testing.T) {
+		ctx := consistency.NewContext(context.Background(), consistency.NewSession(0x3000060))
+		mockRead.ExpectQuery(replayQuery).WillReturnRows(sqlmock.NewRows([]string{"lsn"}).AddRow("0/3000000"))
+		mockWrite.ExpectQuery(query).WithArgs(req.ID).WillReturnRows(storedRows(req))
+
+		userRow, err := u.GetByID(ctx, req.ID, model.UserSelectField)
+
+		assert.NoError(t, err)
+		assert.Equal(t, req.ID, userRow.ID)
+
+		// the replayed position is cached, so the next read goes to primary without asking the replica
+		mockWrite.ExpectQuery(query).WithArgs(req.ID).WillReturnRows(storedRows(req))
+
+		_, err = u.GetByID(ctx, req.ID, model.UserSelectField)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mockRead.ExpectationsWereMet())
+		assert.NoError(t, mockWrite.ExpectationsWereMet())
+	})
+
+	t.Run("replica-caught-up", func(t *testing.T) {
+		time.Sleep(user.ReplayCacheTTL)
+
+		ctx := consistency.NewContext(context.Background(), consistency.NewSession(0x3000060))
+		mockRead.ExpectQuery(replayQuery).WillReturnRows(sqlmock.NewRows([]string{"lsn"}).AddRow("0/3000080"))
+		mockRead.ExpectQuery(query).WithArgs(req.ID).WillReturnRows(storedRows(req))
+		mockRead.ExpectQuery(query).WithArgs(req.ID).WillReturnRows(storedRows(req))
+
+		_, err := u.GetByID(ctx, req.ID, model.UserSelectField)
+		assert.NoError(t, err)
+
+		// the replica which has replayed the position has it for good
+		time.Sleep(user.ReplayCacheTTL)
+		_, err = u.GetByID(ctx, req.ID, model.UserSelectField)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mockRead.ExpectationsWereMet())
+		assert.NoError(t, mockWrite.ExpectationsWereMet())
+	})
+
+	t.Run("write", func(t *testing.T) {
+		session := consistency.NewSession(0)
+		ctx := consistency.NewContext(context.Background(), session)
+		mockWrite.ExpectBegin()
+		mockWrite.ExpectQuery("INSERT INTO users").WillReturnRows(storedRows(req))
+		mockWrite.ExpectExec(outboxQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+		mockWrite.ExpectCommit()
+		mockWrite.ExpectQuery("SELECT pg_current_wal_lsn\\(\\)").WillReturnRows(sqlmock.NewRows([]string{"lsn"}).AddRow("0/3000060"))
+
+		_, err := u.Create(ctx, req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, consistency.LSN(0x3000060), session.Written())
+		assert.Equal(t, consistency.LSN(0x3000060), session.MinRead())
+		assert.NoError(t, mockWrite.ExpectationsWereMet())
+	})
+}
+
+func TestGetByIDReplicas(t *testing.T) {
+	dbReplica, mockReplica, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer dbReplica.Close()
+	dbWrite, mockWrite, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer dbWrite.Close()
+
+	primary := sqlx.NewDb(dbWrite, "sqlmock")
+	replicas := replica.NewSet(logrus.NewEntry(logrus.New()), primary, []*sqlx.DB{sqlx.NewDb(dbReplica, "sqlmock")}, 0)
+	u := user.NewPostgresReplicaRepository(replicas, primary, nil)
+	req := &model.UserModel{ID: xid.New().String(), Name: "Momo", Email: "momo@mail.com"}
+	query := "SELECT " + model.UserSelectField + " FROM users"
+
+	t.Run("replica", func(t *testing.T) {
+		mockReplica.ExpectQuery(query).WithArgs(req.ID).WillReturnRows(storedRows(req))
+
+		_, err := u.GetByID(context.Background(), req.ID, model.UserSelectField)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mockReplica.ExpectationsWereMet())
+		assert.NoError(t, mockWrite.ExpectationsWereMet())
+	})
+
+	t.Run("replica-ejected", func(t *testing.T) {
+		mockReplica.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnError(errors.New("connection refused"))
+		replicas.Check(context.Background())
+		mockWrite.ExpectQuery(query).WithArgs(req.ID).WillReturnRows(storedRows(req))
+
+		_, err := u.GetByID(context.Background(), req.ID, model.UserSelectField)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, replicas.Healthy())
+		assert.NoError(t, mockReplica.ExpectationsWereMet())
+		assert.NoError(t, mockWrite.ExpectationsWereMet())
+	})
+}
+
 func TestUpdate(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -140,19 +495,77 @@ func TestUpdate(t *testing.T) {
 	sqlxDB := sqlx.NewDb(db, "sqlmock")
 
 	req := &model.UserModel{
-		ID:      xid.New().String(),
-		Name:    "Momo",
-		Email:   "momo@mail.com",
-		Phone:   "085640",
-		Address: "Indonesia",
+		ID:       xid.New().String(),
+		Name:     "Momo",
+		Email:    "momo@mail.com",
+		Phone:    "+6285640123456",
+		PhoneRaw: "085640123456",
+		Address:  "Indonesia",
+	}
+
+	query := "UPDATE users SET name = \\?, email = \\?, phone = \\?, phone_raw = \\?, address = \\?, updated_at = CURRENT_TIMESTAMP WHERE id = \\? AND deleted_at IS NULL RETURNING " + model.UserSelectField
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs(req.Name, req.Email, req.Phone, req.PhoneRaw, req.Address, req.ID).WillReturnRows(storedRows(req))
+	mock.ExpectExec(outboxQuery).WithArgs(user.OutboxAggregate, user.EventUserUpdated, "{\""+req.ID+"\"}").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
+
+	userRow, err := u.Update(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, req.ID, userRow.ID)
+	assert.False(t, userRow.UpdatedAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateFailNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	req := &model.UserModel{ID: xid.New().String(), Name: "Momo", Email: "momo@mail.com"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE users").WillReturnRows(sqlmock.NewRows(strings.Split(model.UserSelectField, ",")))
+	mock.ExpectRollback()
+
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
+	_, err = u.Update(context.Background(), req)
+
+	assert.Equal(t, sql.ErrNoRows, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	req := &model.UserModel{
+		ID:       xid.New().String(),
+		Name:     "Momo",
+		Email:    "momo@mail.com",
+		Phone:    "+6285640123456",
+		PhoneRaw: "085640123456",
+		Address:  "Indonesia",
 	}
 
-	query := "UPDATE users SET name = \\?, email = \\?, phone = \\?, address = \\?, updated_at = CURRENT_TIMESTAMP WHERE id = \\?"
+	query := "UPDATE users SET name = \\?, address = \\?, updated_at = CURRENT_TIMESTAMP WHERE id = \\? AND deleted_at IS NULL RETURNING " + model.UserSelectField
 
-	mock.ExpectExec(query).WithArgs(req.Name, req.Email, req.Phone, req.Address, req.ID).WillReturnResult(sqlmock.NewResult(0, 1))
-	u := user.NewPostgresRepository(sqlxDB, sqlxDB)
+	mock.ExpectBegin()
+	mock.ExpectQuery(query).WithArgs(req.Name, req.Address, req.ID).WillReturnRows(storedRows(req))
+	mock.ExpectExec(outboxQuery).WithArgs(user.OutboxAggregate, user.EventUserUpdated, "{\""+req.ID+"\"}").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
 
-	userRow, err := u.Update(req)
+	userRow, err := u.Patch(context.Background(), req, []string{"name", "address"})
 
 	assert.NoError(t, err)
 	assert.Equal(t, req.ID, userRow.ID)
@@ -168,12 +581,86 @@ func TestDelete(t *testing.T) {
 
 	id := xid.New().String()
 
-	query := "UPDATE users SET deleted_at = CURRENT_TIMESTAMP WHERE id = \\?"
+	query := "UPDATE users SET deleted_at = CURRENT_TIMESTAMP WHERE id = \\? AND deleted_at IS NULL"
 
+	mock.ExpectBegin()
 	mock.ExpectExec(query).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
-	u := user.NewPostgresRepository(sqlxDB, sqlxDB)
+	mock.ExpectExec(outboxQuery).WithArgs(user.OutboxAggregate, user.EventUserDeleted, "{\""+id+"\"}").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
 
-	err = u.Delete(id)
+	err = u.Delete(context.Background(), id)
 
 	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteFailNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users SET deleted_at").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
+	err = u.Delete(context.Background(), xid.New().String())
+
+	assert.Equal(t, sql.ErrNoRows, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "sqlmock")
+
+	u := user.NewPostgresRepository(sqlxDB, sqlxDB, nil)
+	userReq := &model.UserModel{ID: xid.New().String(), Name: "Momo", Email: "momo@mail.com"}
+
+	t.Run("commit", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO users").WillReturnRows(storedRows(userReq))
+		mock.ExpectExec(outboxQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(outboxQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := u.WithTx(context.Background(), func(r user.Repository) error {
+			if _, err := r.Create(context.Background(), userReq); err != nil {
+				return err
+			}
+			// the import joins the running transaction instead of starting another one
+			_, err := r.Import(context.Background(), []*model.UserModel{userReq}, false)
+			return err
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO users").WillReturnRows(storedRows(userReq))
+		mock.ExpectExec(outboxQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectRollback()
+
+		errRollback := errors.New("rollback")
+		err := u.WithTx(context.Background(), func(r user.Repository) error {
+			if _, err := r.Create(context.Background(), userReq); err != nil {
+				return err
+			}
+			return errRollback
+		})
+
+		assert.Equal(t, errRollback, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
@@ -10,116 +10,365 @@ import (
 	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
 	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
 
-	"database/sql"
+	"context"
 	"errors"
-	"net/http"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
 
-// Service represent the services
+// Service represent the services, the errors are the domain errors ErrNotFound, ErrConflict, ErrValidation
+// and ErrUnavailable, any other error is unexpected
 type Service interface {
-	Create(req *form.UserForm) (*model.UserModel, int, error)
-	Delete(id string) (int, error)
-	Detail(id string, selectField string) (*model.UserModel, int, error)
-	List(filter, filterCount map[string]interface{}, where, orderBy, selectField string) ([]*model.UserModel, int, int, error)
-	Update(req *form.UserForm, id string) (*model.UserModel, int, error)
+	Create(ctx context.Context, req *form.UserForm) (*model.UserModel, error)
+	Import(ctx context.Context, reqs []*form.UserForm, upsert, dryRun bool) (*ImportResult, error)
+	Delete(ctx context.Context, id string) error
+	Detail(ctx context.Context, id string, selectField string) (*model.UserModel, error)
+	List(ctx context.Context, criteria *UserCriteria) ([]*model.UserModel, int, error)
+	ListWithoutCount(ctx context.Context, criteria *UserCriteria) ([]*model.UserModel, error)
+	Count(ctx context.Context, criteria *UserCriteria) (int, error)
+	Search(ctx context.Context, criteria *UserCriteria) ([]*model.UserSearchModel, int, error)
+	Export(ctx context.Context, criteria *UserCriteria, fn func(*model.UserModel) error) error
+	Update(ctx context.Context, req *form.UserForm, id string) (*model.UserModel, error)
+	Patch(ctx context.Context, req *form.UserForm, current *model.UserModel) (*model.UserModel, error)
+	Atomic(ctx context.Context, fn func(Service) error) error
+	Subscribe(lastEventID uint64) ([]*model.UserEvent, <-chan *model.UserEvent, func())
+}
+
+// ImportResult represent the outcome of import, Rejected holds the lowercased emails of the users which
+// aren't imported as their email is registered already
+type ImportResult struct {
+	Created  int
+	Updated  int
+	Rejected map[string]bool
 }
 
 type implService struct {
 	log        *logrus.Entry
 	repository Repository
+	broker     *Broker
+	// pending represent the events of transaction, published once it's committed
+	pending *[]*model.UserEvent
 }
 
 // NewService will create an object that represent the Service interface
 func NewService(log *logrus.Entry, r Repository) Service {
-	return &implService{log: log, repository: r}
+	return &implService{log: log, repository: r, broker: NewBroker(EventBufferSize)}
 }
 
-func (u *implService) Create(req *form.UserForm) (*model.UserModel, int, error) {
+func (u *implService) Create(ctx context.Context, req *form.UserForm) (*model.UserModel, error) {
+	if errs := req.Validate(); len(errs) > 0 {
+		return nil, &ValidationError{Fields: errs}
+	}
+
 	userReq := &model.UserModel{
-		ID:      req.ID,
-		Name:    req.Name,
-		Email:   req.Email,
-		Phone:   req.Phone,
-		Address: req.Address,
+		ID:       req.ID,
+		Name:     req.Name,
+		Email:    req.Email,
+		Phone:    req.NormalizedPhone(),
+		PhoneRaw: req.Phone,
+		Address:  req.Address,
 	}
 
-	user, err := u.repository.Create(userReq)
+	user, err := u.repository.Create(ctx, userReq)
 	if err != nil {
-		u.log.Errorf("can't create user: %s", err.Error())
-		return nil, http.StatusInternalServerError, errors.New("Oops! Something went wrong. Please try again later")
+		err = domainError(err)
+		if !errors.Is(err, ErrConflict) {
+			u.log.Errorf("can't create user: %s", err.Error())
+		}
+		return nil, err
 	}
 
-	return user, 0, nil
+	u.publish(EventUserCreated, user)
+
+	return user, nil
 }
 
-func (u *implService) Delete(id string) (int, error) {
+// Import will insert the users within one transaction, on upsert the users matching an existing email are
+// updated instead. Otherwise the users whose email is registered already are rejected and the rest are inserted.
+// On dry run the rejected users are only looked up
+func (u *implService) Import(ctx context.Context, reqs []*form.UserForm, upsert, dryRun bool) (*ImportResult, error) {
+	users := []*model.UserModel{}
+	for _, req := range reqs {
+		users = append(users, &model.UserModel{
+			ID:       req.ID,
+			Name:     req.Name,
+			Email:    req.Email,
+			Phone:    req.NormalizedPhone(),
+			PhoneRaw: req.Phone,
+			Address:  req.Address,
+		})
+	}
+
+	result := &ImportResult{Rejected: map[string]bool{}}
+	run := func(repo Repository) error {
+		if !upsert {
+			emails := []string{}
+			for _, user := range users {
+				emails = append(emails, user.Email)
+			}
+
+			existing, err := repo.ExistingEmails(ctx, emails)
+			if err != nil {
+				return err
+			}
+			result.Rejected = existing
+
+			inserts := []*model.UserModel{}
+			for _, user := range users {
+				if !existing[strings.ToLower(user.Email)] {
+					inserts = append(inserts, user)
+				}
+			}
+			users = inserts
+		}
+
+		if dryRun || len(users) == 0 {
+			return nil
+		}
+
+		updatedEmails, err := repo.Import(ctx, users, upsert)
+		if err != nil {
+			return err
+		}
 
-	_, err := u.repository.GetByID(id, "id")
-	if err == sql.ErrNoRows {
-		return http.StatusNotFound, errors.New("User not found")
+		for _, user := range users {
+			if updatedEmails[strings.ToLower(user.Email)] {
+				result.Updated++
+			}
+		}
+		result.Created = len(users) - result.Updated
+
+		return nil
 	}
 
+	var err error
+	if dryRun {
+		err = run(u.repository)
+	} else {
+		err = u.repository.WithTx(ctx, run)
+	}
 	if err != nil {
-		u.log.Errorf("can't get user: %s with id %v", err.Error(), id)
-		return http.StatusInternalServerError, errors.New("Oops! Something went wrong. Please try again later")
+		err = domainError(err)
+		if !errors.Is(err, ErrConflict) {
+			u.log.Errorf("can't import users: %s", err.Error())
+		}
+		return nil, err
 	}
 
-	err = u.repository.Delete(id)
+	return result, nil
+}
+
+func (u *implService) Delete(ctx context.Context, id string) error {
+	err := u.repository.Delete(ctx, id)
 	if err != nil {
-		u.log.Errorf("can't delete user: %s", err.Error())
-		return http.StatusInternalServerError, errors.New("Oops! Something went wrong. Please try again later")
+		err = domainError(err)
+		if !errors.Is(err, ErrNotFound) {
+			u.log.Errorf("can't delete user: %s with id %v", err.Error(), id)
+		}
+		return err
 	}
 
-	return 0, nil
+	u.publish(EventUserDeleted, &model.UserModel{ID: id})
+
+	return nil
 }
 
-func (u *implService) Detail(id string, selectField string) (*model.UserModel, int, error) {
-	user, err := u.repository.GetByID(id, selectField)
-	if err == sql.ErrNoRows {
-		return nil, http.StatusNotFound, errors.New("User not found")
+func (u *implService) Detail(ctx context.Context, id string, selectField string) (*model.UserModel, error) {
+	user, err := u.repository.GetByID(ctx, id, selectField)
+	if err != nil {
+		err = domainError(err)
+		if !errors.Is(err, ErrNotFound) {
+			u.log.Errorf("can't get user: %s with id %v", err.Error(), id)
+		}
+		return nil, err
 	}
 
+	return user, nil
+}
+
+func (u *implService) List(ctx context.Context, criteria *UserCriteria) ([]*model.UserModel, int, error) {
+
+	users, err := u.repository.Get(ctx, criteria)
 	if err != nil {
-		u.log.Errorf("can't get user: %s with id %v", err.Error(), id)
-		return nil, http.StatusInternalServerError, errors.New("Oops! Something went wrong. Please try again later")
+		u.log.Errorf("can't get users: %s", err.Error())
+		return nil, 0, domainError(err)
 	}
 
-	return user, 0, nil
+	count, err := u.repository.Count(ctx, criteria)
+	if err != nil {
+		u.log.Errorf("can't count users: %s", err.Error())
+		return nil, 0, domainError(err)
+	}
+
+	return users, count, nil
 }
 
-func (u *implService) List(filter, filterCount map[string]interface{}, where, orderBy, selectField string) ([]*model.UserModel, int, int, error) {
+func (u *implService) ListWithoutCount(ctx context.Context, criteria *UserCriteria) ([]*model.UserModel, error) {
 
-	users, err := u.repository.Get(filter, where, orderBy, selectField)
+	users, err := u.repository.Get(ctx, criteria)
 	if err != nil {
 		u.log.Errorf("can't get users: %s", err.Error())
-		return nil, 0, http.StatusInternalServerError, errors.New("Oops! Something went wrong. Please try again later")
+		return nil, domainError(err)
 	}
 
-	count, err := u.repository.Count(filterCount, where)
+	return users, nil
+}
+
+// Count will count the users matching the filter and search of criteria, regardless of the cursor and page
+func (u *implService) Count(ctx context.Context, criteria *UserCriteria) (int, error) {
+
+	count, err := u.repository.Count(ctx, criteria)
 	if err != nil {
 		u.log.Errorf("can't count users: %s", err.Error())
-		return nil, 0, http.StatusInternalServerError, errors.New("Oops! Something went wrong. Please try again later")
+		return 0, domainError(err)
+	}
+
+	return count, nil
+}
+
+func (u *implService) Search(ctx context.Context, criteria *UserCriteria) ([]*model.UserSearchModel, int, error) {
+
+	users, err := u.repository.Search(ctx, criteria)
+	if err != nil {
+		u.log.Errorf("can't search users: %s", err.Error())
+		return nil, 0, domainError(err)
+	}
+
+	count, err := u.repository.Count(ctx, criteria)
+	if err != nil {
+		u.log.Errorf("can't count searched users: %s", err.Error())
+		return nil, 0, domainError(err)
 	}
 
-	return users, count, 0, nil
+	return users, count, nil
 }
 
-func (u *implService) Update(req *form.UserForm, id string) (*model.UserModel, int, error) {
+func (u *implService) Export(ctx context.Context, criteria *UserCriteria, fn func(*model.UserModel) error) error {
+
+	err := u.repository.Export(ctx, criteria, fn)
+	if err != nil {
+		u.log.Errorf("can't export users: %s", err.Error())
+		return domainError(err)
+	}
+
+	return nil
+}
+
+func (u *implService) Update(ctx context.Context, req *form.UserForm, id string) (*model.UserModel, error) {
+	if errs := req.Validate(); len(errs) > 0 {
+		return nil, &ValidationError{Fields: errs}
+	}
+
 	user := &model.UserModel{
-		ID:      id,
-		Name:    req.Name,
-		Phone:   req.Phone,
-		Email:   req.Email,
-		Address: req.Address,
+		ID:       id,
+		Name:     req.Name,
+		Phone:    req.NormalizedPhone(),
+		PhoneRaw: req.Phone,
+		Email:    req.Email,
+		Address:  req.Address,
+	}
+
+	user, err := u.repository.Update(ctx, user)
+	if err != nil {
+		err = domainError(err)
+		if !errors.Is(err, ErrConflict) && !errors.Is(err, ErrNotFound) {
+			u.log.Errorf("can't update user: %s with id %v", err.Error(), id)
+		}
+		return nil, err
+	}
+
+	u.publish(EventUserUpdated, user)
+
+	return user, nil
+}
+
+func (u *implService) Patch(ctx context.Context, req *form.UserForm, current *model.UserModel) (*model.UserModel, error) {
+	if errs := req.Validate(); len(errs) > 0 {
+		return nil, &ValidationError{Fields: errs}
+	}
+
+	user := *current
+	fields := []string{}
+
+	if req.Name != user.Name {
+		user.Name = req.Name
+		fields = append(fields, "name")
+	}
+
+	if req.Email != user.Email {
+		user.Email = req.Email
+		fields = append(fields, "email")
+	}
+
+	if phone := req.NormalizedPhone(); phone != user.Phone {
+		user.Phone = phone
+		user.PhoneRaw = req.Phone
+		fields = append(fields, "phone", "phone_raw")
+	}
+
+	if req.Address != user.Address {
+		user.Address = req.Address
+		fields = append(fields, "address")
+	}
+
+	if len(fields) == 0 {
+		return current, nil
+	}
+
+	patched, err := u.repository.Patch(ctx, &user, fields)
+	if err != nil {
+		err = domainError(err)
+		if !errors.Is(err, ErrConflict) && !errors.Is(err, ErrNotFound) {
+			u.log.Errorf("can't patch user: %s with id %v", err.Error(), user.ID)
+		}
+		return nil, err
+	}
+
+	u.publish(EventUserUpdated, patched)
+
+	return patched, nil
+}
+
+// Atomic will run fn with the service bound to one transaction, every write done through the given
+// service is rolled back when fn returns error, which is returned as is. The events of fn are only
+// published after commit
+func (u *implService) Atomic(ctx context.Context, fn func(Service) error) error {
+	pending := []*model.UserEvent{}
+
+	var fnErr error
+	err := u.repository.WithTx(ctx, func(r Repository) error {
+		fnErr = fn(&implService{log: u.log, repository: r, broker: u.broker, pending: &pending})
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
 	}
 
-	user, err := u.repository.Update(user)
 	if err != nil {
-		u.log.Errorf("can't update user: %s with id %v", err.Error(), id)
-		return nil, http.StatusInternalServerError, errors.New("Oops! Something went wrong. Please try again later")
+		u.log.Errorf("can't run transaction: %s", err.Error())
+		return domainError(err)
+	}
+
+	for _, event := range pending {
+		u.publish(event.Type, event.User)
+	}
+
+	return nil
+}
+
+// Subscribe will return the events after lastEventID kept for replay and the channel of next events,
+// cancel must be called once the subscriber is done
+func (u *implService) Subscribe(lastEventID uint64) ([]*model.UserEvent, <-chan *model.UserEvent, func()) {
+	return u.broker.Subscribe(lastEventID)
+}
+
+// publish will send the event to subscribers, or hold it until the transaction is committed
+func (u *implService) publish(eventType string, user *model.UserModel) {
+	if u.pending != nil {
+		*u.pending = append(*u.pending, &model.UserEvent{Type: eventType, User: user})
+		return
 	}
 
-	return user, 0, nil
+	u.broker.Publish(eventType, user)
 }
@@ -13,9 +13,9 @@ import (
 	"github.com/moemoe89/go-graphql-gendhis/api/v1/user/mocks"
 	"github.com/moemoe89/go-graphql-gendhis/config"
 
+	"context"
 	"database/sql"
 	"errors"
-	"net/http"
 	"testing"
 	"time"
 
@@ -32,109 +32,204 @@ func TestServiceCreate(t *testing.T) {
 		ID:      xid.New().String(),
 		Name:    "Momo",
 		Email:   "momo@mail.com",
-		Phone:   "085640",
+		Phone:   "085640123456",
 		Address: "Indonesia",
 	}
 
 	mockUser := &model.UserModel{
-		ID:      reqUser.ID,
-		Name:    reqUser.Name,
-		Email:   reqUser.Email,
-		Phone:   reqUser.Phone,
-		Address: reqUser.Address,
+		ID:       reqUser.ID,
+		Name:     reqUser.Name,
+		Email:    reqUser.Email,
+		Phone:    "+6285640123456",
+		PhoneRaw: reqUser.Phone,
+		Address:  reqUser.Address,
 	}
 
 	t.Run("success", func(t *testing.T) {
-		mockRepo.On("Create", mockUser).Return(mockUser, nil).Once()
+		mockRepo.On("Create", mock.Anything, mockUser).Return(mockUser, nil).Once()
 		u := user.NewService(log, mockRepo)
 
-		userRow, status, err := u.Create(reqUser)
+		userRow, err := u.Create(context.Background(), reqUser)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, userRow)
-		assert.Equal(t, 0, status)
 
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("failed", func(t *testing.T) {
-		mockRepo.On("Create", mockUser).Return(nil, errors.New("Unexpected database error")).Once()
+		mockRepo.On("Create", mock.Anything, mockUser).Return(nil, errors.New("Unexpected database error")).Once()
 		u := user.NewService(log, mockRepo)
 
-		userRow, status, err := u.Create(reqUser)
+		userRow, err := u.Create(context.Background(), reqUser)
 
 		assert.Error(t, err)
 		assert.Nil(t, userRow)
-		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.EqualError(t, err, "Unexpected database error")
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed-conflict", func(t *testing.T) {
+		mockRepo.On("Create", mock.Anything, mockUser).Return(nil, user.NewError(user.ErrConflict, errors.New("duplicate key"))).Once()
+		u := user.NewService(log, mockRepo)
+
+		userRow, err := u.Create(context.Background(), reqUser)
+
+		assert.True(t, errors.Is(err, user.ErrConflict))
+		assert.Nil(t, userRow)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed-unavailable", func(t *testing.T) {
+		mockRepo.On("Create", mock.Anything, mockUser).Return(nil, context.DeadlineExceeded).Once()
+		u := user.NewService(log, mockRepo)
+
+		userRow, err := u.Create(context.Background(), reqUser)
+
+		assert.True(t, errors.Is(err, user.ErrUnavailable))
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.Nil(t, userRow)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed-validation", func(t *testing.T) {
+		u := user.NewService(log, mockRepo)
+
+		userRow, err := u.Create(context.Background(), &form.UserForm{ID: reqUser.ID, Email: "momo"})
+
+		var validationErr *user.ValidationError
+		assert.True(t, errors.As(err, &validationErr))
+		assert.True(t, errors.Is(err, user.ErrValidation))
+		assert.Len(t, validationErr.Fields, 2)
+		assert.Nil(t, userRow)
 
 		mockRepo.AssertExpectations(t)
 	})
 }
 
-func TestServiceDelete(t *testing.T) {
+func TestServiceImport(t *testing.T) {
 	log := config.InitLog()
 	mockRepo := new(mocks.Repository)
-	mockUser := &model.UserModel{
-		ID:        xid.New().String(),
-		Name:      "Momo",
-		Email:     "momo@mail.com",
-		Phone:     "085640",
-		Address:   "Indonesia",
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
+
+	reqs := []*form.UserForm{
+		{ID: xid.New().String(), Name: "Momo", Email: "Momo@mail.com"},
+		{ID: xid.New().String(), Name: "Gendhis", Email: "gendhis@mail.com"},
+	}
+
+	withTx := func(ctx context.Context, fn func(user.Repository) error) error {
+		return fn(mockRepo)
 	}
 
 	t.Run("success", func(t *testing.T) {
-		mockRepo.On("GetByID", mock.AnythingOfType("string"), "id").Return(mockUser, nil).Once()
-		mockRepo.On("Delete", mock.AnythingOfType("string")).Return(nil).Once()
+		mockRepo.On("WithTx", mock.Anything, mock.Anything).Return(withTx).Once()
+		mockRepo.On("Import", mock.Anything, mock.AnythingOfType("[]*model.UserModel"), true).Return(map[string]bool{"momo@mail.com": true}, nil).Once()
 		u := user.NewService(log, mockRepo)
 
-		status, err := u.Delete(mockUser.ID)
+		result, err := u.Import(context.Background(), reqs, true, false)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 0, status)
+		assert.Equal(t, 1, result.Created)
+		assert.Equal(t, 1, result.Updated)
+		assert.Empty(t, result.Rejected)
 
 		mockRepo.AssertExpectations(t)
 	})
 
-	t.Run("failed-delete", func(t *testing.T) {
-		mockRepo.On("GetByID", mock.AnythingOfType("string"), "id").Return(mockUser, nil).Once()
-		mockRepo.On("Delete", mock.AnythingOfType("string")).Return(errors.New("Unexpected database error")).Once()
+	t.Run("success reject registered email", func(t *testing.T) {
+		mockRepo.On("WithTx", mock.Anything, mock.Anything).Return(withTx).Once()
+		mockRepo.On("ExistingEmails", mock.Anything, []string{"Momo@mail.com", "gendhis@mail.com"}).Return(map[string]bool{"momo@mail.com": true}, nil).Once()
+		mockRepo.On("Import", mock.Anything, mock.MatchedBy(func(users []*model.UserModel) bool {
+			return len(users) == 1 && users[0].Name == "Gendhis"
+		}), false).Return(map[string]bool{}, nil).Once()
 		u := user.NewService(log, mockRepo)
 
-		status, err := u.Delete(mockUser.ID)
+		result, err := u.Import(context.Background(), reqs, false, false)
 
-		assert.Error(t, err)
-		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result.Created)
+		assert.Equal(t, map[string]bool{"momo@mail.com": true}, result.Rejected)
 
 		mockRepo.AssertExpectations(t)
 	})
 
-	t.Run("failed-get-not-found", func(t *testing.T) {
-		mockRepo.On("GetByID", mock.AnythingOfType("string"), "id").Return(nil, sql.ErrNoRows).Once()
+	t.Run("success dry run", func(t *testing.T) {
+		mockRepo := new(mocks.Repository)
+		mockRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{"gendhis@mail.com": true}, nil).Once()
+		u := user.NewService(log, mockRepo)
+
+		result, err := u.Import(context.Background(), reqs, false, true)
 
+		assert.NoError(t, err)
+		assert.Zero(t, result.Created)
+		assert.Equal(t, map[string]bool{"gendhis@mail.com": true}, result.Rejected)
+
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "Import", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "WithTx", mock.Anything, mock.Anything)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockRepo.On("WithTx", mock.Anything, mock.Anything).Return(withTx).Once()
+		mockRepo.On("ExistingEmails", mock.Anything, mock.Anything).Return(map[string]bool{}, nil).Once()
+		mockRepo.On("Import", mock.Anything, mock.AnythingOfType("[]*model.UserModel"), false).Return(nil, errors.New("Unexpected database error")).Once()
 		u := user.NewService(log, mockRepo)
 
-		status, err := u.Delete(mockUser.ID)
+		_, err := u.Import(context.Background(), reqs, false, false)
 
-		assert.Error(t, err)
-		assert.Equal(t, http.StatusNotFound, status)
+		assert.EqualError(t, err, "Unexpected database error")
 
 		mockRepo.AssertExpectations(t)
 	})
+}
 
-	t.Run("failed-get", func(t *testing.T) {
-		mockRepo.On("GetByID", mock.AnythingOfType("string"), "id").Return(nil, errors.New("Unexpected database error")).Once()
+func TestServiceDelete(t *testing.T) {
+	log := config.InitLog()
+	mockRepo := new(mocks.Repository)
+	mockUser := &model.UserModel{
+		ID:        xid.New().String(),
+		Name:      "Momo",
+		Email:     "momo@mail.com",
+		Phone:     "085640123456",
+		Address:   "Indonesia",
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo.On("Delete", mock.Anything, mockUser.ID).Return(nil).Once()
 		u := user.NewService(log, mockRepo)
 
-		status, err := u.Delete(mockUser.ID)
+		err := u.Delete(context.Background(), mockUser.ID)
 
-		assert.Error(t, err)
-		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.NoError(t, err)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed-not-found", func(t *testing.T) {
+		mockRepo.On("Delete", mock.Anything, mockUser.ID).Return(sql.ErrNoRows).Once()
+		u := user.NewService(log, mockRepo)
+
+		err := u.Delete(context.Background(), mockUser.ID)
+
+		assert.True(t, errors.Is(err, user.ErrNotFound))
 
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("failed", func(t *testing.T) {
+		mockRepo.On("Delete", mock.Anything, mockUser.ID).Return(errors.New("Unexpected database error")).Once()
+		u := user.NewService(log, mockRepo)
+
+		err := u.Delete(context.Background(), mockUser.ID)
+
+		assert.EqualError(t, err, "Unexpected database error")
+
+		mockRepo.AssertExpectations(t)
+	})
 }
 
 func TestServiceDetail(t *testing.T) {
@@ -144,47 +239,46 @@ func TestServiceDetail(t *testing.T) {
 		ID:        xid.New().String(),
 		Name:      "Momo",
 		Email:     "momo@mail.com",
-		Phone:     "085640",
+		Phone:     "085640123456",
 		Address:   "Indonesia",
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
 	}
 
 	t.Run("success", func(t *testing.T) {
-		mockRepo.On("GetByID", mock.AnythingOfType("string"), "").Return(mockUser, nil).Once()
+		mockRepo.On("GetByID", mock.Anything, mock.AnythingOfType("string"), "").Return(mockUser, nil).Once()
 		u := user.NewService(log, mockRepo)
 
-		userRow, status, err := u.Detail(mockUser.ID, "")
+		userRow, err := u.Detail(context.Background(), mockUser.ID, "")
 
 		assert.NoError(t, err)
 		assert.NotNil(t, userRow)
-		assert.Equal(t, 0, status)
 
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("failed-not-found", func(t *testing.T) {
-		mockRepo.On("GetByID", mock.AnythingOfType("string"), "").Return(nil, sql.ErrNoRows).Once()
+		mockRepo.On("GetByID", mock.Anything, mock.AnythingOfType("string"), "").Return(nil, sql.ErrNoRows).Once()
 		u := user.NewService(log, mockRepo)
 
-		userRow, status, err := u.Detail(mockUser.ID, "")
+		userRow, err := u.Detail(context.Background(), mockUser.ID, "")
 
 		assert.Error(t, err)
 		assert.Nil(t, userRow)
-		assert.Equal(t, http.StatusNotFound, status)
+		assert.True(t, errors.Is(err, user.ErrNotFound))
 
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("failed", func(t *testing.T) {
-		mockRepo.On("GetByID", mock.AnythingOfType("string"), "").Return(nil, errors.New("Unexpected database error")).Once()
+		mockRepo.On("GetByID", mock.Anything, mock.AnythingOfType("string"), "").Return(nil, errors.New("Unexpected database error")).Once()
 		u := user.NewService(log, mockRepo)
 
-		userRow, status, err := u.Detail(mockUser.ID, "")
+		userRow, err := u.Detail(context.Background(), mockUser.ID, "")
 
 		assert.Error(t, err)
 		assert.Nil(t, userRow)
-		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.EqualError(t, err, "Unexpected database error")
 
 		mockRepo.AssertExpectations(t)
 	})
@@ -197,7 +291,7 @@ func TestServiceList(t *testing.T) {
 		ID:        xid.New().String(),
 		Name:      "Momo",
 		Email:     "momo@mail.com",
-		Phone:     "085640",
+		Phone:     "085640123456",
 		Address:   "Indonesia",
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
@@ -206,54 +300,215 @@ func TestServiceList(t *testing.T) {
 	mockListUser := make([]*model.UserModel, 0)
 	mockListUser = append(mockListUser, mockUser)
 
-	filter := map[string]interface{}{}
-	filter["limit"] = "10"
-	filter["offset"] = "0"
-	orderBy := "id DESC"
-	deletedNull := "WHERE deleted_at IS NULL"
+	criteria := &user.UserCriteria{Sort: []user.Sort{{Field: "id", Desc: true}}, Limit: 10}
 
 	t.Run("success", func(t *testing.T) {
-		mockRepo.On("Get", filter, deletedNull, orderBy, model.UserSelectField).Return(mockListUser, nil).Once()
-		mockRepo.On("Count", filter, deletedNull).Return(1, nil).Once()
+		mockRepo.On("Get", mock.Anything, criteria).Return(mockListUser, nil).Once()
+		mockRepo.On("Count", mock.Anything, criteria).Return(1, nil).Once()
 		u := user.NewService(log, mockRepo)
 
-		users, count, status, err := u.List(filter, filter, deletedNull, orderBy, model.UserSelectField)
+		users, count, err := u.List(context.Background(), criteria)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, users)
 		assert.Equal(t, 1, count)
-		assert.Equal(t, 0, status)
 
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("failed-get", func(t *testing.T) {
-		mockRepo.On("Get", filter, deletedNull, orderBy, model.UserSelectField).Return(nil, errors.New("Unexpected database error")).Once()
+		mockRepo.On("Get", mock.Anything, criteria).Return(nil, errors.New("Unexpected database error")).Once()
 
 		u := user.NewService(log, mockRepo)
 
-		users, count, status, err := u.List(filter, filter, deletedNull, orderBy, model.UserSelectField)
+		users, count, err := u.List(context.Background(), criteria)
 
 		assert.Error(t, err)
 		assert.Nil(t, users)
 		assert.Equal(t, 0, count)
-		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.EqualError(t, err, "Unexpected database error")
 
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("failed-count", func(t *testing.T) {
-		mockRepo.On("Get", filter, deletedNull, orderBy, model.UserSelectField).Return(mockListUser, nil).Once()
-		mockRepo.On("Count", filter, deletedNull).Return(0, errors.New("Unexpected database error")).Once()
+		mockRepo.On("Get", mock.Anything, criteria).Return(mockListUser, nil).Once()
+		mockRepo.On("Count", mock.Anything, criteria).Return(0, errors.New("Unexpected database error")).Once()
+
+		u := user.NewService(log, mockRepo)
+
+		users, count, err := u.List(context.Background(), criteria)
+
+		assert.Error(t, err)
+		assert.Nil(t, users)
+		assert.Equal(t, 0, count)
+		assert.EqualError(t, err, "Unexpected database error")
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServiceListWithoutCount(t *testing.T) {
+	log := config.InitLog()
+	mockRepo := new(mocks.Repository)
+	mockListUser := []*model.UserModel{
+		{
+			ID:        xid.New().String(),
+			Name:      "Momo",
+			Email:     "momo@mail.com",
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+		},
+	}
+
+	criteria := &user.UserCriteria{Keyset: user.NewKeyset(nil), Limit: 11}
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo.On("Get", mock.Anything, criteria).Return(mockListUser, nil).Once()
+		u := user.NewService(log, mockRepo)
+
+		users, err := u.ListWithoutCount(context.Background(), criteria)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockListUser, users)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockRepo.On("Get", mock.Anything, criteria).Return(nil, errors.New("Unexpected database error")).Once()
+		u := user.NewService(log, mockRepo)
+
+		users, err := u.ListWithoutCount(context.Background(), criteria)
+
+		assert.Error(t, err)
+		assert.Nil(t, users)
+		assert.EqualError(t, err, "Unexpected database error")
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServiceCount(t *testing.T) {
+	log := config.InitLog()
+	mockRepo := new(mocks.Repository)
+
+	criteria := &user.UserCriteria{}
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo.On("Count", mock.Anything, criteria).Return(3, nil).Once()
+		u := user.NewService(log, mockRepo)
+
+		count, err := u.Count(context.Background(), criteria)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, count)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockRepo.On("Count", mock.Anything, criteria).Return(0, errors.New("Unexpected database error")).Once()
+		u := user.NewService(log, mockRepo)
+
+		count, err := u.Count(context.Background(), criteria)
+
+		assert.Error(t, err)
+		assert.Equal(t, 0, count)
+		assert.EqualError(t, err, "Unexpected database error")
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServiceSearch(t *testing.T) {
+	log := config.InitLog()
+	mockRepo := new(mocks.Repository)
+	mockUser := &model.UserSearchModel{
+		UserModel: model.UserModel{
+			ID:        xid.New().String(),
+			Name:      "Momo",
+			Email:     "momo@mail.com",
+			Phone:     "085640123456",
+			Address:   "Indonesia",
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+		},
+		Rank: 0.6,
+	}
+
+	mockListUser := []*model.UserSearchModel{mockUser}
+
+	criteria := &user.UserCriteria{Query: "momo", Limit: 10}
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo.On("Search", mock.Anything, criteria).Return(mockListUser, nil).Once()
+		mockRepo.On("Count", mock.Anything, criteria).Return(1, nil).Once()
+		u := user.NewService(log, mockRepo)
+
+		users, count, err := u.Search(context.Background(), criteria)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockListUser, users)
+		assert.Equal(t, 1, count)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed-search", func(t *testing.T) {
+		mockRepo.On("Search", mock.Anything, criteria).Return(nil, errors.New("Unexpected database error")).Once()
+		u := user.NewService(log, mockRepo)
 
+		users, count, err := u.Search(context.Background(), criteria)
+
+		assert.Error(t, err)
+		assert.Nil(t, users)
+		assert.Equal(t, 0, count)
+		assert.EqualError(t, err, "Unexpected database error")
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed-count", func(t *testing.T) {
+		mockRepo.On("Search", mock.Anything, criteria).Return(mockListUser, nil).Once()
+		mockRepo.On("Count", mock.Anything, criteria).Return(0, errors.New("Unexpected database error")).Once()
 		u := user.NewService(log, mockRepo)
 
-		users, count, status, err := u.List(filter, filter, deletedNull, orderBy, model.UserSelectField)
+		users, count, err := u.Search(context.Background(), criteria)
 
 		assert.Error(t, err)
 		assert.Nil(t, users)
 		assert.Equal(t, 0, count)
-		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.EqualError(t, err, "Unexpected database error")
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServiceExport(t *testing.T) {
+	log := config.InitLog()
+	mockRepo := new(mocks.Repository)
+
+	criteria := &user.UserCriteria{}
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo.On("Export", mock.Anything, criteria, mock.Anything).Return(nil).Once()
+		u := user.NewService(log, mockRepo)
+
+		err := u.Export(context.Background(), criteria, func(*model.UserModel) error { return nil })
+
+		assert.NoError(t, err)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockRepo.On("Export", mock.Anything, criteria, mock.Anything).Return(errors.New("Unexpected database error")).Once()
+		u := user.NewService(log, mockRepo)
+
+		err := u.Export(context.Background(), criteria, func(*model.UserModel) error { return nil })
+
+		assert.EqualError(t, err, "Unexpected database error")
 
 		mockRepo.AssertExpectations(t)
 	})
@@ -266,41 +521,258 @@ func TestServiceUpdate(t *testing.T) {
 	reqUser := &form.UserForm{
 		Name:    "Momo",
 		Email:   "momo@mail.com",
-		Phone:   "085640",
+		Phone:   "085640123456",
 		Address: "Indonesia",
 	}
 
 	mockUser := &model.UserModel{
-		ID:      xid.New().String(),
-		Name:    reqUser.Name,
-		Email:   reqUser.Email,
-		Phone:   reqUser.Phone,
-		Address: reqUser.Address,
+		ID:       xid.New().String(),
+		Name:     reqUser.Name,
+		Email:    reqUser.Email,
+		Phone:    "+6285640123456",
+		PhoneRaw: reqUser.Phone,
+		Address:  reqUser.Address,
 	}
 
 	t.Run("success", func(t *testing.T) {
-		mockRepo.On("Update", mockUser).Return(mockUser, nil).Once()
+		mockRepo.On("Update", mock.Anything, mockUser).Return(mockUser, nil).Once()
 		u := user.NewService(log, mockRepo)
 
-		userRow, status, err := u.Update(reqUser, mockUser.ID)
+		userRow, err := u.Update(context.Background(), reqUser, mockUser.ID)
 
 		assert.NoError(t, err)
 		assert.NotNil(t, userRow)
-		assert.Equal(t, 0, status)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed-not-found", func(t *testing.T) {
+		mockRepo.On("Update", mock.Anything, mockUser).Return(nil, sql.ErrNoRows).Once()
+		u := user.NewService(log, mockRepo)
+
+		userRow, err := u.Update(context.Background(), reqUser, mockUser.ID)
+
+		assert.Nil(t, userRow)
+		assert.True(t, errors.Is(err, user.ErrNotFound))
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockRepo.On("Update", mock.Anything, mockUser).Return(nil, errors.New("Unexpected database error")).Once()
+		u := user.NewService(log, mockRepo)
+
+		userRow, err := u.Update(context.Background(), reqUser, mockUser.ID)
+
+		assert.Error(t, err)
+		assert.Nil(t, userRow)
+		assert.EqualError(t, err, "Unexpected database error")
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServicePatch(t *testing.T) {
+	log := config.InitLog()
+	mockRepo := new(mocks.Repository)
+
+	currentUser := &model.UserModel{
+		ID:       xid.New().String(),
+		Name:     "Momo",
+		Email:    "momo@mail.com",
+		Phone:    "+6285640123456",
+		PhoneRaw: "085640123456",
+		Address:  "Indonesia",
+	}
+
+	reqUser := &form.UserForm{
+		ID:      currentUser.ID,
+		Name:    "Baruno",
+		Email:   currentUser.Email,
+		Phone:   currentUser.Phone,
+		Address: "Japan",
+	}
+
+	mockUser := &model.UserModel{
+		ID:       currentUser.ID,
+		Name:     reqUser.Name,
+		Email:    reqUser.Email,
+		Phone:    currentUser.Phone,
+		PhoneRaw: currentUser.PhoneRaw,
+		Address:  reqUser.Address,
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo.On("Patch", mock.Anything, mockUser, []string{"name", "address"}).Return(mockUser, nil).Once()
+		u := user.NewService(log, mockRepo)
+
+		userRow, err := u.Patch(context.Background(), reqUser, currentUser)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockUser, userRow)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("success-unchanged", func(t *testing.T) {
+		unchanged := &form.UserForm{
+			ID:      currentUser.ID,
+			Name:    currentUser.Name,
+			Email:   currentUser.Email,
+			Phone:   currentUser.Phone,
+			Address: currentUser.Address,
+		}
+		u := user.NewService(log, mockRepo)
+
+		userRow, err := u.Patch(context.Background(), unchanged, currentUser)
+
+		assert.NoError(t, err)
+		assert.Equal(t, currentUser, userRow)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("success-phone", func(t *testing.T) {
+		reformatted := &form.UserForm{
+			ID:      currentUser.ID,
+			Name:    currentUser.Name,
+			Email:   currentUser.Email,
+			Phone:   "0856-4012-3456",
+			Address: currentUser.Address,
+		}
+		changed := *reformatted
+		changed.Phone = "+81 90 1234 5678"
+
+		phoneUser := *currentUser
+		phoneUser.Phone = "+819012345678"
+		phoneUser.PhoneRaw = changed.Phone
+
+		mockRepo.On("Patch", mock.Anything, &phoneUser, []string{"phone", "phone_raw"}).Return(&phoneUser, nil).Once()
+		u := user.NewService(log, mockRepo)
+
+		userRow, err := u.Patch(context.Background(), reformatted, currentUser)
+		assert.NoError(t, err)
+		assert.Equal(t, currentUser, userRow)
+
+		userRow, err = u.Patch(context.Background(), &changed, currentUser)
+		assert.NoError(t, err)
+		assert.Equal(t, &phoneUser, userRow)
 
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("failed", func(t *testing.T) {
-		mockRepo.On("Update", mockUser).Return(nil, errors.New("Unexpected database error")).Once()
+		mockRepo.On("Patch", mock.Anything, mockUser, []string{"name", "address"}).Return(nil, errors.New("Unexpected database error")).Once()
 		u := user.NewService(log, mockRepo)
 
-		userRow, status, err := u.Update(reqUser, mockUser.ID)
+		userRow, err := u.Patch(context.Background(), reqUser, currentUser)
 
 		assert.Error(t, err)
 		assert.Nil(t, userRow)
-		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.EqualError(t, err, "Unexpected database error")
 
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestServiceAtomic(t *testing.T) {
+	log := config.InitLog()
+	mockRepo := new(mocks.Repository)
+
+	withTx := func(ctx context.Context, fn func(user.Repository) error) error {
+		return fn(mockRepo)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		mockRepo.On("WithTx", mock.Anything, mock.Anything).Return(withTx).Once()
+		u := user.NewService(log, mockRepo)
+
+		called := false
+		err := u.Atomic(context.Background(), func(svc user.Service) error {
+			called = true
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, called)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		mockRepo.On("WithTx", mock.Anything, mock.Anything).Return(withTx).Once()
+		u := user.NewService(log, mockRepo)
+
+		errRollback := errors.New("rollback")
+		err := u.Atomic(context.Background(), func(svc user.Service) error {
+			return errRollback
+		})
+
+		assert.Equal(t, errRollback, err)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		mockRepo.On("WithTx", mock.Anything, mock.Anything).Return(errors.New("Unexpected database error")).Once()
+		u := user.NewService(log, mockRepo)
+
+		err := u.Atomic(context.Background(), func(svc user.Service) error {
+			return nil
+		})
+
+		assert.EqualError(t, err, "Unexpected database error")
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServiceEvents(t *testing.T) {
+	log := config.InitLog()
+	mockRepo := new(mocks.Repository)
+
+	withTx := func(ctx context.Context, fn func(user.Repository) error) error {
+		return fn(mockRepo)
+	}
+
+	created := &model.UserModel{ID: xid.New().String(), Name: "Momo"}
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*model.UserModel")).Return(created, nil)
+	mockRepo.On("WithTx", mock.Anything, mock.Anything).Return(withTx)
+
+	u := user.NewService(log, mockRepo)
+	_, events, cancel := u.Subscribe(0)
+	defer cancel()
+
+	_, err := u.Create(context.Background(), &form.UserForm{ID: created.ID, Name: "Momo", Email: "momo@mail.com"})
+	assert.NoError(t, err)
+
+	event := <-events
+	assert.Equal(t, user.EventUserCreated, event.Type)
+	assert.Equal(t, created, event.User)
+
+	t.Run("rollback", func(t *testing.T) {
+		err := u.Atomic(context.Background(), func(svc user.Service) error {
+			_, err := svc.Create(context.Background(), &form.UserForm{ID: created.ID, Name: "Momo", Email: "momo@mail.com"})
+			assert.NoError(t, err)
+			return errors.New("rollback")
+		})
+		assert.Error(t, err)
+		assert.Len(t, events, 0)
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		err := u.Atomic(context.Background(), func(svc user.Service) error {
+			_, err := svc.Create(context.Background(), &form.UserForm{ID: created.ID, Name: "Momo", Email: "momo@mail.com"})
+			assert.NoError(t, err)
+			assert.Len(t, events, 0)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+
+		replay, _, cancel := u.Subscribe(event.ID)
+		defer cancel()
+		assert.Len(t, replay, 1)
+		assert.Equal(t, event.ID+1, replay[0].ID)
+	})
+}
@@ -0,0 +1,99 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package user
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"modernc.org/sqlite"
+)
+
+const (
+	// sqliteSearchText represent the searched text of users on SQLite
+	sqliteSearchText = "name || ' ' || email || ' ' || address"
+	// sqliteOutboxPayload represent the JSON of stored user row written as outbox payload on SQLite, the timestamps
+	// are formatted like PostgreSQL does
+	sqliteOutboxPayload = "json_object('id', id, 'name', name, 'email', email, 'phone', phone, 'phone_raw', phone_raw, 'address', address, 'created_at', strftime('%Y-%m-%dT%H:%M:%S', created_at), 'updated_at', strftime('%Y-%m-%dT%H:%M:%S', updated_at))"
+	// sqliteTimeFormat represent the format of CURRENT_TIMESTAMP, the time arguments are written in it so they
+	// compare with the stored timestamps
+	sqliteTimeFormat = "2006-01-02 15:04:05"
+
+	// sqliteConstraintUnique and sqliteConstraintPrimaryKey represent the sqlite extended result code of violated
+	// unique index and primary key
+	sqliteConstraintUnique     = 2067
+	sqliteConstraintPrimaryKey = 1555
+)
+
+// sqliteDialect represent the SQL of repository on SQLite. SQLite has no full-text search without the FTS tables,
+// so the query is matched as case-insensitive substring and ranked by the weight of matched column like
+// PostgreSQL (name, email, then address). LIKE has no default escape and it's case-sensitive by the pragma
+// set by config.InitDB. SQLite compares the timestamps as text, so the arguments bound to time columns (the
+// RFC 3339 text of cursor included) are formatted as stored
+var sqliteDialect = dialect{
+	searchCondition: "instr(lower(" + sqliteSearchText + "), lower(:q)) > 0",
+	searchRank:      "(instr(lower(name), lower(:q)) > 0) * 3 + (instr(lower(email), lower(:q)) > 0) * 2 + (instr(lower(address), lower(:q)) > 0)",
+	searchHighlight: sqliteSearchText,
+	rank:            "rank",
+	like:            `%s LIKE %s ESCAPE '\'`,
+	timeArg:         sqliteTime,
+	returning:       true,
+	importUpdate:    sqliteImportUpdate,
+	outbox:          inOutbox(sqliteOutboxPayload),
+	storeError:      sqliteStoreError,
+}
+
+// NewSQLiteRepository will create an object that represent the Repository interface, DB is opened by
+// config.InitDB
+func NewSQLiteRepository(DB *sqlx.DB, timeouts Timeouts) Repository {
+	return &sqlRepository{DBRead: DB, DBWrite: DB, timeouts: timeouts, dialect: sqliteDialect}
+}
+
+// sqliteTime will format the time or RFC 3339 text as stored, any other value is kept
+func sqliteTime(arg interface{}) interface{} {
+	t, ok := arg.(time.Time)
+	if s, isText := arg.(string); isText {
+		parsed, err := time.Parse(time.RFC3339Nano, s)
+		t, ok = parsed, err == nil
+	}
+	if !ok {
+		return arg
+	}
+
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+// sqliteImportUpdate will update the existing users matching the email of batch and return their ids by
+// lowercased email. The columns of SQLite VALUES can't be named, they're column1 to column5
+func sqliteImportUpdate(ctx context.Context, tx dbExecutor, users []*model.UserModel) (map[string]string, error) {
+	values := []string{}
+	args := []interface{}{}
+	for _, user := range users {
+		values = append(values, "(?, ?, ?, ?, ?)")
+		args = append(args, user.Email, user.Name, user.Phone, user.PhoneRaw, user.Address)
+	}
+
+	query := fmt.Sprintf(`UPDATE users AS u SET name = v.column2, phone = v.column3, phone_raw = v.column4, address = v.column5, updated_at = CURRENT_TIMESTAMP FROM (VALUES %s) AS v WHERE lower(u.email) = lower(v.column1) AND u.deleted_at IS NULL RETURNING id, lower(email) AS email`, strings.Join(values, ", "))
+
+	return selectUpdated(ctx, tx, query, args...)
+}
+
+// sqliteStoreError will translate the sqlite error into domain error, so the service doesn't depend on the driver
+func sqliteStoreError(err error) error {
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) && (liteErr.Code() == sqliteConstraintUnique || liteErr.Code() == sqliteConstraintPrimaryKey) {
+		return conflictError(err, strings.Contains(liteErr.Error(), "'"+emailIndex+"'"))
+	}
+
+	return err
+}
@@ -0,0 +1,471 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+// Package usertest provides the conformance suite of user.Repository, every implementation runs it so
+// they match the users the same way
+package usertest
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Factory represent the constructor of the empty repository under test, close will release it
+type Factory func(t *testing.T) (repo user.Repository, close func())
+
+// TestRepository will run the conformance suite against the repositories of newRepository, every case
+// gets its own empty repository
+func TestRepository(t *testing.T, newRepository Factory) {
+	cases := []struct {
+		name string
+		fn   func(*testing.T, user.Repository)
+	}{
+		{"Create", testCreate},
+		{"GetByID", testGetByID},
+		{"Update", testUpdate},
+		{"Patch", testPatch},
+		{"SoftDelete", testSoftDelete},
+		{"Filter", testFilter},
+		{"Order", testOrder},
+		{"Pagination", testPagination},
+		{"Keyset", testKeyset},
+		{"Search", testSearch},
+		{"Export", testExport},
+		{"Import", testImport},
+		{"WithTx", testWithTx},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			repo, close := newRepository(t)
+			defer close()
+
+			c.fn(t, repo)
+		})
+	}
+}
+
+// newUser will create the user request with new id
+func newUser(name, email, address string) *model.UserModel {
+	return &model.UserModel{
+		ID:       xid.New().String(),
+		Name:     name,
+		Email:    email,
+		Phone:    "+6285640123456",
+		PhoneRaw: "0856-4012-3456",
+		Address:  address,
+	}
+}
+
+// seed will create Momo, Gendhis and Baruno and return the stored users by name
+func seed(t *testing.T, repo user.Repository) map[string]*model.UserModel {
+	users := map[string]*model.UserModel{}
+	for _, u := range []*model.UserModel{
+		newUser("Momo", "momo@mail.com", "Indonesia"),
+		newUser("Gendhis", "gendhis@acme.com", "Japan"),
+		newUser("Baruno", "baruno@acme.com", "Indonesia"),
+	} {
+		stored, err := repo.Create(context.Background(), u)
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when creating %s", err, u.Name)
+		}
+		users[u.Name] = stored
+	}
+
+	return users
+}
+
+// names will return the names of users in order
+func names(users []*model.UserModel) []string {
+	result := []string{}
+	for _, u := range users {
+		result = append(result, u.Name)
+	}
+
+	return result
+}
+
+// count will count the users matching criteria
+func count(t *testing.T, repo user.Repository, criteria *user.UserCriteria) int {
+	n, err := repo.Count(context.Background(), criteria)
+	assert.NoError(t, err)
+
+	return n
+}
+
+func testCreate(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	req := newUser("Momo", "Momo@Mail.com", "Indonesia")
+
+	stored, err := repo.Create(ctx, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, req.ID, stored.ID)
+	assert.Equal(t, "Momo@Mail.com", stored.Email)
+	assert.Equal(t, req.PhoneRaw, stored.PhoneRaw)
+	assert.False(t, stored.CreatedAt.IsZero())
+	assert.False(t, stored.UpdatedAt.IsZero())
+
+	_, err = repo.Create(ctx, newUser("Momo", "MOMO@mail.com", ""))
+	assert.True(t, errors.Is(err, user.ErrConflict))
+	assert.True(t, errors.Is(err, user.ErrEmailTaken))
+
+	duplicate := newUser("Gendhis", "gendhis@acme.com", "")
+	duplicate.ID = req.ID
+	_, err = repo.Create(ctx, duplicate)
+	assert.True(t, errors.Is(err, user.ErrConflict))
+
+	assert.Equal(t, 1, count(t, repo, &user.UserCriteria{}))
+}
+
+func testGetByID(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	users := seed(t, repo)
+
+	found, err := repo.GetByID(ctx, users["Gendhis"].ID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Gendhis", found.Name)
+	assert.Equal(t, "gendhis@acme.com", found.Email)
+	assert.True(t, users["Gendhis"].CreatedAt.Equal(found.CreatedAt))
+
+	found, err = repo.GetByID(ctx, users["Gendhis"].ID, "id,name")
+	assert.NoError(t, err)
+	assert.Equal(t, "Gendhis", found.Name)
+	assert.Empty(t, found.Email)
+
+	_, err = repo.GetByID(ctx, xid.New().String(), "")
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+}
+
+func testUpdate(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	users := seed(t, repo)
+
+	req := *users["Momo"]
+	req.Name = "Momo Update"
+	req.Address = "Japan"
+	updated, err := repo.Update(ctx, &req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Momo Update", updated.Name)
+	assert.Equal(t, "Japan", updated.Address)
+	assert.True(t, users["Momo"].CreatedAt.Equal(updated.CreatedAt))
+	assert.False(t, updated.UpdatedAt.Before(users["Momo"].UpdatedAt))
+
+	found, err := repo.GetByID(ctx, req.ID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Momo Update", found.Name)
+
+	req.Email = "GENDHIS@acme.com"
+	_, err = repo.Update(ctx, &req)
+	assert.True(t, errors.Is(err, user.ErrEmailTaken))
+
+	_, err = repo.Update(ctx, newUser("Nobody", "nobody@mail.com", ""))
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+}
+
+func testPatch(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	users := seed(t, repo)
+
+	patched, err := repo.Patch(ctx, &model.UserModel{ID: users["Momo"].ID, Name: "Momo Patch"}, []string{"name"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Momo Patch", patched.Name)
+	assert.Equal(t, "momo@mail.com", patched.Email)
+	assert.Equal(t, "Indonesia", patched.Address)
+
+	_, err = repo.Patch(ctx, &model.UserModel{ID: users["Momo"].ID, Email: "Baruno@acme.com"}, []string{"email"})
+	assert.True(t, errors.Is(err, user.ErrEmailTaken))
+
+	_, err = repo.Patch(ctx, &model.UserModel{ID: xid.New().String(), Name: "Nobody"}, []string{"name"})
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+}
+
+func testSoftDelete(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	users := seed(t, repo)
+	id := users["Momo"].ID
+
+	assert.NoError(t, repo.Delete(ctx, id))
+
+	_, err := repo.GetByID(ctx, id, "")
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+	assert.True(t, errors.Is(repo.Delete(ctx, id), sql.ErrNoRows))
+	assert.True(t, errors.Is(repo.Delete(ctx, xid.New().String()), sql.ErrNoRows))
+
+	req := *users["Momo"]
+	req.Name = "Momo Update"
+	_, err = repo.Update(ctx, &req)
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+
+	found, err := repo.Get(ctx, &user.UserCriteria{Sort: user.ParseSort("name")})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Baruno", "Gendhis"}, names(found))
+	assert.Equal(t, 2, count(t, repo, &user.UserCriteria{}))
+
+	// the email of deleted user can be taken again
+	_, err = repo.Create(ctx, newUser("Momo", "momo@mail.com", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count(t, repo, &user.UserCriteria{}))
+}
+
+func testFilter(t *testing.T, repo user.Repository) {
+	seed(t, repo)
+	before := time.Now().UTC().Add(-time.Hour)
+
+	cases := []struct {
+		name   string
+		filter *user.Filter
+		want   []string
+	}{
+		{"eq", &user.Filter{Field: "name", Op: user.OpEq, Value: "Momo"}, []string{"Momo"}},
+		{"ne", &user.Filter{Field: "name", Op: user.OpNe, Value: "Momo"}, []string{"Baruno", "Gendhis"}},
+		{"in", &user.Filter{Field: "name", Op: user.OpIn, Value: []string{"Momo", "Baruno"}}, []string{"Baruno", "Momo"}},
+		{"contains", &user.Filter{Field: "name", Op: user.OpContains, Value: "end"}, []string{"Gendhis"}},
+		{"contains case-sensitive", &user.Filter{Field: "name", Op: user.OpContains, Value: "END"}, []string{}},
+		{"contains wildcard", &user.Filter{Field: "name", Op: user.OpContains, Value: "%"}, []string{}},
+		{"startswith", &user.Filter{Field: "name", Op: user.OpStartsWith, Value: "Mo"}, []string{"Momo"}},
+		{"endswith", &user.Filter{Field: "email", Op: user.OpEndsWith, Value: "@acme.com"}, []string{"Baruno", "Gendhis"}},
+		{"isnull", &user.Filter{Field: "phone", Op: user.OpIsNull, Value: true}, []string{}},
+		{"time text", &user.Filter{Field: "created_at", Op: user.OpGte, Value: "2000-01-01"}, []string{"Baruno", "Gendhis", "Momo"}},
+		{"time", &user.Filter{Field: "created_at", Op: user.OpLt, Value: before}, []string{}},
+		{"or", &user.Filter{Or: []*user.Filter{
+			{Field: "name", Op: user.OpEq, Value: "Momo"},
+			{Field: "address", Op: user.OpEq, Value: "Japan"},
+		}}, []string{"Gendhis", "Momo"}},
+		{"not", &user.Filter{And: []*user.Filter{
+			{Field: "address", Op: user.OpEq, Value: "Indonesia"},
+		}, Not: &user.Filter{Field: "name", Op: user.OpEq, Value: "Momo"}}, []string{"Baruno"}},
+	}
+
+	for _, c := range cases {
+		criteria := &user.UserCriteria{Filter: c.filter, Sort: user.ParseSort("name")}
+		found, err := repo.Get(context.Background(), criteria)
+
+		assert.NoError(t, err, c.name)
+		assert.Equal(t, c.want, names(found), c.name)
+		assert.Equal(t, len(c.want), count(t, repo, criteria), c.name)
+	}
+
+	_, err := repo.Get(context.Background(), (&user.UserCriteria{}).Where("password", user.OpEq, "secret"))
+	assert.True(t, errors.Is(err, user.ErrInvalidFilter))
+
+	// the text looking like a timestamp is only a timestamp for the time columns
+	_, err = repo.Create(context.Background(), newUser("Timestamp", "timestamp@mail.com", "2020-04-05T09:00:00Z"))
+	assert.NoError(t, err)
+
+	found, err := repo.Get(context.Background(), (&user.UserCriteria{}).Where("address", user.OpEq, "2020-04-05T09:00:00Z"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Timestamp"}, names(found))
+
+	found, err = repo.Get(context.Background(), (&user.UserCriteria{}).Where("address", user.OpIn, []string{"2020-04-05T09:00:00Z"}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Timestamp"}, names(found))
+}
+
+func testOrder(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	seed(t, repo)
+
+	found, err := repo.Get(ctx, &user.UserCriteria{Sort: user.ParseSort("name")})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Baruno", "Gendhis", "Momo"}, names(found))
+
+	found, err = repo.Get(ctx, &user.UserCriteria{Sort: user.ParseSort("-name")})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Momo", "Gendhis", "Baruno"}, names(found))
+
+	found, err = repo.Get(ctx, &user.UserCriteria{Sort: user.ParseSort("name"), Fields: []string{"id", "name"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Baruno", found[0].Name)
+	assert.Empty(t, found[0].Email)
+}
+
+func testPagination(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	seed(t, repo)
+
+	criteria := &user.UserCriteria{Sort: user.ParseSort("name"), Limit: 2}
+	found, err := repo.Get(ctx, criteria)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Baruno", "Gendhis"}, names(found))
+	assert.Equal(t, 3, count(t, repo, criteria))
+
+	criteria.Offset = 2
+	found, err = repo.Get(ctx, criteria)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Momo"}, names(found))
+
+	criteria.Offset = 4
+	found, err = repo.Get(ctx, criteria)
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+func testKeyset(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	seed(t, repo)
+
+	// the users can be created within the same timestamp, so the pages rely on id as tie-breaker. The cursor
+	// goes through its encoding like the one of client
+	keyset := user.NewKeyset(nil)
+	seen := map[string]bool{}
+	var cursor *user.Cursor
+	for page := 0; page < 3; page++ {
+		found, err := repo.Get(ctx, &user.UserCriteria{Keyset: keyset, Cursor: cursor, Limit: 2})
+		assert.NoError(t, err)
+		if len(found) == 0 {
+			break
+		}
+		for _, u := range found {
+			assert.False(t, seen[u.ID], "user %s is paged twice", u.Name)
+			seen[u.ID] = true
+		}
+
+		cursor, err = user.DecodeCursor(keyset.Cursor(found[len(found)-1], false).Encode())
+		assert.NoError(t, err)
+	}
+	assert.Len(t, seen, 3)
+
+	keyset = user.NewKeyset(user.ParseSort("name"))
+	found, err := repo.Get(ctx, &user.UserCriteria{Keyset: keyset, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Baruno", "Gendhis"}, names(found))
+
+	cursor, _ = user.DecodeCursor(keyset.Cursor(found[1], false).Encode())
+	next, err := repo.Get(ctx, &user.UserCriteria{Keyset: keyset, Cursor: cursor, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Momo"}, names(next))
+
+	// backward the users before the cursor come nearest first
+	cursor, _ = user.DecodeCursor(keyset.Cursor(next[0], true).Encode())
+	prev, err := repo.Get(ctx, &user.UserCriteria{Keyset: keyset, Cursor: cursor, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Gendhis", "Baruno"}, names(prev))
+
+	_, err = repo.Get(ctx, &user.UserCriteria{Cursor: cursor})
+	assert.True(t, errors.Is(err, user.ErrInvalidCursor))
+}
+
+func testSearch(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	seed(t, repo)
+
+	found, err := repo.Search(ctx, &user.UserCriteria{Query: "gendhis", Highlight: true})
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, "Gendhis", found[0].Name)
+	assert.True(t, found[0].Rank > 0)
+	assert.NotEmpty(t, found[0].Highlight)
+
+	criteria := &user.UserCriteria{Query: "indonesia", Sort: user.ParseSort("name")}
+	found, err = repo.Search(ctx, criteria)
+	assert.NoError(t, err)
+	assert.Len(t, found, 2)
+	assert.Equal(t, "Baruno", found[0].Name)
+	assert.Equal(t, 2, count(t, repo, criteria))
+
+	found, err = repo.Search(ctx, &user.UserCriteria{Query: "nobody"})
+	assert.NoError(t, err)
+	assert.Empty(t, found)
+
+	// without query every user is found unranked
+	found, err = repo.Search(ctx, &user.UserCriteria{Highlight: true})
+	assert.NoError(t, err)
+	assert.Len(t, found, 3)
+	assert.Zero(t, found[0].Rank)
+}
+
+func testExport(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	users := seed(t, repo)
+	assert.NoError(t, repo.Delete(ctx, users["Gendhis"].ID))
+
+	exported := []*model.UserModel{}
+	err := repo.Export(ctx, &user.UserCriteria{Sort: user.ParseSort("name")}, func(u *model.UserModel) error {
+		exported = append(exported, u)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Baruno", "Momo"}, names(exported))
+
+	stop := errors.New("stop")
+	err = repo.Export(ctx, &user.UserCriteria{}, func(u *model.UserModel) error {
+		return stop
+	})
+	assert.True(t, errors.Is(err, stop))
+}
+
+func testImport(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	users := seed(t, repo)
+
+	updated, err := repo.Import(ctx, []*model.UserModel{
+		newUser("Momo Import", "MOMO@mail.com", "Japan"),
+		newUser("Sekar", "sekar@mail.com", "Indonesia"),
+	}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"momo@mail.com": true}, updated)
+	assert.Equal(t, 4, count(t, repo, &user.UserCriteria{}))
+
+	found, err := repo.GetByID(ctx, users["Momo"].ID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Momo Import", found.Name)
+	assert.Equal(t, "momo@mail.com", found.Email)
+
+	// the import is all or nothing
+	_, err = repo.Import(ctx, []*model.UserModel{
+		newUser("Ayu", "ayu@mail.com", ""),
+		newUser("Gendhis", "Gendhis@acme.com", ""),
+	}, false)
+	assert.True(t, errors.Is(err, user.ErrEmailTaken))
+	assert.Equal(t, 4, count(t, repo, &user.UserCriteria{}))
+
+	// the registered emails are found case-insensitively, the deleted users aren't registered
+	assert.NoError(t, repo.Delete(ctx, users["Baruno"].ID))
+	existing, err := repo.ExistingEmails(ctx, []string{"GENDHIS@acme.com", "baruno@acme.com", "ayu@mail.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"gendhis@acme.com": true}, existing)
+}
+
+func testWithTx(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	rollback := errors.New("rollback")
+
+	err := repo.WithTx(ctx, func(r user.Repository) error {
+		stored, err := r.Create(ctx, newUser("Momo", "momo@mail.com", ""))
+		if err != nil {
+			return err
+		}
+
+		// the write is read inside the transaction
+		if _, err := r.GetByID(ctx, stored.ID, ""); err != nil {
+			return err
+		}
+
+		return rollback
+	})
+	assert.True(t, errors.Is(err, rollback))
+	assert.Equal(t, 0, count(t, repo, &user.UserCriteria{}))
+
+	err = repo.WithTx(ctx, func(r user.Repository) error {
+		_, err := r.Create(ctx, newUser("Momo", "momo@mail.com", ""))
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count(t, repo, &user.UserCriteria{}))
+}
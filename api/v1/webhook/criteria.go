@@ -0,0 +1,58 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package webhook
+
+import (
+	"strings"
+)
+
+// WebhookCriteria represent the typed query of webhooks, newest first. The deleted webhooks are never matched
+type WebhookCriteria struct {
+	// Limit represent the page size, zero is every webhook
+	Limit  int
+	Offset int
+}
+
+// DeliveryCriteria represent the typed query of webhook deliveries, newest first
+type DeliveryCriteria struct {
+	// WebhookID represent the webhook of deliveries, every webhook when it's empty
+	WebhookID string
+	// Status represent the delivery status, every status when it's empty
+	Status string
+	// Limit represent the page size, zero is every delivery
+	Limit  int
+	Offset int
+}
+
+// where will compile the conditions of criteria into WHERE clause with ? placeholders
+func (c *DeliveryCriteria) where() (string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+	if len(c.WebhookID) > 0 {
+		conditions = append(conditions, "webhook_id = ?")
+		args = append(args, c.WebhookID)
+	}
+	if len(c.Status) > 0 {
+		conditions = append(conditions, "status = ?")
+		args = append(args, c.Status)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// page will compile the limit and offset into LIMIT clause with ? placeholders, empty when limit is zero
+func page(limit, offset int) (string, []interface{}) {
+	if limit < 1 {
+		return "", nil
+	}
+
+	return " LIMIT ? OFFSET ?", []interface{}{limit, offset}
+}
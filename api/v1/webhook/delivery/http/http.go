@@ -0,0 +1,268 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package http
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/webhook"
+	cons "github.com/moemoe89/go-graphql-gendhis/constant"
+
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/moemoe89/go-helpers"
+	"github.com/moemoe89/go-localization"
+	"github.com/sirupsen/logrus"
+)
+
+type webhookCtrl struct {
+	lang *language.Config
+	log  *logrus.Entry
+	svc  webhook.Service
+}
+
+// NewWebhookCtrl will create an object that represent the webhookCtrl struct
+func NewWebhookCtrl(lang *language.Config, log *logrus.Entry, svc webhook.Service) *webhookCtrl {
+	return &webhookCtrl{lang, log, svc}
+}
+
+//
+// @Summary Webhook Create
+// @Description subscribe to user events, the secret signing the payloads is only returned here
+// @Accept  json
+// @Produce  json
+// @Produce  application/problem+json
+// @Param Accept-Language header string false "language message response"
+// @Param body body form.WebhookForm true "Request Payload"
+// @Success 201 {object} model.WebhookResponse
+// @Failure 400 {object} model.GenericResponse
+// @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
+// @Router /webhooks [post]
+func (w *webhookCtrl) Create(c *gin.Context) {
+	l := c.Request.Header.Get("Accept-Language")
+	resp := &model.WebhookResponse{}
+
+	req := &form.WebhookForm{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		w.log.Errorf("can't get json body: %s", err.Error())
+		w.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, "Oops! Something went wrong with your request")
+		return
+	}
+
+	errs := req.Validate()
+	if len(errs) > 0 {
+		w.validationResponse(c, errs)
+		return
+	}
+
+	hook, err := w.svc.Create(req)
+	if err != nil {
+		w.serviceError(c, err)
+		return
+	}
+
+	resp.GenericResponse = model.NewGenericResponse(http.StatusCreated, cons.OK, []string{w.lang.Lookup(l, "Created data successful")})
+	resp.Data = hook
+	c.JSON(http.StatusCreated, resp)
+}
+
+//
+// @Summary Webhook List
+// @Description get list of webhook subscriptions
+// @Produce  json
+// @Produce  application/problem+json
+// @Param Accept-Language header string false "language message response"
+// @Param per_page query int false "Limit per page"
+// @Param page query int false "Page number"
+// @Success 200 {object} model.WebhooksResponse
+// @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
+// @Router /webhooks [get]
+func (w *webhookCtrl) List(c *gin.Context) {
+	l := c.Request.Header.Get("Accept-Language")
+	resp := &model.WebhooksResponse{}
+	pagination := &model.WebhookPaginationResponse{}
+
+	offset, perPage, showPage, err := helpers.PaginationSetter(c.Query("per_page"), c.Query("page"))
+	if err != nil {
+		w.errorResponse(c, http.StatusInternalServerError, model.CodeInternalError, err.Error())
+		return
+	}
+
+	hooks, count, err := w.svc.List(&webhook.WebhookCriteria{Limit: perPage, Offset: offset})
+	if err != nil {
+		w.serviceError(c, err)
+		return
+	}
+
+	totalPage := int(math.Ceil(float64(count) / float64(perPage)))
+	pagination.PaginationResponse = model.NewPaginationResponse(showPage, perPage, totalPage, count)
+	pagination.List = hooks
+
+	resp.GenericResponse = model.NewGenericResponse(http.StatusOK, cons.OK, []string{w.lang.Lookup(l, "OK")})
+	resp.Data = pagination
+	c.JSON(http.StatusOK, resp)
+}
+
+//
+// @Summary Webhook Detail
+// @Description get webhook subscription by ID
+// @Produce  json
+// @Produce  application/problem+json
+// @Param Accept-Language header string false "language message response"
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} model.WebhookResponse
+// @Failure 404 {object} model.GenericResponse
+// @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
+// @Router /webhooks/{id} [get]
+func (w *webhookCtrl) Detail(c *gin.Context) {
+	l := c.Request.Header.Get("Accept-Language")
+	resp := &model.WebhookResponse{}
+
+	hook, err := w.svc.Detail(c.Param("id"))
+	if err != nil {
+		w.serviceError(c, err)
+		return
+	}
+
+	resp.GenericResponse = model.NewGenericResponse(http.StatusOK, cons.OK, []string{w.lang.Lookup(l, "OK")})
+	resp.Data = hook
+	c.JSON(http.StatusOK, resp)
+}
+
+//
+// @Summary Webhook Update
+// @Description replace webhook subscription by ID, the secret is kept when it's empty
+// @Accept  json
+// @Produce  json
+// @Produce  application/problem+json
+// @Param Accept-Language header string false "language message response"
+// @Param id path string true "Webhook ID"
+// @Param body body form.WebhookForm true "Request Payload"
+// @Success 200 {object} model.WebhookResponse
+// @Failure 400 {object} model.GenericResponse
+// @Failure 404 {object} model.GenericResponse
+// @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
+// @Router /webhooks/{id} [put]
+func (w *webhookCtrl) Update(c *gin.Context) {
+	l := c.Request.Header.Get("Accept-Language")
+	resp := &model.WebhookResponse{}
+
+	req := &form.WebhookForm{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		w.log.Errorf("can't get json body: %s", err.Error())
+		w.errorResponse(c, http.StatusBadRequest, model.CodeBadRequest, "Oops! Something went wrong with your request")
+		return
+	}
+
+	errs := req.Validate()
+	if len(errs) > 0 {
+		w.validationResponse(c, errs)
+		return
+	}
+
+	hook, err := w.svc.Update(req, c.Param("id"))
+	if err != nil {
+		w.serviceError(c, err)
+		return
+	}
+
+	resp.GenericResponse = model.NewGenericResponse(http.StatusOK, cons.OK, []string{w.lang.Lookup(l, "Updated data successful")})
+	resp.Data = hook
+	c.JSON(http.StatusOK, resp)
+}
+
+//
+// @Summary Webhook Delete
+// @Description unsubscribe webhook by ID, its delivery log is kept
+// @Produce  json
+// @Produce  application/problem+json
+// @Param Accept-Language header string false "language message response"
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} model.GenericResponse
+// @Failure 404 {object} model.GenericResponse
+// @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
+// @Router /webhooks/{id} [delete]
+func (w *webhookCtrl) Delete(c *gin.Context) {
+	l := c.Request.Header.Get("Accept-Language")
+
+	if err := w.svc.Delete(c.Param("id")); err != nil {
+		w.serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.NewGenericResponse(http.StatusOK, cons.OK, []string{w.lang.Lookup(l, "Deleted data successful")}))
+}
+
+//
+// @Summary Webhook Deliveries
+// @Description get the delivery log of webhook, newest first
+// @Produce  json
+// @Produce  application/problem+json
+// @Param Accept-Language header string false "language message response"
+// @Param id path string true "Webhook ID"
+// @Param status query string false "Delivery status, pending, succeeded or dead"
+// @Param per_page query int false "Limit per page"
+// @Param page query int false "Page number"
+// @Success 200 {object} model.WebhookDeliveriesResponse
+// @Failure 400 {object} model.GenericResponse
+// @Failure 404 {object} model.GenericResponse
+// @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
+// @Router /webhooks/{id}/deliveries [get]
+func (w *webhookCtrl) Deliveries(c *gin.Context) {
+	w.deliveries(c, c.Param("id"), c.Query("status"))
+}
+
+//
+// @Summary Webhook Dead Letters
+// @Description get the deliveries of every webhook which failed all attempts, newest first
+// @Produce  json
+// @Produce  application/problem+json
+// @Param Accept-Language header string false "language message response"
+// @Param per_page query int false "Limit per page"
+// @Param page query int false "Page number"
+// @Success 200 {object} model.WebhookDeliveriesResponse
+// @Failure 500 {object} model.GenericResponse
+// @Failure 503 {object} model.GenericResponse
+// @Router /webhooks/dead-letters [get]
+func (w *webhookCtrl) DeadLetters(c *gin.Context) {
+	w.deliveries(c, "", model.DeliveryDead)
+}
+
+// deliveries will write the page of delivery log
+func (w *webhookCtrl) deliveries(c *gin.Context, webhookID, deliveryStatus string) {
+	l := c.Request.Header.Get("Accept-Language")
+	resp := &model.WebhookDeliveriesResponse{}
+	pagination := &model.WebhookDeliveryPaginationResponse{}
+
+	offset, perPage, showPage, err := helpers.PaginationSetter(c.Query("per_page"), c.Query("page"))
+	if err != nil {
+		w.errorResponse(c, http.StatusInternalServerError, model.CodeInternalError, err.Error())
+		return
+	}
+
+	deliveries, count, err := w.svc.Deliveries(&webhook.DeliveryCriteria{WebhookID: webhookID, Status: deliveryStatus, Limit: perPage, Offset: offset})
+	if err != nil {
+		w.serviceError(c, err)
+		return
+	}
+
+	totalPage := int(math.Ceil(float64(count) / float64(perPage)))
+	pagination.PaginationResponse = model.NewPaginationResponse(showPage, perPage, totalPage, count)
+	pagination.List = deliveries
+
+	resp.GenericResponse = model.NewGenericResponse(http.StatusOK, cons.OK, []string{w.lang.Lookup(l, "OK")})
+	resp.Data = pagination
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,188 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package http_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/webhook"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/webhook/mocks"
+	"github.com/moemoe89/go-graphql-gendhis/config"
+	"github.com/moemoe89/go-graphql-gendhis/routers"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDeliveryCreate(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	hook := &model.WebhookModel{ID: xid.New().String(), URL: "https://crm.example.com/hooks", Secret: "0123456789abcdef"}
+
+	mockService := new(mocks.Service)
+	mockService.On("Create", &form.WebhookForm{URL: hook.URL, Events: []string{"user.created"}}).Return(hook, nil)
+
+	router := routers.GetRouter(lang, log, nil, mockService)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/webhooks", strings.NewReader(`{"url":"https://crm.example.com/hooks","events":["user.created"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	resp := &model.WebhookResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, hook.Secret, resp.Data.Secret)
+}
+
+func TestDeliveryCreateFailValidation(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	mockService := new(mocks.Service)
+
+	router := routers.GetRouter(lang, log, nil, mockService)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api/v1/webhooks", strings.NewReader(`{"url":"https://crm.example.com/hooks","events":["user.merged"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", model.ProblemJSONType)
+	router.ServeHTTP(w, req)
+
+	resp := &model.ProblemResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, model.CodeValidationFailed, resp.Code)
+	assert.Equal(t, "Unknown event user.merged: must be user.created, user.updated or user.deleted", resp.Detail)
+	mockService.AssertNotCalled(t, "Create", mock.Anything)
+}
+
+func TestDeliveryDetailFail(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	id := xid.New().String()
+
+	mockService := new(mocks.Service)
+	mockService.On("Detail", id).Return(nil, webhook.NewError(webhook.ErrNotFound, errors.New("sql: no rows in result set")))
+
+	router := routers.GetRouter(lang, log, nil, mockService)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/webhooks/"+id, nil)
+	req.Header.Set("Accept", model.ProblemJSONType)
+	router.ServeHTTP(w, req)
+
+	resp := &model.ProblemResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, model.CodeWebhookNotFound, resp.Code)
+	assert.Equal(t, "Webhook not found", resp.Detail)
+}
+
+func TestDeliveryDeliveriesFail(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	id := xid.New().String()
+
+	mockService := new(mocks.Service)
+	mockService.On("Deliveries", &webhook.DeliveryCriteria{WebhookID: id, Status: "failed", Limit: 10}).Return(nil, 0, &webhook.ValidationError{Fields: form.ValidationErrors{{
+		Field:   "status",
+		Rule:    form.RuleOneOf,
+		Message: "Invalid parameter status: must be pending, succeeded or dead",
+	}}})
+	mockService.On("Deliveries", &webhook.DeliveryCriteria{WebhookID: id, Limit: 10}).Return(nil, 0, webhook.NewError(webhook.ErrUnavailable, context.DeadlineExceeded))
+
+	router := routers.GetRouter(lang, log, nil, mockService)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/webhooks/"+id+"/deliveries?status=failed", nil)
+	req.Header.Set("Accept", model.ProblemJSONType)
+	router.ServeHTTP(w, req)
+
+	resp := &model.ProblemResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, model.CodeValidationFailed, resp.Code)
+	assert.Equal(t, "status", resp.Errors[0].Field)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/api/v1/webhooks/"+id+"/deliveries", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestDeliveryDeliveries(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	id := xid.New().String()
+
+	deliveries := []*model.WebhookDeliveryModel{{ID: xid.New().String(), WebhookID: id, Payload: json.RawMessage(`{}`)}}
+
+	mockService := new(mocks.Service)
+	mockService.On("Deliveries", &webhook.DeliveryCriteria{WebhookID: id, Status: model.DeliverySucceeded, Limit: 5, Offset: 5}).Return(deliveries, 6, nil)
+
+	router := routers.GetRouter(lang, log, nil, mockService)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/webhooks/"+id+"/deliveries?status=succeeded&per_page=5&page=2", nil)
+	router.ServeHTTP(w, req)
+
+	resp := &model.WebhookDeliveriesResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), resp))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, resp.Data.TotalPage)
+	assert.Len(t, resp.Data.List, 1)
+}
+
+func TestDeliveryDeadLetters(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+
+	mockService := new(mocks.Service)
+	mockService.On("Deliveries", &webhook.DeliveryCriteria{Status: model.DeliveryDead, Limit: 10}).Return([]*model.WebhookDeliveryModel{}, 0, nil)
+
+	router := routers.GetRouter(lang, log, nil, mockService)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/webhooks/dead-letters", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeliveryDelete(t *testing.T) {
+	lang, _ := config.InitLang()
+	log := config.InitLog()
+	id := xid.New().String()
+
+	mockService := new(mocks.Service)
+	mockService.On("Delete", id).Return(nil)
+
+	router := routers.GetRouter(lang, log, nil, mockService)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api/v1/webhooks/"+id, nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
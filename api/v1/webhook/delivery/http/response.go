@@ -0,0 +1,87 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package http
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/webhook"
+	cons "github.com/moemoe89/go-graphql-gendhis/constant"
+
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serviceError will write the response of domain error returned by service
+func (w *webhookCtrl) serviceError(c *gin.Context, err error) {
+	var validationErr *webhook.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		w.validationResponse(c, validationErr.Fields)
+	case errors.Is(err, webhook.ErrValidation):
+		w.errorResponse(c, http.StatusBadRequest, model.CodeValidationFailed, webhook.ErrValidation.Error())
+	case errors.Is(err, webhook.ErrNotFound):
+		w.errorResponse(c, http.StatusNotFound, model.CodeWebhookNotFound, webhook.ErrNotFound.Error())
+	case errors.Is(err, webhook.ErrUnavailable):
+		w.errorResponse(c, http.StatusServiceUnavailable, model.CodeServiceUnavailable, webhook.ErrUnavailable.Error())
+	default:
+		w.errorResponse(c, http.StatusInternalServerError, model.CodeInternalError, "Oops! Something went wrong. Please try again later")
+	}
+}
+
+// errorResponse will write the localized error response from the message keys
+func (w *webhookCtrl) errorResponse(c *gin.Context, status int, code string, messages ...string) {
+	l := c.Request.Header.Get("Accept-Language")
+
+	messagesLocale := []string{}
+	for _, m := range messages {
+		messagesLocale = append(messagesLocale, w.lang.Lookup(l, m))
+	}
+
+	w.writeError(c, status, code, messagesLocale, nil)
+}
+
+// validationResponse will write the localized field-level validation problems
+func (w *webhookCtrl) validationResponse(c *gin.Context, errs form.ValidationErrors) {
+	l := c.Request.Header.Get("Accept-Language")
+
+	messagesLocale := []string{}
+	details := []*model.ErrorDetail{}
+	for _, e := range errs {
+		detail := e.Localize(w.lang, l)
+		messagesLocale = append(messagesLocale, detail)
+		details = append(details, &model.ErrorDetail{
+			Field:  e.Field,
+			Rule:   e.Rule,
+			Params: e.Params,
+			Detail: detail,
+		})
+	}
+
+	w.writeError(c, http.StatusBadRequest, model.CodeValidationFailed, messagesLocale, details)
+}
+
+// writeError will use problem+json when requested by Accept header and the legacy
+// GenericResponse envelope otherwise
+func (w *webhookCtrl) writeError(c *gin.Context, status int, code string, messages []string, details []*model.ErrorDetail) {
+	if c.NegotiateFormat(gin.MIMEJSON, model.ProblemJSONType) != model.ProblemJSONType {
+		resp := model.NewGenericResponse(status, cons.ERR, messages)
+		resp.Errors = details
+		c.JSON(status, resp)
+		return
+	}
+
+	detail := ""
+	if len(messages) > 0 {
+		detail = messages[0]
+	}
+
+	c.Header("Content-Type", model.ProblemJSONType)
+	c.JSON(status, model.NewProblemResponse(status, code, detail, c.Request.URL.Path, details))
+}
@@ -0,0 +1,90 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package webhook
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+)
+
+var (
+	// ErrNotFound represent the error of missing or deleted webhook
+	ErrNotFound = errors.New("Webhook not found")
+	// ErrValidation represent the error of invalid webhook data, the fields are carried by ValidationError
+	ErrValidation = errors.New("Invalid webhook data")
+	// ErrUnavailable represent the error of storage being unreachable or too slow, the call can be retried
+	ErrUnavailable = errors.New("Service is unavailable. Please try again later")
+)
+
+// Error represent the domain error of Kind caused by Err, errors.Is matches the Kind and the
+// chain of Err
+type Error struct {
+	Kind error
+	Err  error
+}
+
+// NewError will create an object that represent the Error struct
+func NewError(kind, err error) *Error {
+	return &Error{Kind: kind, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Kind.Error()
+	}
+
+	return e.Kind.Error() + ": " + e.Err.Error()
+}
+
+// Is will match the kind of error
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+// Unwrap will return the cause of error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError represent the ErrValidation carrying the field-level problems
+type ValidationError struct {
+	Fields form.ValidationErrors
+}
+
+func (e *ValidationError) Error() string {
+	return ErrValidation.Error() + ": " + e.Fields.Error()
+}
+
+// Is will match ErrValidation
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// domainError will classify the repository error into domain error, the error which is already one
+// is returned as is and the unexpected error is returned untyped
+func domainError(err error) error {
+	for _, kind := range []error{ErrNotFound, ErrValidation, ErrUnavailable} {
+		if errors.Is(err, kind) {
+			return err
+		}
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return NewError(ErrNotFound, err)
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone), errors.As(err, &netErr):
+		return NewError(ErrUnavailable, err)
+	}
+
+	return err
+}
@@ -0,0 +1,34 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package webhook
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/replica"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// mysqlDialect represent the SQL of webhooks on MySQL, the events are stored as the text of PostgreSQL array
+// and matched after they're read. Skipping the locked deliveries needs MySQL 8.0
+var mysqlDialect = dialect{
+	deliverySelectField: model.WebhookDeliverySelectField,
+	onConflict:          " ON DUPLICATE KEY UPDATE id = id",
+	lock:                " FOR UPDATE SKIP LOCKED",
+	timestamp:           utcTimestamp,
+}
+
+// NewMySQLRepository will create an object that represent the Repository interface
+func NewMySQLRepository(DBRead *sqlx.DB, DBWrite *sqlx.DB) Repository {
+	return &sqlRepository{DBRead: DBRead, DBWrite: DBWrite, dialect: mysqlDialect}
+}
+
+// NewMySQLReplicaRepository will create an object that represent the Repository interface reading from
+// the replicas
+func NewMySQLReplicaRepository(replicas *replica.Set, DBWrite *sqlx.DB) Repository {
+	return &sqlRepository{DBRead: DBWrite, DBWrite: DBWrite, replicas: replicas, dialect: mysqlDialect}
+}
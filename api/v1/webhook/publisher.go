@@ -0,0 +1,78 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package webhook
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// outboxTimeLayouts represent the layouts of timestamps in the outbox payload, the databases write them in UTC
+// without zone
+var outboxTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"}
+
+// outboxPublisher represent the publisher of outbox events into webhook deliveries
+type outboxPublisher struct {
+	svc Service
+}
+
+// NewOutboxPublisher will create the publisher of outbox relay which dispatches the user events to webhooks, the
+// event is relayed again until it's dispatched so no event is lost
+func NewOutboxPublisher(svc Service) *outboxPublisher {
+	return &outboxPublisher{svc}
+}
+
+// Publish will dispatch the user event of outbox, the outbox id is the event id
+func (p *outboxPublisher) Publish(msg *model.OutboxModel) error {
+	user, err := outboxUser(msg.Payload)
+	if err != nil {
+		return fmt.Errorf("can't decode outbox payload of event %d: %s", msg.ID, err.Error())
+	}
+
+	return p.svc.Dispatch(&model.UserEvent{ID: uint64(msg.ID), Type: msg.EventType, User: user})
+}
+
+// outboxUser will decode the user row written as outbox payload
+func outboxUser(payload []byte) (*model.UserModel, error) {
+	row := struct {
+		model.UserModel
+		CreatedAt string `json:"created_at"`
+		UpdatedAt string `json:"updated_at"`
+	}{}
+	if err := json.Unmarshal(payload, &row); err != nil {
+		return nil, err
+	}
+
+	user := row.UserModel
+	for _, t := range []struct {
+		value string
+		dst   *time.Time
+	}{{row.CreatedAt, &user.CreatedAt}, {row.UpdatedAt, &user.UpdatedAt}} {
+		parsed, err := parseOutboxTime(t.value)
+		if err != nil {
+			return nil, err
+		}
+		*t.dst = parsed
+	}
+
+	return &user, nil
+}
+
+// parseOutboxTime will parse the timestamp of outbox payload as UTC
+func parseOutboxTime(value string) (time.Time, error) {
+	for _, layout := range outboxTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, errors.New("invalid timestamp " + value)
+}
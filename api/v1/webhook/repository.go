@@ -0,0 +1,241 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package webhook
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/replica"
+
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Repository represent the repositories
+type Repository interface {
+	Get(criteria *WebhookCriteria) ([]*model.WebhookModel, error)
+	Count(criteria *WebhookCriteria) (int, error)
+	GetByID(id string) (*model.WebhookModel, error)
+	GetByEvent(eventType string) ([]*model.WebhookModel, error)
+	Create(webhook *model.WebhookModel) (*model.WebhookModel, error)
+	Update(webhook *model.WebhookModel) (*model.WebhookModel, error)
+	Delete(id string) error
+	GetDeliveries(criteria *DeliveryCriteria) ([]*model.WebhookDeliveryModel, error)
+	CountDeliveries(criteria *DeliveryCriteria) (int, error)
+	CreateDelivery(delivery *model.WebhookDeliveryModel) error
+	UpdateDelivery(delivery *model.WebhookDeliveryModel) error
+	ClaimDeliveries(now, until time.Time, limit int) ([]*model.WebhookDeliveryModel, error)
+}
+
+// dialect represent the SQL of webhooks which differs by database, the queries are written with ? and rebound
+// to the placeholders of the driver
+type dialect struct {
+	// deliverySelectField represent the selected column of webhook delivery
+	deliverySelectField string
+	// eventCondition represent the condition of webhooks subscribed to the event type bound to ?, the webhooks
+	// are matched after they're read when it's empty
+	eventCondition string
+	// onConflict represent the clause skipping the delivery of outbox event already logged for the webhook
+	onConflict string
+	// lock represent the row lock of claimed deliveries, skipping the ones locked by other worker
+	lock string
+	// timestamp will return the argument of TIMESTAMP column
+	timestamp func(time.Time) interface{}
+}
+
+// postgresDialect represent the SQL of webhooks on PostgreSQL, the events are TEXT[]
+var postgresDialect = dialect{
+	deliverySelectField: model.WebhookDeliverySelectField,
+	eventCondition:      "(cardinality(events) = 0 OR ? = ANY(events))",
+	onConflict:          " ON CONFLICT (webhook_id, outbox_id) DO NOTHING",
+	lock:                " FOR UPDATE SKIP LOCKED",
+	timestamp:           utcTimestamp,
+}
+
+// utcTimestamp will return t in UTC as the argument of TIMESTAMP column
+func utcTimestamp(t time.Time) interface{} {
+	return t.UTC()
+}
+
+type sqlRepository struct {
+	DBRead  *sqlx.DB
+	DBWrite *sqlx.DB
+	// replicas represent the balanced replicas serving the reads instead of DBRead when it's set
+	replicas *replica.Set
+	dialect  dialect
+}
+
+// NewPostgresRepository will create an object that represent the Repository interface
+func NewPostgresRepository(DBRead *sqlx.DB, DBWrite *sqlx.DB) Repository {
+	return &sqlRepository{DBRead: DBRead, DBWrite: DBWrite, dialect: postgresDialect}
+}
+
+// NewPostgresReplicaRepository will create an object that represent the Repository interface reading from
+// the replicas
+func NewPostgresReplicaRepository(replicas *replica.Set, DBWrite *sqlx.DB) Repository {
+	return &sqlRepository{DBRead: DBWrite, DBWrite: DBWrite, replicas: replicas, dialect: postgresDialect}
+}
+
+// reader will return DBRead, the next replica when there are replicas
+func (p *sqlRepository) reader() *sqlx.DB {
+	if p.replicas != nil {
+		return p.replicas.Next()
+	}
+
+	return p.DBRead
+}
+
+func (p *sqlRepository) Get(criteria *WebhookCriteria) ([]*model.WebhookModel, error) {
+	webhooks := []*model.WebhookModel{}
+	limit, args := page(criteria.Limit, criteria.Offset)
+	query := fmt.Sprintf("SELECT %s FROM webhooks WHERE deleted_at IS NULL ORDER BY created_at DESC%s", model.WebhookSelectField, limit)
+	db := p.reader()
+	err := db.Select(&webhooks, db.Rebind(query), args...)
+	return webhooks, err
+}
+
+func (p *sqlRepository) Count(criteria *WebhookCriteria) (int, error) {
+	var count int
+	db := p.reader()
+	err := db.Get(&count, "SELECT COUNT(id) FROM webhooks WHERE deleted_at IS NULL")
+	return count, err
+}
+
+func (p *sqlRepository) GetByID(id string) (*model.WebhookModel, error) {
+	webhook := &model.WebhookModel{}
+	query := fmt.Sprintf("SELECT %s FROM webhooks WHERE deleted_at IS NULL AND id = ?", model.WebhookSelectField)
+	db := p.reader()
+	err := db.Get(webhook, db.Rebind(query), id)
+	return webhook, err
+}
+
+// GetByEvent will get the active webhooks subscribed to the event type, from DBWrite so the
+// subscription changed just before is respected
+func (p *sqlRepository) GetByEvent(eventType string) ([]*model.WebhookModel, error) {
+	webhooks := []*model.WebhookModel{}
+	query := fmt.Sprintf("SELECT %s FROM webhooks WHERE deleted_at IS NULL AND active", model.WebhookSelectField)
+	if len(p.dialect.eventCondition) > 0 {
+		err := p.DBWrite.Select(&webhooks, p.DBWrite.Rebind(query+" AND "+p.dialect.eventCondition), eventType)
+		return webhooks, err
+	}
+
+	if err := p.DBWrite.Select(&webhooks, query); err != nil {
+		return webhooks, err
+	}
+
+	subscribed := []*model.WebhookModel{}
+	for _, webhook := range webhooks {
+		if subscribes(webhook, eventType) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+
+	return subscribed, nil
+}
+
+// subscribes will check whether the webhook is subscribed to the event type, empty events is every event
+func subscribes(webhook *model.WebhookModel, eventType string) bool {
+	if len(webhook.Events) == 0 {
+		return true
+	}
+
+	for _, event := range webhook.Events {
+		if event == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *sqlRepository) Create(webhook *model.WebhookModel) (*model.WebhookModel, error) {
+	_, err := p.DBWrite.NamedExec(`INSERT INTO webhooks (id, url, secret, events, active, created_at, updated_at) VALUES (:id, :url, :secret, :events, :active, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`, webhook)
+	return webhook, err
+}
+
+func (p *sqlRepository) Update(webhook *model.WebhookModel) (*model.WebhookModel, error) {
+	_, err := p.DBWrite.NamedExec(`UPDATE webhooks SET url = :url, secret = :secret, events = :events, active = :active, updated_at = CURRENT_TIMESTAMP WHERE id = :id`, webhook)
+	return webhook, err
+}
+
+func (p *sqlRepository) Delete(id string) error {
+	_, err := p.DBWrite.Exec(p.DBWrite.Rebind(`UPDATE webhooks SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?`), id)
+	return err
+}
+
+func (p *sqlRepository) GetDeliveries(criteria *DeliveryCriteria) ([]*model.WebhookDeliveryModel, error) {
+	deliveries := []*model.WebhookDeliveryModel{}
+	where, args := criteria.where()
+	limit, limitArgs := page(criteria.Limit, criteria.Offset)
+	query := fmt.Sprintf("SELECT %s FROM webhook_deliveries%s ORDER BY created_at DESC%s", p.dialect.deliverySelectField, where, limit)
+	db := p.reader()
+	err := db.Select(&deliveries, db.Rebind(query), append(args, limitArgs...)...)
+	return deliveries, err
+}
+
+func (p *sqlRepository) CountDeliveries(criteria *DeliveryCriteria) (int, error) {
+	var count int
+	where, args := criteria.where()
+	db := p.reader()
+	err := db.Get(&count, db.Rebind("SELECT COUNT(id) FROM webhook_deliveries"+where), args...)
+	return count, err
+}
+
+// CreateDelivery will insert the delivery, payload is passed as text since []byte is sent as bytea. The event
+// id is the outbox event, the delivery of event already logged for the webhook is skipped
+func (p *sqlRepository) CreateDelivery(delivery *model.WebhookDeliveryModel) error {
+	query := `INSERT INTO webhook_deliveries (id, webhook_id, event_id, outbox_id, event_type, payload, status, attempts, response_status, last_error, next_attempt_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)` + p.dialect.onConflict
+	_, err := p.DBWrite.Exec(p.DBWrite.Rebind(query),
+		delivery.ID, delivery.WebhookID, delivery.EventID, delivery.EventID, delivery.EventType, string(delivery.Payload), delivery.Status, delivery.Attempts, delivery.ResponseStatus, delivery.LastError, p.dialect.timestamp(delivery.NextAttemptAt))
+	return err
+}
+
+func (p *sqlRepository) UpdateDelivery(delivery *model.WebhookDeliveryModel) error {
+	_, err := p.DBWrite.Exec(p.DBWrite.Rebind(`UPDATE webhook_deliveries SET status = ?, attempts = ?, response_status = ?, last_error = ?, next_attempt_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`),
+		delivery.Status, delivery.Attempts, delivery.ResponseStatus, delivery.LastError, p.dialect.timestamp(delivery.NextAttemptAt), delivery.ID)
+	return err
+}
+
+// ClaimDeliveries will take up to limit pending deliveries due at now, skipping the ones claimed by other
+// worker, and postpone them until until. So the delivery whose worker stops before recording the attempt is
+// retried then
+func (p *sqlRepository) ClaimDeliveries(now, until time.Time, limit int) ([]*model.WebhookDeliveryModel, error) {
+	tx, err := p.DBWrite.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deliveries := []*model.WebhookDeliveryModel{}
+	query := fmt.Sprintf("SELECT %s FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ?%s", p.dialect.deliverySelectField, p.dialect.lock)
+	if err := tx.Select(&deliveries, tx.Rebind(query), model.DeliveryPending, p.dialect.timestamp(now), limit); err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	ids := make([]string, len(deliveries))
+	for i, delivery := range deliveries {
+		ids[i] = delivery.ID
+		delivery.NextAttemptAt = until
+	}
+	query, args, err := sqlx.In(`UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN (?)`, p.dialect.timestamp(until), ids)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(tx.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,280 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package webhook_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/webhook"
+
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetByEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+
+	rows := sqlmock.NewRows([]string{"id", "url", "secret", "events", "active", "created_at", "updated_at"}).
+		AddRow(xid.New().String(), "https://crm.example.com/hooks", "0123456789abcdef", "{user.created,user.updated}", true, time.Now().UTC(), time.Now().UTC())
+
+	query := "SELECT " + model.WebhookSelectField + " FROM webhooks WHERE deleted_at IS NULL AND active AND (cardinality(events) = 0 OR $1 = ANY(events))"
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs("user.created").WillReturnRows(rows)
+	w := webhook.NewPostgresRepository(sqlxDB, sqlxDB)
+
+	webhooks, err := w.GetByEvent("user.created")
+
+	assert.NoError(t, err)
+	assert.Len(t, webhooks, 1)
+	assert.Equal(t, []string{"user.created", "user.updated"}, []string(webhooks[0].Events))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateWebhook(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+
+	hook := &model.WebhookModel{
+		ID:     xid.New().String(),
+		URL:    "https://crm.example.com/hooks",
+		Secret: "0123456789abcdef",
+		Events: []string{"user.created"},
+		Active: true,
+	}
+
+	mock.ExpectExec("INSERT INTO webhooks").
+		WithArgs(hook.ID, hook.URL, hook.Secret, "{\"user.created\"}", true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	w := webhook.NewPostgresRepository(sqlxDB, sqlxDB)
+
+	created, err := w.Create(hook)
+
+	assert.NoError(t, err)
+	assert.Equal(t, hook, created)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetDeliveries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+
+	rows := sqlmock.NewRows([]string{"id", "webhook_id", "event_id", "event_type", "payload", "status", "attempts", "response_status", "last_error", "next_attempt_at", "created_at", "updated_at"}).
+		AddRow(xid.New().String(), xid.New().String(), 42, "user.deleted", []byte(`{"type":"user.deleted"}`), model.DeliveryDead, 5, 500, "unexpected response status 500", time.Now().UTC(), time.Now().UTC(), time.Now().UTC())
+
+	query := "SELECT " + model.WebhookDeliverySelectField + " FROM webhook_deliveries WHERE status = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3"
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(model.DeliveryDead, 10, 0).WillReturnRows(rows)
+	w := webhook.NewPostgresRepository(sqlxDB, sqlxDB)
+
+	deliveries, err := w.GetDeliveries(&webhook.DeliveryCriteria{Status: model.DeliveryDead, Limit: 10})
+
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, uint64(42), deliveries[0].EventID)
+	assert.JSONEq(t, `{"type":"user.deleted"}`, string(deliveries[0].Payload))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountDeliveries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+
+	id := xid.New().String()
+	query := "SELECT COUNT(id) FROM webhook_deliveries WHERE webhook_id = $1 AND status = $2"
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(id, model.DeliveryPending).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	w := webhook.NewPostgresRepository(sqlxDB, sqlxDB)
+
+	count, err := w.CountDeliveries(&webhook.DeliveryCriteria{WebhookID: id, Status: model.DeliveryPending, Limit: 10})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetWebhooks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+
+	rows := sqlmock.NewRows([]string{"id", "url", "secret", "events", "active", "created_at", "updated_at"}).
+		AddRow(xid.New().String(), "https://crm.example.com/hooks", "0123456789abcdef", "{}", true, time.Now().UTC(), time.Now().UTC())
+
+	query := "SELECT " + model.WebhookSelectField + " FROM webhooks WHERE deleted_at IS NULL ORDER BY created_at DESC LIMIT $1 OFFSET $2"
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(10, 20).WillReturnRows(rows)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(id) FROM webhooks WHERE deleted_at IS NULL")).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(21))
+	w := webhook.NewPostgresRepository(sqlxDB, sqlxDB)
+
+	criteria := &webhook.WebhookCriteria{Limit: 10, Offset: 20}
+	webhooks, err := w.Get(criteria)
+	assert.NoError(t, err)
+	assert.Len(t, webhooks, 1)
+
+	count, err := w.Count(criteria)
+	assert.NoError(t, err)
+	assert.Equal(t, 21, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateDelivery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+
+	delivery := &model.WebhookDeliveryModel{
+		ID:            xid.New().String(),
+		WebhookID:     xid.New().String(),
+		EventID:       1,
+		EventType:     "user.created",
+		Payload:       []byte(`{"type":"user.created"}`),
+		Status:        model.DeliveryPending,
+		NextAttemptAt: time.Now().UTC(),
+	}
+
+	// the delivery of outbox event is created once per webhook
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO webhook_deliveries (id, webhook_id, event_id, outbox_id,")+".+"+regexp.QuoteMeta("ON CONFLICT (webhook_id, outbox_id) DO NOTHING")).
+		WithArgs(delivery.ID, delivery.WebhookID, delivery.EventID, delivery.EventID, delivery.EventType, `{"type":"user.created"}`, model.DeliveryPending, 0, 0, "", delivery.NextAttemptAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	w := webhook.NewPostgresRepository(sqlxDB, sqlxDB)
+
+	err = w.CreateDelivery(delivery)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func deliveryRows(nextAttemptAt time.Time) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "webhook_id", "event_id", "event_type", "payload", "status", "attempts", "response_status", "last_error", "next_attempt_at", "created_at", "updated_at"}).
+		AddRow("bpielbbipt341rif5i20", xid.New().String(), 42, "user.created", []byte(`{"type":"user.created"}`), model.DeliveryPending, 1, 500, "unexpected response status 500", nextAttemptAt, nextAttemptAt, nextAttemptAt)
+}
+
+func TestClaimDeliveries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "postgres")
+
+	now := time.Now().UTC()
+	until := now.Add(time.Minute)
+
+	query := "SELECT " + model.WebhookDeliverySelectField + " FROM webhook_deliveries WHERE status = $1 AND next_attempt_at <= $2 ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs(model.DeliveryPending, now, 10).WillReturnRows(deliveryRows(now))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE webhook_deliveries SET next_attempt_at = $1 WHERE id IN ($2)")).WithArgs(until, "bpielbbipt341rif5i20").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	w := webhook.NewPostgresRepository(sqlxDB, sqlxDB)
+
+	deliveries, err := w.ClaimDeliveries(now, until, 10)
+
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, until, deliveries[0].NextAttemptAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByEventMySQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "mysql")
+
+	// the events are read as the text of PostgreSQL array and matched after reading
+	rows := sqlmock.NewRows([]string{"id", "url", "secret", "events", "active", "created_at", "updated_at"}).
+		AddRow("bpielbbipt341rif5i20", "https://crm.example.com/hooks", "0123456789abcdef", []byte("{user.created,user.updated}"), true, time.Now().UTC(), time.Now().UTC()).
+		AddRow("bpielbbipt341rif5i21", "https://erp.example.com/hooks", "0123456789abcdef", []byte("{user.deleted}"), true, time.Now().UTC(), time.Now().UTC()).
+		AddRow("bpielbbipt341rif5i22", "https://log.example.com/hooks", "0123456789abcdef", []byte("{}"), true, time.Now().UTC(), time.Now().UTC())
+
+	query := "SELECT " + model.WebhookSelectField + " FROM webhooks WHERE deleted_at IS NULL AND active"
+
+	mock.ExpectQuery(regexp.QuoteMeta(query)).WithArgs().WillReturnRows(rows)
+	w := webhook.NewMySQLRepository(sqlxDB, sqlxDB)
+
+	webhooks, err := w.GetByEvent("user.created")
+
+	assert.NoError(t, err)
+	assert.Len(t, webhooks, 2)
+	assert.Equal(t, "bpielbbipt341rif5i20", webhooks[0].ID)
+	assert.Equal(t, "bpielbbipt341rif5i22", webhooks[1].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeliveriesMySQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	sqlxDB := sqlx.NewDb(db, "mysql")
+
+	now := time.Now().UTC()
+	until := now.Add(time.Minute)
+	delivery := &model.WebhookDeliveryModel{
+		ID:            "bpielbbipt341rif5i20",
+		WebhookID:     xid.New().String(),
+		EventID:       42,
+		EventType:     "user.created",
+		Payload:       []byte(`{"type":"user.created"}`),
+		Status:        model.DeliveryPending,
+		NextAttemptAt: now,
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO webhook_deliveries (id, webhook_id, event_id, outbox_id,")+".+"+regexp.QuoteMeta("VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) ON DUPLICATE KEY UPDATE id = id")).
+		WithArgs(delivery.ID, delivery.WebhookID, delivery.EventID, delivery.EventID, delivery.EventType, `{"type":"user.created"}`, model.DeliveryPending, 0, 0, "", now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT "+model.WebhookDeliverySelectField+" FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ? FOR UPDATE SKIP LOCKED")).
+		WithArgs(model.DeliveryPending, now, 10).WillReturnRows(deliveryRows(now))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN (?)")).WithArgs(until, delivery.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE webhook_deliveries SET status = ?, attempts = ?, response_status = ?, last_error = ?, next_attempt_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?")).
+		WithArgs(model.DeliverySucceeded, 2, 204, "", now, delivery.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	w := webhook.NewMySQLRepository(sqlxDB, sqlxDB)
+
+	assert.NoError(t, w.CreateDelivery(delivery))
+
+	deliveries, err := w.ClaimDeliveries(now, until, 10)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+
+	delivery.Status, delivery.Attempts, delivery.ResponseStatus = model.DeliverySucceeded, 2, 204
+	assert.NoError(t, w.UpdateDelivery(delivery))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
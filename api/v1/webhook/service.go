@@ -0,0 +1,428 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package webhook
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMaxAttempts represent the number of delivery attempts before it's dead letter
+	DefaultMaxAttempts = 5
+	// DefaultBackoff represent the wait after the first failed attempt, doubled after every next one
+	DefaultBackoff = time.Second
+	// DefaultTimeout represent the timeout of one delivery attempt
+	DefaultTimeout = 10 * time.Second
+	// DefaultBatchSize represent the number of due deliveries attempted at once
+	DefaultBatchSize = 100
+	// DefaultInterval represent the wait before polling the due deliveries again once there're none left
+	DefaultInterval = time.Second
+	// DefaultLease represent the time a claimed delivery is held by its worker, after which it's attempted
+	// again when the worker didn't record the attempt. It's at least twice the timeout of attempt
+	DefaultLease = time.Minute
+
+	// HeaderSignature represent the header of payload signature, sha256= followed by hex HMAC-SHA256
+	// of the timestamp, a dot and the body with webhook secret as the key
+	HeaderSignature = "X-Webhook-Signature"
+	// HeaderTimestamp represent the header of unix time the payload is signed at
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderEvent represent the header of event type
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderDelivery represent the header of delivery id, the same on every attempt
+	HeaderDelivery = "X-Webhook-Delivery"
+
+	// responseMaxBytes represent the maximum bytes of receiver response read before the connection is reused
+	responseMaxBytes = 64 << 10
+)
+
+// Service represent the services
+type Service interface {
+	Create(req *form.WebhookForm) (*model.WebhookModel, error)
+	List(criteria *WebhookCriteria) ([]*model.WebhookModel, int, error)
+	Detail(id string) (*model.WebhookModel, error)
+	Update(req *form.WebhookForm, id string) (*model.WebhookModel, error)
+	Delete(id string) error
+	Deliveries(criteria *DeliveryCriteria) ([]*model.WebhookDeliveryModel, int, error)
+	Dispatch(event *model.UserEvent) error
+	DeliverDue(limit int) (int, error)
+}
+
+type implService struct {
+	log         *logrus.Entry
+	repository  Repository
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// NewService will create an object that represent the Service interface, zero maxAttempts and
+// backoff use the defaults
+func NewService(log *logrus.Entry, r Repository, client *http.Client, maxAttempts int, backoff time.Duration) Service {
+	if maxAttempts < 1 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+
+	return &implService{log: log, repository: r, client: client, maxAttempts: maxAttempts, backoff: backoff}
+}
+
+// Create will store the webhook and return it with the secret, which isn't returned anymore afterward
+func (s *implService) Create(req *form.WebhookForm) (*model.WebhookModel, error) {
+	if errs := req.Validate(); len(errs) > 0 {
+		return nil, &ValidationError{Fields: errs}
+	}
+
+	webhook := &model.WebhookModel{
+		ID:     xid.New().String(),
+		URL:    req.URL,
+		Secret: req.Secret,
+		Events: req.Events,
+		Active: req.Active == nil || *req.Active,
+	}
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+
+	if len(webhook.Secret) == 0 {
+		secret, err := newSecret()
+		if err != nil {
+			s.log.Errorf("can't generate webhook secret: %s", err.Error())
+			return nil, err
+		}
+		webhook.Secret = secret
+	}
+
+	webhook, err := s.repository.Create(webhook)
+	if err != nil {
+		s.log.Errorf("can't create webhook: %s", err.Error())
+		return nil, domainError(err)
+	}
+
+	return webhook, nil
+}
+
+func (s *implService) List(criteria *WebhookCriteria) ([]*model.WebhookModel, int, error) {
+	webhooks, err := s.repository.Get(criteria)
+	if err != nil {
+		s.log.Errorf("can't get webhooks: %s", err.Error())
+		return nil, 0, domainError(err)
+	}
+
+	count, err := s.repository.Count(criteria)
+	if err != nil {
+		s.log.Errorf("can't count webhooks: %s", err.Error())
+		return nil, 0, domainError(err)
+	}
+
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+
+	return webhooks, count, nil
+}
+
+func (s *implService) Detail(id string) (*model.WebhookModel, error) {
+	webhook, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.Secret = ""
+
+	return webhook, nil
+}
+
+// Update will replace the webhook, the secret is kept when it's not given
+func (s *implService) Update(req *form.WebhookForm, id string) (*model.WebhookModel, error) {
+	if errs := req.Validate(); len(errs) > 0 {
+		return nil, &ValidationError{Fields: errs}
+	}
+
+	webhook, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.URL = req.URL
+	webhook.Events = req.Events
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	if len(req.Secret) > 0 {
+		webhook.Secret = req.Secret
+	}
+
+	webhook, err = s.repository.Update(webhook)
+	if err != nil {
+		s.log.Errorf("can't update webhook: %s with id %v", err.Error(), id)
+		return nil, domainError(err)
+	}
+
+	webhook.Secret = ""
+
+	return webhook, nil
+}
+
+func (s *implService) Delete(id string) error {
+	if _, err := s.get(id); err != nil {
+		return err
+	}
+
+	if err := s.repository.Delete(id); err != nil {
+		s.log.Errorf("can't delete webhook: %s", err.Error())
+		return domainError(err)
+	}
+
+	return nil
+}
+
+// Deliveries will get the delivery log, of one webhook when the WebhookID of criteria is set and of one
+// status when its Status is set, e.g. the dead letters with DeliveryDead
+func (s *implService) Deliveries(criteria *DeliveryCriteria) ([]*model.WebhookDeliveryModel, int, error) {
+	if len(criteria.WebhookID) > 0 {
+		if _, err := s.get(criteria.WebhookID); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if len(criteria.Status) > 0 && criteria.Status != model.DeliveryPending && criteria.Status != model.DeliverySucceeded && criteria.Status != model.DeliveryDead {
+		return nil, 0, &ValidationError{Fields: form.ValidationErrors{{
+			Field:   "status",
+			Rule:    form.RuleOneOf,
+			Message: "Invalid parameter status: must be pending, succeeded or dead",
+		}}}
+	}
+
+	count, err := s.repository.CountDeliveries(criteria)
+	if err != nil {
+		s.log.Errorf("can't count webhook deliveries: %s", err.Error())
+		return nil, 0, domainError(err)
+	}
+
+	deliveries, err := s.repository.GetDeliveries(criteria)
+	if err != nil {
+		s.log.Errorf("can't get webhook deliveries: %s", err.Error())
+		return nil, 0, domainError(err)
+	}
+
+	return deliveries, count, nil
+}
+
+// Dispatch will log the pending delivery of event for every subscribed webhook, they're sent by DeliverDue. The
+// event id is the id of outbox event, the delivery already logged for it is kept as is
+func (s *implService) Dispatch(event *model.UserEvent) error {
+	webhooks, err := s.repository.GetByEvent(event.Type)
+	if err != nil {
+		s.log.Errorf("can't get webhooks of event %s: %s", event.Type, err.Error())
+		return domainError(err)
+	}
+
+	now := time.Now().UTC()
+	for _, webhook := range webhooks {
+		delivery := &model.WebhookDeliveryModel{
+			ID:            xid.New().String(),
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Status:        model.DeliveryPending,
+			NextAttemptAt: now,
+		}
+
+		delivery.Payload, err = json.Marshal(&model.WebhookPayload{
+			ID:        delivery.ID,
+			Type:      event.Type,
+			EventID:   event.ID,
+			CreatedAt: now,
+			Data:      event.User,
+		})
+		if err != nil {
+			s.log.Errorf("can't marshal webhook payload: %s", err.Error())
+			return err
+		}
+
+		err = s.repository.CreateDelivery(delivery)
+		if err != nil {
+			s.log.Errorf("can't create webhook delivery: %s", err.Error())
+			return domainError(err)
+		}
+	}
+
+	return nil
+}
+
+// DeliverDue will attempt up to limit pending deliveries which are due side by side, and return the number
+// of attempted deliveries
+func (s *implService) DeliverDue(limit int) (int, error) {
+	now := time.Now().UTC()
+	lease := DefaultLease
+	if s.client.Timeout*2 > lease {
+		lease = s.client.Timeout * 2
+	}
+
+	deliveries, err := s.repository.ClaimDeliveries(now, now.Add(lease), limit)
+	if err != nil {
+		s.log.Errorf("can't claim webhook deliveries: %s", err.Error())
+		return 0, domainError(err)
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *model.WebhookDeliveryModel) {
+			defer wg.Done()
+			s.deliver(delivery)
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// get will get the webhook by id with the secret
+func (s *implService) get(id string) (*model.WebhookModel, error) {
+	webhook, err := s.repository.GetByID(id)
+	if err != nil {
+		err = domainError(err)
+		if !errors.Is(err, ErrNotFound) {
+			s.log.Errorf("can't get webhook: %s with id %v", err.Error(), id)
+		}
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// deliver will attempt the delivery once and record the attempt. The failed delivery is attempted again after
+// backoff doubled after every failed attempt, and it's dead letter after the last attempt or when its webhook
+// is deleted
+func (s *implService) deliver(delivery *model.WebhookDeliveryModel) {
+	delivery.Attempts++
+	delivery.LastError = ""
+
+	webhook, err := s.repository.GetByID(delivery.WebhookID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = errors.New("webhook is deleted")
+		delivery.Attempts = s.maxAttempts
+	case err != nil:
+		s.log.Errorf("can't get webhook: %s with id %v", err.Error(), delivery.WebhookID)
+		return
+	default:
+		delivery.ResponseStatus, err = s.send(webhook, delivery)
+	}
+
+	if err == nil {
+		delivery.Status = model.DeliverySucceeded
+	} else {
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= s.maxAttempts {
+			delivery.Status = model.DeliveryDead
+		}
+		delivery.NextAttemptAt = time.Now().UTC().Add(s.backoff << uint(delivery.Attempts-1))
+	}
+
+	if err := s.repository.UpdateDelivery(delivery); err != nil {
+		s.log.Errorf("can't update webhook delivery: %s with id %v", err.Error(), delivery.ID)
+	}
+}
+
+// send will post the signed payload to webhook and return the response status
+func (s *implService) send(webhook *model.WebhookModel, delivery *model.WebhookDeliveryModel) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, responseMaxBytes))
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign will create the value of signature header, receivers verify it by computing the same from
+// the timestamp header and raw body
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RunDeliveries will attempt the due deliveries of svc until ctx is done, zero batchSize and interval use the
+// defaults. The next batch is claimed right away while the batches are full
+func RunDeliveries(ctx context.Context, svc Service, batchSize int, interval time.Duration) {
+	if batchSize < 1 {
+		batchSize = DefaultBatchSize
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	for ctx.Err() == nil {
+		n, err := svc.DeliverDue(batchSize)
+		if err == nil && n == batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
+
+// newSecret will generate the random secret of webhook
+func newSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,343 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package webhook_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/webhook"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/webhook/mocks"
+	"github.com/moemoe89/go-graphql-gendhis/config"
+
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// receivedDelivery represent the request got by test receiver
+type receivedDelivery struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver will start the webhook receiver answering with the statuses in order, the last one repeated
+func newReceiver(statuses ...int) (*httptest.Server, chan *receivedDelivery) {
+	received := make(chan *receivedDelivery, 10)
+	attempt := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- &receivedDelivery{r.Header, body}
+
+		status := statuses[len(statuses)-1]
+		if attempt < len(statuses) {
+			status = statuses[attempt]
+		}
+		attempt++
+		w.WriteHeader(status)
+	}))
+
+	return server, received
+}
+
+func TestServiceCreate(t *testing.T) {
+	log := config.InitLog()
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("Create", mock.AnythingOfType("*model.WebhookModel")).Return(func(hook *model.WebhookModel) *model.WebhookModel {
+		return hook
+	}, nil)
+
+	w := webhook.NewService(log, mockRepo, http.DefaultClient, 0, 0)
+
+	hook, err := w.Create(&form.WebhookForm{URL: "https://crm.example.com/hooks"})
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, hook.ID)
+	assert.Len(t, hook.Secret, 48)
+	assert.True(t, hook.Active)
+	assert.Equal(t, []string{}, []string(hook.Events))
+}
+
+func TestServiceDetailFail(t *testing.T) {
+	log := config.InitLog()
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("GetByID", "unknown").Return(nil, sql.ErrNoRows)
+
+	w := webhook.NewService(log, mockRepo, http.DefaultClient, 0, 0)
+
+	hook, err := w.Detail("unknown")
+
+	assert.Nil(t, hook)
+	assert.True(t, errors.Is(err, webhook.ErrNotFound))
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+}
+
+func TestServiceCreateFail(t *testing.T) {
+	log := config.InitLog()
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("Create", mock.AnythingOfType("*model.WebhookModel")).Return(nil, context.DeadlineExceeded)
+
+	w := webhook.NewService(log, mockRepo, http.DefaultClient, 0, 0)
+
+	_, err := w.Create(&form.WebhookForm{URL: "ftp://crm.example.com"})
+
+	var validationErr *webhook.ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, "url", validationErr.Fields[0].Field)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything)
+
+	_, err = w.Create(&form.WebhookForm{URL: "https://crm.example.com/hooks"})
+
+	assert.True(t, errors.Is(err, webhook.ErrUnavailable))
+}
+
+func TestServiceUpdate(t *testing.T) {
+	log := config.InitLog()
+	id := xid.New().String()
+
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("GetByID", id).Return(&model.WebhookModel{ID: id, Secret: "0123456789abcdef", Active: true}, nil)
+	mockRepo.On("Update", mock.MatchedBy(func(hook *model.WebhookModel) bool {
+		return hook.Secret == "0123456789abcdef" && !hook.Active && hook.URL == "https://crm.example.com/v2"
+	})).Return(func(hook *model.WebhookModel) *model.WebhookModel {
+		return hook
+	}, nil)
+
+	w := webhook.NewService(log, mockRepo, http.DefaultClient, 0, 0)
+
+	active := false
+	hook, err := w.Update(&form.WebhookForm{URL: "https://crm.example.com/v2", Active: &active}, id)
+
+	assert.NoError(t, err)
+	assert.Empty(t, hook.Secret)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceDeliveries(t *testing.T) {
+	log := config.InitLog()
+	id := xid.New().String()
+
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("GetByID", id).Return(&model.WebhookModel{ID: id}, nil)
+	criteria := &webhook.DeliveryCriteria{WebhookID: id, Status: model.DeliveryDead, Limit: 10}
+	mockRepo.On("CountDeliveries", criteria).Return(1, nil)
+	mockRepo.On("GetDeliveries", criteria).Return([]*model.WebhookDeliveryModel{{ID: xid.New().String()}}, nil)
+
+	w := webhook.NewService(log, mockRepo, http.DefaultClient, 0, 0)
+
+	deliveries, count, err := w.Deliveries(criteria)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Len(t, deliveries, 1)
+
+	_, _, err = w.Deliveries(&webhook.DeliveryCriteria{Status: "failed", Limit: 10})
+
+	var validationErr *webhook.ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, "status", validationErr.Fields[0].Field)
+}
+
+func TestServiceDispatch(t *testing.T) {
+	log := config.InitLog()
+
+	hook := &model.WebhookModel{ID: xid.New().String(), URL: "http://localhost", Secret: "0123456789abcdef"}
+	created := make(chan *model.WebhookDeliveryModel, 1)
+
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("GetByEvent", "user.created").Return([]*model.WebhookModel{hook}, nil)
+	mockRepo.On("CreateDelivery", mock.AnythingOfType("*model.WebhookDeliveryModel")).Run(func(args mock.Arguments) {
+		created <- args.Get(0).(*model.WebhookDeliveryModel)
+	}).Return(nil)
+
+	w := webhook.NewService(log, mockRepo, http.DefaultClient, 3, time.Millisecond)
+
+	user := &model.UserModel{ID: xid.New().String(), Name: "Momo"}
+	assert.NoError(t, w.Dispatch(&model.UserEvent{ID: 7, Type: "user.created", User: user}))
+
+	// the delivery is only logged, it's sent by DeliverDue
+	delivery := <-created
+	assert.Equal(t, model.DeliveryPending, delivery.Status)
+	assert.Equal(t, hook.ID, delivery.WebhookID)
+	assert.Equal(t, uint64(7), delivery.EventID)
+	assert.False(t, delivery.NextAttemptAt.After(time.Now().UTC()))
+	mockRepo.AssertNotCalled(t, "UpdateDelivery", mock.Anything)
+
+	payload := &model.WebhookPayload{}
+	assert.NoError(t, json.Unmarshal(delivery.Payload, payload))
+	assert.Equal(t, uint64(7), payload.EventID)
+	assert.Equal(t, user.ID, payload.Data.ID)
+}
+
+func TestServiceDispatchFail(t *testing.T) {
+	log := config.InitLog()
+
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("GetByEvent", "user.created").Return([]*model.WebhookModel{{ID: xid.New().String()}}, nil)
+	mockRepo.On("CreateDelivery", mock.AnythingOfType("*model.WebhookDeliveryModel")).Return(errors.New("Unexpected database error"))
+
+	w := webhook.NewService(log, mockRepo, http.DefaultClient, 3, time.Millisecond)
+	err := w.Dispatch(&model.UserEvent{ID: 7, Type: "user.created", User: &model.UserModel{}})
+
+	assert.EqualError(t, err, "Unexpected database error")
+}
+
+func TestServiceDeliverDue(t *testing.T) {
+	log := config.InitLog()
+
+	server, received := newReceiver(http.StatusInternalServerError, http.StatusNoContent)
+	defer server.Close()
+
+	hook := &model.WebhookModel{ID: xid.New().String(), URL: server.URL, Secret: "0123456789abcdef"}
+	delivery := &model.WebhookDeliveryModel{
+		ID:        xid.New().String(),
+		WebhookID: hook.ID,
+		EventID:   7,
+		EventType: "user.created",
+		Payload:   json.RawMessage(`{"type":"user.created"}`),
+		Status:    model.DeliveryPending,
+	}
+	updates := make(chan model.WebhookDeliveryModel, 10)
+
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("ClaimDeliveries", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return([]*model.WebhookDeliveryModel{delivery}, nil)
+	mockRepo.On("GetByID", hook.ID).Return(hook, nil)
+	mockRepo.On("UpdateDelivery", delivery).Run(func(args mock.Arguments) {
+		updates <- *args.Get(0).(*model.WebhookDeliveryModel)
+	}).Return(nil)
+
+	w := webhook.NewService(log, mockRepo, http.DefaultClient, 3, time.Minute)
+
+	n, err := w.DeliverDue(10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// the failed attempt is stored to be retried after backoff
+	failed := <-updates
+	assert.Equal(t, model.DeliveryPending, failed.Status)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, failed.ResponseStatus)
+	assert.True(t, failed.NextAttemptAt.After(time.Now().UTC().Add(50*time.Second)))
+
+	_, err = w.DeliverDue(10)
+	assert.NoError(t, err)
+
+	succeeded := <-updates
+	assert.Equal(t, model.DeliverySucceeded, succeeded.Status)
+	assert.Equal(t, 2, succeeded.Attempts)
+	assert.Equal(t, http.StatusNoContent, succeeded.ResponseStatus)
+	assert.Empty(t, succeeded.LastError)
+
+	first := <-received
+	retry := <-received
+	assert.Equal(t, first.body, retry.body)
+	assert.Equal(t, delivery.ID, retry.header.Get(webhook.HeaderDelivery))
+	assert.Equal(t, "user.created", retry.header.Get(webhook.HeaderEvent))
+	assert.Equal(t, webhook.Sign(hook.Secret, retry.header.Get(webhook.HeaderTimestamp), retry.body), retry.header.Get(webhook.HeaderSignature))
+}
+
+func TestServiceDeliverDueDeadLetter(t *testing.T) {
+	log := config.InitLog()
+
+	server, received := newReceiver(http.StatusServiceUnavailable)
+	defer server.Close()
+
+	hook := &model.WebhookModel{ID: xid.New().String(), URL: server.URL}
+	retried := &model.WebhookDeliveryModel{ID: xid.New().String(), WebhookID: hook.ID, Status: model.DeliveryPending, Attempts: 2}
+	orphan := &model.WebhookDeliveryModel{ID: xid.New().String(), WebhookID: xid.New().String(), Status: model.DeliveryPending}
+
+	mockRepo := new(mocks.Repository)
+	mockRepo.On("ClaimDeliveries", mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time"), 10).Return([]*model.WebhookDeliveryModel{retried, orphan}, nil)
+	mockRepo.On("GetByID", hook.ID).Return(hook, nil)
+	mockRepo.On("GetByID", orphan.WebhookID).Return(&model.WebhookModel{}, sql.ErrNoRows)
+	mockRepo.On("UpdateDelivery", mock.AnythingOfType("*model.WebhookDeliveryModel")).Return(nil)
+
+	w := webhook.NewService(log, mockRepo, http.DefaultClient, 3, time.Millisecond)
+
+	n, err := w.DeliverDue(10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	assert.Equal(t, model.DeliveryDead, retried.Status)
+	assert.Equal(t, 3, retried.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, retried.ResponseStatus)
+	assert.Equal(t, "unexpected response status 503", retried.LastError)
+	assert.Len(t, received, 1)
+
+	// the delivery of deleted webhook isn't retried
+	assert.Equal(t, model.DeliveryDead, orphan.Status)
+	assert.Equal(t, "webhook is deleted", orphan.LastError)
+}
+
+func TestRunDeliveries(t *testing.T) {
+	claimed := make(chan int, 10)
+	calls := 0
+
+	mockService := new(mocks.Service)
+	mockService.On("DeliverDue", 2).Return(func(limit int) int {
+		calls++
+		claimed <- limit
+		// the first batch is full, so the next one is claimed right away
+		if calls == 1 {
+			return limit
+		}
+		return 0
+	}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		webhook.RunDeliveries(ctx, mockService, 2, time.Hour)
+		done <- true
+	}()
+
+	assert.Equal(t, 2, <-claimed)
+	assert.Equal(t, 2, <-claimed)
+
+	cancel()
+	<-done
+}
+
+func TestOutboxPublisher(t *testing.T) {
+	dispatched := make(chan *model.UserEvent, 1)
+
+	mockService := new(mocks.Service)
+	mockService.On("Dispatch", mock.AnythingOfType("*model.UserEvent")).Run(func(args mock.Arguments) {
+		dispatched <- args.Get(0).(*model.UserEvent)
+	}).Return(nil).Once()
+	mockService.On("Dispatch", mock.AnythingOfType("*model.UserEvent")).Return(webhook.NewError(webhook.ErrUnavailable, errors.New("connection refused")))
+
+	p := webhook.NewOutboxPublisher(mockService)
+	msg := &model.OutboxModel{
+		ID:        42,
+		EventType: "user.updated",
+		Payload:   json.RawMessage(`{"id":"bpielbbipt341rif5i20","name":"Momo","created_at":"2020-04-05T09:00:00.123456","updated_at":"2020-04-06T10:00:00"}`),
+	}
+
+	assert.NoError(t, p.Publish(msg))
+
+	event := <-dispatched
+	assert.Equal(t, uint64(42), event.ID)
+	assert.Equal(t, "user.updated", event.Type)
+	assert.Equal(t, "Momo", event.User.Name)
+	assert.Equal(t, time.Date(2020, 4, 5, 9, 0, 0, 123456000, time.UTC), event.User.CreatedAt)
+	assert.Equal(t, time.Date(2020, 4, 6, 10, 0, 0, 0, time.UTC), event.User.UpdatedAt)
+
+	// the failed dispatch fails the publish, so the outbox relays the event again
+	assert.Error(t, p.Publish(msg))
+	assert.Error(t, p.Publish(&model.OutboxModel{ID: 43, Payload: json.RawMessage(`{"created_at":"yesterday"}`)}))
+}
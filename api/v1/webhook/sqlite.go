@@ -0,0 +1,35 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package webhook
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// sqliteDialect represent the SQL of webhooks on SQLite, the events are stored as the text of PostgreSQL array
+// and matched after they're read. The payload is stored as TEXT and it's read as BLOB so it scans into
+// json.RawMessage. SQLite has no row lock, the transaction of the only writer keeps other worker out of the claim
+var sqliteDialect = dialect{
+	deliverySelectField: strings.Replace(model.WebhookDeliverySelectField, "payload", "CAST(payload AS BLOB) AS payload", 1),
+	onConflict:          " ON CONFLICT (webhook_id, outbox_id) DO NOTHING",
+	timestamp:           sqliteTimestamp,
+}
+
+// sqliteTimestamp will format t as CURRENT_TIMESTAMP does, so the stored timestamps compare as text
+func sqliteTimestamp(t time.Time) interface{} {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// NewSQLiteRepository will create an object that represent the Repository interface
+func NewSQLiteRepository(DBRead *sqlx.DB, DBWrite *sqlx.DB) Repository {
+	return &sqlRepository{DBRead: DBRead, DBWrite: DBWrite, dialect: sqliteDialect}
+}
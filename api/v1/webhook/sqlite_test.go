@@ -0,0 +1,98 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package webhook_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/webhook"
+	"github.com/moemoe89/go-graphql-gendhis/db/sqlite"
+
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+)
+
+func TestSQLiteRepository(t *testing.T) {
+	db, err := sqlx.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening the sqlite database", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if err := sqlite.Migrate(db); err != nil {
+		t.Fatalf("an error '%s' was not expected when migrating the sqlite database", err)
+	}
+	w := webhook.NewSQLiteRepository(db, db)
+
+	hooks := []*model.WebhookModel{
+		{ID: xid.New().String(), URL: "https://crm.example.com/hooks", Secret: "0123456789abcdef", Events: []string{"user.created", "user.updated"}, Active: true},
+		{ID: xid.New().String(), URL: "https://erp.example.com/hooks", Secret: "0123456789abcdef", Events: []string{}, Active: true},
+		{ID: xid.New().String(), URL: "https://log.example.com/hooks", Secret: "0123456789abcdef", Events: []string{"user.created"}, Active: false},
+	}
+	for _, hook := range hooks {
+		_, err := w.Create(hook)
+		assert.NoError(t, err)
+	}
+
+	hook, err := w.GetByID(hooks[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user.created", "user.updated"}, []string(hook.Events))
+	assert.False(t, hook.CreatedAt.IsZero())
+
+	subscribed, err := w.GetByEvent("user.updated")
+	assert.NoError(t, err)
+	assert.Len(t, subscribed, 2)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	delivery := &model.WebhookDeliveryModel{
+		ID:            xid.New().String(),
+		WebhookID:     hooks[0].ID,
+		EventID:       42,
+		EventType:     "user.updated",
+		Payload:       []byte(`{"type":"user.updated"}`),
+		Status:        model.DeliveryPending,
+		NextAttemptAt: now,
+	}
+	assert.NoError(t, w.CreateDelivery(delivery))
+
+	// the outbox event relayed again doesn't log another delivery
+	again := *delivery
+	again.ID = xid.New().String()
+	assert.NoError(t, w.CreateDelivery(&again))
+
+	count, err := w.CountDeliveries(&webhook.DeliveryCriteria{WebhookID: hooks[0].ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	claimed, err := w.ClaimDeliveries(now, now.Add(time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Len(t, claimed, 1)
+	assert.JSONEq(t, `{"type":"user.updated"}`, string(claimed[0].Payload))
+
+	// the claimed delivery is leased until the claim expires
+	claimed, err = w.ClaimDeliveries(now, now.Add(time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Len(t, claimed, 0)
+
+	delivery.Status, delivery.Attempts, delivery.ResponseStatus = model.DeliverySucceeded, 1, 204
+	assert.NoError(t, w.UpdateDelivery(delivery))
+
+	claimed, err = w.ClaimDeliveries(now.Add(time.Hour), now.Add(time.Hour), 10)
+	assert.NoError(t, err)
+	assert.Len(t, claimed, 0)
+
+	deliveries, err := w.GetDeliveries(&webhook.DeliveryCriteria{Status: model.DeliverySucceeded, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, 204, deliveries[0].ResponseStatus)
+	assert.Equal(t, now, deliveries[0].NextAttemptAt)
+}
@@ -10,6 +10,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -19,6 +20,7 @@ import (
 type ConfigurationModel struct {
 	RunMode        string `json:"run_mode"`
 	Port           string `json:"port"`
+	GrpcPort       string `json:"grpc_port"`
 	DialectMaster  string `json:"dialect_master"`
 	DsnMaster      string `json:"dsn_master"`
 	IdleConnMaster int    `json:"idle_conn_master"`
@@ -27,13 +29,62 @@ type ConfigurationModel struct {
 	DsnSlave       string `json:"dsn_slave"`
 	IdleConnSlave  int    `json:"idle_conn_slave"`
 	MaxConnSlave   int    `json:"max_conn_slave"`
+
+	DsnSlaves              []string `json:"dsn_slaves"`
+	ReplicaCheckIntervalMs int      `json:"replica_check_interval_ms"`
+	ReplicaMaxLagMs        int      `json:"replica_max_lag_ms"`
+
+	ReadYourWritesWindowMs int    `json:"read_your_writes_window_ms"`
+	ReadYourWritesSecret   string `json:"read_your_writes_secret"`
+
+	QueryTimeoutMs map[string]int `json:"query_timeout_ms"`
+
+	PhoneRegion string `json:"phone_region"`
+
+	WebhookMaxAttempts int `json:"webhook_max_attempts"`
+	WebhookBackoffMs   int `json:"webhook_backoff_ms"`
+	WebhookTimeoutMs   int `json:"webhook_timeout_ms"`
+	WebhookIntervalMs  int `json:"webhook_interval_ms"`
+
+	OutboxPublisher   string `json:"outbox_publisher"`
+	OutboxURL         string `json:"outbox_url"`
+	OutboxBatchSize   int    `json:"outbox_batch_size"`
+	OutboxIntervalMs  int    `json:"outbox_interval_ms"`
+	OutboxMaxAttempts int    `json:"outbox_max_attempts"`
 }
 
+// LocalEnv represent the environment variable which opts in to defaultConfiguration when config.json is missing,
+// so a deploy without config.json fails instead of running on local SQLite
+const LocalEnv = "LOCAL_SQLITE"
+
 var (
 	// Configuration represent the variable of configuration model
 	Configuration = &ConfigurationModel{}
 )
 
+// defaultConfiguration will return the configuration used without config.json when LocalEnv is set, which runs
+// on SQLite file so the app starts with no external services
+func defaultConfiguration() *ConfigurationModel {
+	return &ConfigurationModel{
+		RunMode:                "development",
+		Port:                   "8791",
+		GrpcPort:               "8792",
+		DialectMaster:          DialectSQLite,
+		DsnMaster:              "simple_api.db",
+		ReadYourWritesWindowMs: 5000,
+		QueryTimeoutMs:         map[string]int{"default": 5000, "export": 0, "import": 60000},
+		PhoneRegion:            "ID",
+		WebhookMaxAttempts:     5,
+		WebhookBackoffMs:       1000,
+		WebhookTimeoutMs:       10000,
+		WebhookIntervalMs:      1000,
+		OutboxPublisher:        "log",
+		OutboxBatchSize:        100,
+		OutboxIntervalMs:       1000,
+		OutboxMaxAttempts:      10,
+	}
+}
+
 func init() {
 	_, b, _, _ := runtime.Caller(0)
 	basepath := filepath.Dir(b)
@@ -41,6 +92,13 @@ func init() {
 	basepath = strings.Replace(basepath, "config", "", -1)
 	file := basepath + "config.json"
 	raw, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		if os.Getenv(LocalEnv) != "1" {
+			panic(fmt.Sprintf("Failed to load auth configuration file: %s, set %s=1 to run on local SQLite", err.Error(), LocalEnv))
+		}
+		Configuration = defaultConfiguration()
+		return
+	}
 	if err != nil {
 		panic(fmt.Sprintf("Failed to load auth configuration file: %s", err.Error()))
 	}
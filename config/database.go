@@ -7,36 +7,96 @@
 package config
 
 import (
+	"github.com/moemoe89/go-graphql-gendhis/db/sqlite"
+
 	"fmt"
+	"strings"
 
+	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
-// InitDB will create a variable that represent the sqlx.DB
-func InitDB() (*sqlx.DB, *sqlx.DB, error) {
-	DBRead, err := sqlx.Connect(Configuration.DialectSlave, Configuration.DsnSlave)
-	if err != nil {
-		return nil, nil, fmt.Errorf("Failed to open connection to slave database: %s", err.Error())
+const (
+	// DialectPostgres represent the dialect of PostgreSQL database
+	DialectPostgres = "postgres"
+	// DialectMySQL represent the dialect of MySQL database
+	DialectMySQL = "mysql"
+	// DialectSQLite represent the dialect of SQLite database, the pure-Go driver needs no cgo
+	DialectSQLite = "sqlite"
+)
+
+// InitDB will create the variables that represent the sqlx.DB of the replicas and the master. The replicas are
+// from dsn_slaves (dsn_slave when it's empty) and aren't pinged, the health check of replica.Set decides whether
+// they serve. SQLite has no replicas
+func InitDB() ([]*sqlx.DB, *sqlx.DB, error) {
+	if Configuration.DialectMaster == DialectSQLite {
+		DB, err := initSQLite(Configuration.DsnMaster)
+		return nil, DB, err
 	}
 
-	err = DBRead.Ping()
-	if err != nil {
-		return nil, nil, fmt.Errorf("Failed to ping connection to slave database: %s", err.Error())
+	dsns := Configuration.DsnSlaves
+	if len(dsns) == 0 {
+		dsns = []string{Configuration.DsnSlave}
 	}
 
-	DBRead.SetMaxIdleConns(Configuration.IdleConnSlave)
-	DBRead.SetMaxOpenConns(Configuration.MaxConnSlave)
+	DBReads := make([]*sqlx.DB, 0, len(dsns))
+	for i, dsn := range dsns {
+		DBRead, err := sqlx.Open(Configuration.DialectSlave, dsn)
+		if err != nil {
+			closeDB(DBReads)
+			return nil, nil, fmt.Errorf("Failed to open connection to slave database %d: %s", i, err.Error())
+		}
+
+		DBRead.SetMaxIdleConns(Configuration.IdleConnSlave)
+		DBRead.SetMaxOpenConns(Configuration.MaxConnSlave)
+		DBReads = append(DBReads, DBRead)
+	}
 
 	DBWrite, err := sqlx.Connect(Configuration.DialectMaster, Configuration.DsnMaster)
 	if err != nil {
+		closeDB(DBReads)
 		return nil, nil, fmt.Errorf("Failed to open connection to master database: %s", err.Error())
 	}
 
 	err = DBWrite.Ping()
 	if err != nil {
+		closeDB(DBReads)
 		return nil, nil, fmt.Errorf("Failed to ping connection to master database: %s", err.Error())
 	}
 
-	return DBRead, DBWrite, nil
+	return DBReads, DBWrite, nil
+}
+
+// initSQLite will open the SQLite database of dsn and create its schema. SQLite allows one writer at once, so
+// the pool holds one connection which is kept open, otherwise `:memory:` database would be dropped with it.
+// LIKE is made case-sensitive like PostgreSQL
+func initSQLite(dsn string) (*sqlx.DB, error) {
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+
+	DB, err := sqlx.Connect(DialectSQLite, dsn+sep+"_pragma=case_sensitive_like(1)")
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open connection to sqlite database: %s", err.Error())
+	}
+
+	DB.SetMaxOpenConns(1)
+	DB.SetMaxIdleConns(1)
+
+	err = sqlite.Migrate(DB)
+	if err != nil {
+		DB.Close()
+		return nil, fmt.Errorf("Failed to create schema of sqlite database: %s", err.Error())
+	}
+
+	return DB, nil
+}
+
+func closeDB(dbs []*sqlx.DB) {
+	for _, db := range dbs {
+		db.Close()
+	}
 }
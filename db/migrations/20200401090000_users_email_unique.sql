@@ -0,0 +1,20 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- the index can't be built while users share an email regardless of case, so the migration stops listing them
+-- +goose StatementBegin
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(email, ', ') INTO duplicates
+    FROM (SELECT lower(email) AS email FROM users WHERE deleted_at IS NULL GROUP BY lower(email) HAVING count(*) > 1) d;
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'users share the emails %, merge or soft delete them before creating idx_users_lower_email', duplicates;
+    END IF;
+END $$;
+-- +goose StatementEnd
+CREATE UNIQUE INDEX idx_users_lower_email ON users (lower(email)) WHERE deleted_at IS NULL;
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_users_lower_email;
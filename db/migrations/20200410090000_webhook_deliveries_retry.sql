@@ -0,0 +1,15 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- the retries are stored so the pending deliveries survive a restart, the ones left pending are due right away.
+-- outbox_id is the outbox event delivered, so the event relayed again doesn't log another delivery
+ALTER TABLE webhook_deliveries ADD COLUMN next_attempt_at TIMESTAMP NOT NULL DEFAULT (now() AT TIME ZONE 'UTC');
+ALTER TABLE webhook_deliveries ADD COLUMN outbox_id BIGINT NULL DEFAULT NULL;
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE UNIQUE INDEX idx_webhook_deliveries_outbox ON webhook_deliveries (webhook_id, outbox_id);
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_webhook_deliveries_outbox;
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS outbox_id;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS next_attempt_at;
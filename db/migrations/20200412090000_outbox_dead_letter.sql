@@ -0,0 +1,17 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- the event failing the max attempts is dead and isn't relayed anymore, the claimed events are leased until
+-- locked_until instead of being locked while they're published
+ALTER TABLE outbox ADD COLUMN dead_at TIMESTAMP NULL DEFAULT NULL;
+ALTER TABLE outbox ADD COLUMN locked_until TIMESTAMP NULL DEFAULT NULL;
+DROP INDEX IF EXISTS idx_outbox_unpublished;
+CREATE INDEX idx_outbox_unpublished ON outbox (id) WHERE published_at IS NULL AND dead_at IS NULL;
+CREATE INDEX idx_outbox_dead ON outbox (dead_at) WHERE dead_at IS NOT NULL;
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX IF EXISTS idx_outbox_dead;
+DROP INDEX IF EXISTS idx_outbox_unpublished;
+CREATE INDEX idx_outbox_unpublished ON outbox (id) WHERE published_at IS NULL;
+ALTER TABLE outbox DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE outbox DROP COLUMN IF EXISTS dead_at;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied
+CREATE FULLTEXT INDEX idx_search_fulltext ON users (name, email, address);
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX idx_search_fulltext ON users;
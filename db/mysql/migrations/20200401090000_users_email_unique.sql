@@ -0,0 +1,26 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- the index can't be built while users share an email regardless of case, so the migration stops listing them
+DROP PROCEDURE IF EXISTS check_users_email_unique;
+-- +goose StatementBegin
+CREATE PROCEDURE check_users_email_unique()
+BEGIN
+    DECLARE duplicates TEXT;
+    SELECT GROUP_CONCAT(email SEPARATOR ', ') INTO duplicates
+    FROM (SELECT lower(email) AS email FROM users WHERE deleted_at IS NULL GROUP BY lower(email) HAVING count(*) > 1) d;
+    IF duplicates IS NOT NULL THEN
+        SET @message = LEFT(CONCAT('users share the emails ', duplicates, ', merge or soft delete them first'), 128);
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = @message;
+    END IF;
+END;
+-- +goose StatementEnd
+CALL check_users_email_unique();
+DROP PROCEDURE check_users_email_unique;
+-- MySQL has no partial index, the generated column is NULL for deleted users so they don't take the email
+ALTER TABLE users ADD COLUMN active_email VARCHAR(50) AS (IF(deleted_at IS NULL, lower(email), NULL)) STORED;
+CREATE UNIQUE INDEX idx_users_lower_email ON users (active_email);
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX idx_users_lower_email ON users;
+ALTER TABLE users DROP COLUMN active_email;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied
+ALTER TABLE users ADD COLUMN phone_raw VARCHAR(20) NOT NULL DEFAULT '';
+UPDATE users SET phone_raw = phone;
+-- only the international phones are normalized here, the national ones need the calling code of region so
+-- they're normalized by `app -backfill-phones` after migrating
+UPDATE users SET phone = CONCAT('+', REGEXP_REPLACE(phone, '[^0-9]', '')) WHERE phone REGEXP '^\\+[0-9 ().-]+$';
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+UPDATE users SET phone = phone_raw WHERE phone_raw <> '';
+ALTER TABLE users DROP COLUMN phone_raw;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- SQL in section 'Up' is executed when this migration is applied
+-- the event failing the max attempts is dead and isn't relayed anymore, the claimed events are leased until
+-- locked_until instead of being locked while they're published
+ALTER TABLE outbox ADD COLUMN dead_at TIMESTAMP NULL DEFAULT NULL, ADD COLUMN locked_until TIMESTAMP NULL DEFAULT NULL;
+DROP INDEX idx_outbox_unpublished ON outbox;
+CREATE INDEX idx_outbox_unpublished ON outbox (published_at, dead_at, id);
+
+-- +goose Down
+-- SQL section 'Down' is executed when this migration is rolled back
+DROP INDEX idx_outbox_unpublished ON outbox;
+CREATE INDEX idx_outbox_unpublished ON outbox (published_at, id);
+ALTER TABLE outbox DROP COLUMN locked_until, DROP COLUMN dead_at;
@@ -0,0 +1,71 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package sqlite
+
+import (
+	"database/sql"
+	_ "embed"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Schema represent the schema of SQLite database, the migrations of db/migrations squashed into one
+//
+//go:embed schema.sql
+var Schema string
+
+// columns represent the columns added to the tables of Schema after they were created, SQLite can't add the
+// column only if it doesn't exist so the duplicate ones are ignored
+var columns = []string{
+	"ALTER TABLE outbox ADD COLUMN dead_at TIMESTAMP NULL DEFAULT NULL",
+	"ALTER TABLE outbox ADD COLUMN locked_until TIMESTAMP NULL DEFAULT NULL",
+}
+
+// indexes represent the indexes created after columns by their name, the index whose definition differs is
+// recreated. They match db/migrations
+var indexes = []struct {
+	name string
+	sql  string
+}{
+	{"idx_outbox_unpublished", "CREATE INDEX idx_outbox_unpublished ON outbox (id) WHERE published_at IS NULL AND dead_at IS NULL"},
+	{"idx_outbox_dead", "CREATE INDEX idx_outbox_dead ON outbox (dead_at) WHERE dead_at IS NOT NULL"},
+}
+
+// Migrate will create the tables, columns and indexes of Schema which don't exist yet
+func Migrate(db *sqlx.DB) error {
+	if _, err := db.Exec(Schema); err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		_, err := db.Exec(column)
+		if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return err
+		}
+	}
+
+	for _, index := range indexes {
+		var existing string
+		err := db.Get(&existing, "SELECT sql FROM sqlite_master WHERE type = 'index' AND name = ?", index.name)
+		if err == nil && existing == index.sql {
+			continue
+		}
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		if _, err := db.Exec("DROP INDEX IF EXISTS " + index.name); err != nil {
+			return err
+		}
+		if _, err := db.Exec(index.sql); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -7,16 +7,31 @@
 package main
 
 import (
+	mw "github.com/moemoe89/go-graphql-gendhis/api/middleware"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/form"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/outbox"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/replica"
 	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+	usrGrpc "github.com/moemoe89/go-graphql-gendhis/api/v1/user/delivery/grpc"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/webhook"
 	conf "github.com/moemoe89/go-graphql-gendhis/config"
 	_ "github.com/moemoe89/go-graphql-gendhis/docs"
 	"github.com/moemoe89/go-graphql-gendhis/routers"
 
+	"context"
+	"flag"
 	"fmt"
+	"net"
+	"net/http"
+	"time"
 
 	"github.com/DeanThompson/ginpprof"
 )
 
+// backfillPhones represent the flag running BackfillPhones of user instead of the app, after the phone_raw
+// migration
+var backfillPhones = flag.Bool("backfill-phones", false, "normalize the national phones of users with phone_region and exit")
+
 // @title Simple REST API
 // @version 1.0.0
 // @description This is a documentation of Simple REST API.
@@ -32,15 +47,19 @@ import (
 // @host
 // @BasePath /api/v1
 func main() {
-	dbR, dbW, err := conf.InitDB()
+	flag.Parse()
+
+	dbRs, dbW, err := conf.InitDB()
 	if err != nil {
 		panic(err)
 	}
 
 	defer func() {
-		err := dbR.Close()
-		if err != nil {
-			panic(err)
+		for _, dbR := range dbRs {
+			err := dbR.Close()
+			if err != nil {
+				panic(err)
+			}
 		}
 	}()
 
@@ -58,10 +77,100 @@ func main() {
 
 	log := conf.InitLog()
 
-	userRepo := user.NewPostgresRepository(dbR, dbW)
+	if len(conf.Configuration.PhoneRegion) > 0 {
+		if !form.IsRegion(conf.Configuration.PhoneRegion) {
+			panic(fmt.Sprintf("Unknown phone region: %s", conf.Configuration.PhoneRegion))
+		}
+		form.DefaultRegion = conf.Configuration.PhoneRegion
+	}
+
+	if *backfillPhones {
+		normalized, invalid, err := user.BackfillPhones(dbW, form.DefaultRegion)
+		if err != nil {
+			panic(fmt.Sprintf("Can't backfill the phones: %s", err.Error()))
+		}
+		for _, id := range invalid {
+			log.Warnf("can't normalize the phone of user %s, fix it by hand", id)
+		}
+		log.Infof("normalized %d phones, %d can't be normalized", normalized, len(invalid))
+		return
+	}
+
+	if conf.Configuration.ReadYourWritesWindowMs > 0 {
+		mw.ConsistencyWindow = time.Duration(conf.Configuration.ReadYourWritesWindowMs) * time.Millisecond
+	}
+	if len(conf.Configuration.ReadYourWritesSecret) > 0 {
+		mw.ConsistencyKey = []byte(conf.Configuration.ReadYourWritesSecret)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	replicas := replica.NewSet(log, dbW, dbRs, time.Duration(conf.Configuration.ReplicaMaxLagMs)*time.Millisecond)
+	timeouts := user.NewTimeouts(conf.Configuration.QueryTimeoutMs)
+
+	var userRepo user.Repository
+	var outboxRepo outbox.Repository
+	var webhookRepo webhook.Repository
+	switch conf.Configuration.DialectMaster {
+	case conf.DialectPostgres:
+		userRepo = user.NewPostgresReplicaRepository(replicas, dbW, timeouts)
+		outboxRepo = outbox.NewPostgresRepository(dbW)
+		webhookRepo = webhook.NewPostgresReplicaRepository(replicas, dbW)
+	case conf.DialectMySQL:
+		replicas.WithLagQuery(replica.MySQLLagQuery)
+		userRepo = user.NewMySQLReplicaRepository(replicas, dbW, timeouts)
+		outboxRepo = outbox.NewMySQLRepository(dbW)
+		webhookRepo = webhook.NewMySQLReplicaRepository(replicas, dbW)
+	case conf.DialectSQLite:
+		userRepo = user.NewSQLiteRepository(dbW, timeouts)
+		outboxRepo = outbox.NewSQLiteRepository(dbW)
+		webhookRepo = webhook.NewSQLiteRepository(dbW, dbW)
+	default:
+		panic(fmt.Sprintf("Unsupported database dialect: %s", conf.Configuration.DialectMaster))
+	}
+
+	replicas.Check(ctx)
+	go replicas.Run(ctx, time.Duration(conf.Configuration.ReplicaCheckIntervalMs)*time.Millisecond)
+
 	userSvc := user.NewService(log, userRepo)
 
-	app := routers.GetRouter(lang, log, userSvc)
+	webhookTimeout := time.Duration(conf.Configuration.WebhookTimeoutMs) * time.Millisecond
+	if webhookTimeout <= 0 {
+		webhookTimeout = webhook.DefaultTimeout
+	}
+	webhookClient := &http.Client{Timeout: webhookTimeout}
+	webhookSvc := webhook.NewService(log, webhookRepo, webhookClient, conf.Configuration.WebhookMaxAttempts, time.Duration(conf.Configuration.WebhookBackoffMs)*time.Millisecond)
+
+	go webhook.RunDeliveries(ctx, webhookSvc, 0, time.Duration(conf.Configuration.WebhookIntervalMs)*time.Millisecond)
+
+	outboxPublisher, err := outbox.NewPublisher(conf.Configuration.OutboxPublisher, log, &http.Client{Timeout: webhookTimeout}, conf.Configuration.OutboxURL)
+	if err != nil {
+		panic(err)
+	}
+	// the webhooks are fed from the outbox first, so the event isn't marked as published before it's dispatched
+	outboxPublisher = outbox.NewFanoutPublisher(webhook.NewOutboxPublisher(webhookSvc), outboxPublisher)
+	outboxRelay := outbox.NewRelay(log, outboxRepo, outboxPublisher, conf.Configuration.OutboxBatchSize, conf.Configuration.OutboxMaxAttempts, time.Duration(conf.Configuration.OutboxIntervalMs)*time.Millisecond)
+	go outboxRelay.Run(ctx)
+
+	if len(conf.Configuration.GrpcPort) > 0 {
+		lis, err := net.Listen("tcp", ":"+conf.Configuration.GrpcPort)
+		if err != nil {
+			panic(fmt.Sprintf("Can't listen the grpc port: %s", err.Error()))
+		}
+
+		grpcServer := usrGrpc.NewServer(lang, log, userSvc)
+		defer grpcServer.GracefulStop()
+
+		go func() {
+			err := grpcServer.Serve(lis)
+			if err != nil {
+				panic(fmt.Sprintf("Can't start the grpc server: %s", err.Error()))
+			}
+		}()
+	}
+
+	app := routers.GetRouter(lang, log, userSvc, webhookSvc)
 	ginpprof.Wrap(app)
 	err = app.Run(":" + conf.Configuration.Port)
 	if err != nil {
@@ -12,6 +12,11 @@ import (
 	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
 	usrGraphQL "github.com/moemoe89/go-graphql-gendhis/api/v1/user/delivery/graphql"
 	usrHttp "github.com/moemoe89/go-graphql-gendhis/api/v1/user/delivery/http"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/webhook"
+	whHttp "github.com/moemoe89/go-graphql-gendhis/api/v1/webhook/delivery/http"
+
+	"context"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/moemoe89/go-localization"
@@ -21,29 +26,80 @@ import (
 )
 
 // GetRouter will create a variable that represent the gin.Engine
-func GetRouter(lang *language.Config, log *logrus.Entry, userSvc user.Service) *gin.Engine {
+func GetRouter(lang *language.Config, log *logrus.Entry, userSvc user.Service, webhookSvc webhook.Service) *gin.Engine {
 	r := gin.New()
 	r.Use(gin.Recovery())
 	r.Use(mw.CORS)
+	r.Use(mw.ReadYourWrites)
 	r.GET("/", ap.Ping)
 	r.GET("/ping", ap.Ping)
 
 	api := r.Group("/api")
 	apiV1 := api.Group("/v1")
 
+	userRoutes(apiV1, lang, log, userSvc)
+
+	batch := ap.NewBatchCtrl(lang, log, r, func(ctx context.Context, fn func(http.Handler) error) error {
+		return userSvc.Atomic(ctx, func(svc user.Service) error {
+			return fn(atomicRouter(lang, log, svc))
+		})
+	})
+	apiV1.POST("/batch", batch.Batch)
+
+	wh := whHttp.NewWebhookCtrl(lang, log, webhookSvc)
+
+	apiV1.POST("/webhooks", wh.Create)
+	apiV1.GET("/webhooks", wh.List)
+	apiV1.GET("/webhooks/:id", withStaticID(wh.Detail, map[string]gin.HandlerFunc{
+		"dead-letters": wh.DeadLetters,
+	}))
+	apiV1.PUT("/webhooks/:id", wh.Update)
+	apiV1.DELETE("/webhooks/:id", wh.Delete)
+	apiV1.GET("/webhooks/:id/deliveries", wh.Deliveries)
+
+	url := ginSwagger.URL("/swagger/doc.json") // The url pointing to API definition
+	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, url))
+
+	return r
+}
+
+// userRoutes will register the REST and GraphQL routes of user served by userSvc
+func userRoutes(apiV1 gin.IRoutes, lang *language.Config, log *logrus.Entry, userSvc user.Service) {
 	usr := usrHttp.NewUserCtrl(lang, log, userSvc)
 
 	apiV1.POST("/user", usr.Create)
+	apiV1.POST("/user/import", usr.Import)
 	apiV1.GET("/user", usr.List)
-	apiV1.GET("/user/:id", usr.Detail)
+	apiV1.GET("/user/:id", withStaticID(usr.Detail, map[string]gin.HandlerFunc{
+		"events": usr.Events,
+		"export": usr.Export,
+	}))
 	apiV1.PUT("/user/:id", usr.Update)
+	apiV1.PATCH("/user/:id", usr.Patch)
 	apiV1.DELETE("/user/:id", usr.Delete)
 
-	apiV1.GET("/graphql/user", usrGraphQL.Handler(userSvc))
-	apiV1.POST("/graphql/user", usrGraphQL.Handler(userSvc))
+	apiV1.GET("/graphql/user", usrGraphQL.Handler(lang, userSvc))
+	apiV1.POST("/graphql/user", usrGraphQL.Handler(lang, userSvc))
+}
 
-	url := ginSwagger.URL("/swagger/doc.json") // The url pointing to API definition
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, url))
+// atomicRouter will create the handler of atomic batch serving only the user routes of svc, which is bound
+// to the transaction of batch. The middlewares already ran for the batch request, whose context is inherited
+func atomicRouter(lang *language.Config, log *logrus.Entry, svc user.Service) http.Handler {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	userRoutes(r.Group("/api/v1"), lang, log, svc)
 
 	return r
 }
+
+// withStaticID will serve the static routes sharing the segment of :id, since gin can't register
+// a static segment next to the wildcard one
+func withStaticID(handler gin.HandlerFunc, static map[string]gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h, ok := static[c.Param("id")]; ok {
+			h(c)
+			return
+		}
+		handler(c)
+	}
+}
@@ -0,0 +1,401 @@
+//
+//  Practicing GraphQL
+//
+//  Copyright © 2020. All rights reserved.
+//
+
+package routers_test
+
+import (
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/api_struct/model"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/user"
+	"github.com/moemoe89/go-graphql-gendhis/api/v1/webhook"
+	conf "github.com/moemoe89/go-graphql-gendhis/config"
+	"github.com/moemoe89/go-graphql-gendhis/routers"
+
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// newApp will boot the whole API on in-memory SQLite database, so the requests go through the real
+// router, service and repository
+func newApp(t *testing.T) (*gin.Engine, func()) {
+	gin.SetMode(gin.TestMode)
+
+	configuration := *conf.Configuration
+	conf.Configuration.DialectMaster = conf.DialectSQLite
+	conf.Configuration.DsnMaster = "file::memory:"
+
+	_, db, err := conf.InitDB()
+	*conf.Configuration = configuration
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening the sqlite database", err)
+	}
+
+	lang, err := conf.InitLang()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when loading the languages", err)
+	}
+	log := conf.InitLog()
+
+	userSvc := user.NewService(log, user.NewSQLiteRepository(db, nil))
+	webhookSvc := webhook.NewService(log, webhook.NewSQLiteRepository(db, db), http.DefaultClient, 1, 0)
+
+	return routers.GetRouter(lang, log, userSvc, webhookSvc), func() { db.Close() }
+}
+
+// do will serve the request with JSON body and decode the JSON response into v
+func do(t *testing.T, app http.Handler, method, target string, body, v interface{}) int {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(method, target, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+
+	if v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("can't decode the response %q: %s", w.Body.String(), err)
+		}
+	}
+
+	return w.Code
+}
+
+type userResponse struct {
+	Data struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Phone    string `json:"phone"`
+		PhoneRaw string `json:"phone_raw"`
+	} `json:"data"`
+}
+
+type usersResponse struct {
+	Data struct {
+		List []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"list"`
+	} `json:"data"`
+}
+
+func TestE2EUserREST(t *testing.T) {
+	app, close := newApp(t)
+	defer close()
+
+	created := userResponse{}
+	code := do(t, app, http.MethodPost, "/api/v1/user", map[string]string{
+		"name":    "Momo",
+		"email":   "momo@mail.com",
+		"phone":   "0856-4012-3456",
+		"address": "Indonesia",
+	}, &created)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.NotEmpty(t, created.Data.ID)
+	assert.Equal(t, "+6285640123456", created.Data.Phone)
+
+	code = do(t, app, http.MethodPost, "/api/v1/user", map[string]string{"name": "Momo", "email": "MOMO@mail.com"}, nil)
+	assert.Equal(t, http.StatusConflict, code)
+
+	code = do(t, app, http.MethodPost, "/api/v1/user", map[string]string{"name": "Gendhis", "email": "gendhis@acme.com"}, nil)
+	assert.Equal(t, http.StatusCreated, code)
+
+	detail := userResponse{}
+	code = do(t, app, http.MethodGet, "/api/v1/user/"+created.Data.ID, nil, &detail)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "momo@mail.com", detail.Data.Email)
+	assert.Equal(t, "0856-4012-3456", detail.Data.PhoneRaw)
+
+	list := usersResponse{}
+	code = do(t, app, http.MethodGet, "/api/v1/user?filter=email:endswith:@acme.com", nil, &list)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, list.Data.List, 1)
+	assert.Equal(t, "Gendhis", list.Data.List[0].Name)
+
+	code = do(t, app, http.MethodGet, "/api/v1/user?filter=name:contains:momo", nil, &list)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, list.Data.List)
+
+	updated := userResponse{}
+	code = do(t, app, http.MethodPut, "/api/v1/user/"+created.Data.ID, map[string]string{"name": "Momo Update", "email": "momo@mail.com"}, &updated)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Momo Update", updated.Data.Name)
+
+	code = do(t, app, http.MethodDelete, "/api/v1/user/"+created.Data.ID, nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	code = do(t, app, http.MethodGet, "/api/v1/user/"+created.Data.ID, nil, nil)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// the email of deleted user can be taken again
+	code = do(t, app, http.MethodPost, "/api/v1/user", map[string]string{"name": "Momo", "email": "momo@mail.com"}, nil)
+	assert.Equal(t, http.StatusCreated, code)
+}
+
+func TestE2EUserGraphQL(t *testing.T) {
+	app, close := newApp(t)
+	defer close()
+
+	created := struct {
+		Data struct {
+			Create struct {
+				ID    string `json:"id"`
+				Name  string `json:"name"`
+				Phone string `json:"phone"`
+			} `json:"Create"`
+		} `json:"data"`
+	}{}
+	code := do(t, app, http.MethodPost, "/api/v1/graphql/user", map[string]string{
+		"query": `mutation{Create(name:"momo",phone:"0856-4012-3456",email:"m@m.com",address:"Indonesia"){id,name,phone}}`,
+	}, &created)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, created.Data.Create.ID)
+	assert.Equal(t, "+6285640123456", created.Data.Create.Phone)
+
+	detail := struct {
+		Data struct {
+			Detail struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"Detail"`
+		} `json:"data"`
+	}{}
+	code = do(t, app, http.MethodPost, "/api/v1/graphql/user", map[string]string{
+		"query": `{Detail(id:"` + created.Data.Create.ID + `"){id,name}}`,
+	}, &detail)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "momo", detail.Data.Detail.Name)
+
+	list := struct {
+		Data struct {
+			List struct {
+				List []struct {
+					ID string `json:"id"`
+				} `json:"list"`
+			} `json:"List"`
+		} `json:"data"`
+	}{}
+	code = do(t, app, http.MethodPost, "/api/v1/graphql/user", map[string]string{
+		"query": `{List(per_page:"10",page:"1",order_by:"",name:"mo",phone:"",email:"",created_at_start:"",created_at_end:"",select_field:""){list{id}}}`,
+	}, &list)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, list.Data.List.List, 1)
+
+	search := struct {
+		Data struct {
+			Search struct {
+				List []struct {
+					Highlight string `json:"highlight"`
+				} `json:"list"`
+			} `json:"search"`
+		} `json:"data"`
+		Errors []struct {
+			Extensions struct {
+				Code string `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}{}
+	code = do(t, app, http.MethodPost, "/api/v1/graphql/user", map[string]string{
+		"query": `{search(query:"momo",highlight:true){list{highlight}}}`,
+	}, &search)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, search.Data.Search.List, 1)
+	assert.Empty(t, search.Errors)
+
+	code = do(t, app, http.MethodPost, "/api/v1/graphql/user", map[string]string{
+		"query": `{search(query:" ",highlight:true){list{highlight}}}`,
+	}, &search)
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, search.Errors, 1) {
+		assert.Equal(t, model.CodeValidationFailed, search.Errors[0].Extensions.Code)
+	}
+}
+
+func TestE2EUserImport(t *testing.T) {
+	app, close := newApp(t)
+	defer close()
+
+	code := do(t, app, http.MethodPost, "/api/v1/user", map[string]string{"name": "A", "email": "a@acme.com"}, nil)
+	assert.Equal(t, http.StatusCreated, code)
+
+	// the row of registered email is rejected and the others are imported, on dry run nothing is written
+	for _, target := range []string{"/api/v1/user/import?dry_run=true", "/api/v1/user/import"} {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader("name,email\nB,b@acme.com\nA Again,A@acme.com\n"))
+		req.Header.Set("Content-Type", "text/csv")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+
+		report := model.ImportResponse{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []int{1}, report.Data.Accepted)
+		if assert.Len(t, report.Data.Rejected, 1) {
+			assert.Equal(t, 2, report.Data.Rejected[0].Row)
+			assert.Equal(t, "email", report.Data.Rejected[0].Errors[0].Field)
+		}
+	}
+
+	list := usersResponse{}
+	code = do(t, app, http.MethodGet, "/api/v1/user?order_by=name", nil, &list)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, list.Data.List, 2)
+}
+
+func TestE2EUserSearchAndKeyset(t *testing.T) {
+	app, close := newApp(t)
+	defer close()
+
+	for _, name := range []string{"Momo", "Gendhis", "Baruno"} {
+		code := do(t, app, http.MethodPost, "/api/v1/user", map[string]string{"name": name, "email": name + "@acme.com"}, nil)
+		assert.Equal(t, http.StatusCreated, code)
+	}
+
+	list := usersResponse{}
+	code := do(t, app, http.MethodGet, "/api/v1/user?q=gendhis", nil, &list)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, list.Data.List, 1)
+
+	page := struct {
+		Data struct {
+			List []struct {
+				ID string `json:"id"`
+			} `json:"list"`
+			NextCursor string `json:"next_cursor"`
+		} `json:"data"`
+	}{}
+	code = do(t, app, http.MethodGet, "/api/v1/user?limit=2", nil, &page)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, page.Data.List, 2)
+	assert.NotEmpty(t, page.Data.NextCursor)
+
+	seen := map[string]bool{page.Data.List[0].ID: true, page.Data.List[1].ID: true}
+	code = do(t, app, http.MethodGet, "/api/v1/user?limit=2&cursor="+page.Data.NextCursor, nil, &page)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, page.Data.List, 1)
+	assert.False(t, seen[page.Data.List[0].ID])
+}
+
+func TestE2EBatchAtomic(t *testing.T) {
+	app, close := newApp(t)
+	defer close()
+
+	batch := map[string]interface{}{
+		"atomic": true,
+		"operations": []map[string]interface{}{
+			{"method": "POST", "path": "/api/v1/user", "body": map[string]string{"name": "Momo", "email": "momo@mail.com"}},
+			{"method": "POST", "path": "/api/v1/user", "body": map[string]string{"name": "Momo", "email": "MOMO@mail.com"}},
+		},
+	}
+	report := struct {
+		Data struct {
+			RolledBack bool `json:"rolled_back"`
+			Results    []struct {
+				Status int `json:"status"`
+			} `json:"results"`
+		} `json:"data"`
+	}{}
+	code := do(t, app, http.MethodPost, "/api/v1/batch", batch, &report)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, report.Data.RolledBack)
+	assert.Len(t, report.Data.Results, 2)
+	assert.Equal(t, http.StatusCreated, report.Data.Results[0].Status)
+	assert.Equal(t, http.StatusConflict, report.Data.Results[1].Status)
+
+	list := usersResponse{}
+	code = do(t, app, http.MethodGet, "/api/v1/user", nil, &list)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, list.Data.List)
+
+	batch["operations"] = []map[string]interface{}{
+		{"method": "POST", "path": "/api/v1/user", "body": map[string]string{"name": "Momo", "email": "momo@mail.com"}},
+	}
+	code = do(t, app, http.MethodPost, "/api/v1/batch", batch, &report)
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, report.Data.RolledBack)
+
+	code = do(t, app, http.MethodGet, "/api/v1/user", nil, &list)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, list.Data.List, 1)
+}
+
+type webhookResponse struct {
+	Data struct {
+		ID     string   `json:"id"`
+		URL    string   `json:"url"`
+		Secret string   `json:"secret"`
+		Events []string `json:"events"`
+		Active bool     `json:"active"`
+	} `json:"data"`
+}
+
+func TestE2EWebhooks(t *testing.T) {
+	app, close := newApp(t)
+	defer close()
+
+	created := webhookResponse{}
+	code := do(t, app, http.MethodPost, "/api/v1/webhooks", map[string]interface{}{
+		"url":    "https://crm.example.com/hooks/users",
+		"events": []string{"user.created", "user.deleted"},
+	}, &created)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.NotEmpty(t, created.Data.ID)
+	assert.NotEmpty(t, created.Data.Secret)
+	assert.True(t, created.Data.Active)
+
+	code = do(t, app, http.MethodPost, "/api/v1/webhooks", map[string]interface{}{"url": "https://erp.example.com/hooks"}, nil)
+	assert.Equal(t, http.StatusCreated, code)
+
+	code = do(t, app, http.MethodPost, "/api/v1/webhooks", map[string]interface{}{"url": "crm.example.com"}, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	list := struct {
+		Data struct {
+			List []struct {
+				ID     string   `json:"id"`
+				URL    string   `json:"url"`
+				Secret string   `json:"secret"`
+				Events []string `json:"events"`
+			} `json:"list"`
+		} `json:"data"`
+	}{}
+	code = do(t, app, http.MethodGet, "/api/v1/webhooks", nil, &list)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, list.Data.List, 2)
+
+	// the secret is only returned on create
+	for _, hook := range list.Data.List {
+		assert.Empty(t, hook.Secret)
+		if hook.ID == created.Data.ID {
+			assert.Equal(t, []string{"user.created", "user.deleted"}, hook.Events)
+		}
+	}
+
+	detail := webhookResponse{}
+	code = do(t, app, http.MethodGet, "/api/v1/webhooks/"+created.Data.ID, nil, &detail)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "https://crm.example.com/hooks/users", detail.Data.URL)
+
+	code = do(t, app, http.MethodGet, "/api/v1/webhooks/"+created.Data.ID+"/deliveries", nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	code = do(t, app, http.MethodDelete, "/api/v1/webhooks/"+created.Data.ID, nil, nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	code = do(t, app, http.MethodGet, "/api/v1/webhooks", nil, &list)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, list.Data.List, 1)
+}